@@ -0,0 +1,265 @@
+package env
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct tags read by Bind
+const (
+	// TagEnv holds the variable name and options e.g. `env:"PORT,required"`
+	TagEnv = "env"
+	// TagDefault holds the fallback value e.g. `default:"8080"`
+	TagDefault = "default"
+	// TagPrefix marks a nested struct and holds its prefix e.g. `envPrefix:"DB_"`
+	TagPrefix = "envPrefix"
+	// TagSeparator overrides the slice separator e.g. `envSeparator:";"`
+	TagSeparator = "envSeparator"
+)
+
+var (
+	ErrInvalidBindTarget = errors.New("bind target must be a non-nil pointer to a struct")
+	ErrRequired          = errors.New("required environment variable is not set")
+	ErrUnsupportedType   = errors.New("unsupported type")
+	ErrUnknownTagOption  = errors.New("unknown tag option")
+)
+
+var (
+	typeDuration        = reflect.TypeFor[time.Duration]()
+	typeURL             = reflect.TypeFor[url.URL]()
+	typeTextUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// Bind populates the struct v points to from environment variables.
+//
+//	type Config struct {
+//		Addr     string        `env:"ADDR" default:":8080"`
+//		Token    string        `env:"TOKEN,required"`
+//		Timeout  time.Duration `env:"TIMEOUT" default:"5s"`
+//		Upstream *url.URL      `env:"UPSTREAM_URL"`
+//		Hosts    []string      `env:"HOSTS" default:"a,b"`
+//		DB       struct {
+//			Name string `env:"NAME" default:"app"` // reads DB_NAME
+//		} `envPrefix:"DB_"`
+//	}
+//
+// Supported field types are strings, bools, ints, uints, floats,
+// time.Duration, url.URL, encoding.TextUnmarshaler implementations,
+// pointers to them and slices of them. Slices are split on "," unless
+// an envSeparator tag is given.
+//
+// All keys are registered for the Readme. Bind does not stop at the
+// first failure; all errors are joined and returned at once.
+func Bind(v any) error {
+	return BindWithPrefix("", v)
+}
+
+// BindWithPrefix is like Bind but prefixes all variable names.
+func BindWithPrefix(prefix string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidBindTarget
+	}
+
+	return bindStruct(prefix, rv.Elem())
+}
+
+// MustBind calls Bind and panics on error
+func MustBind(v any) {
+	if err := Bind(v); err != nil {
+		panic(err)
+	}
+}
+
+func bindStruct(prefix string, rv reflect.Value) error {
+	var errs []error
+
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fv := rv.Field(i)
+
+		if nested, ok := field.Tag.Lookup(TagPrefix); ok {
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					fv.Set(reflect.New(field.Type.Elem()))
+				}
+
+				fv = fv.Elem()
+			}
+
+			if fv.Kind() != reflect.Struct {
+				errs = append(errs, fmt.Errorf("%s: %w: %s", field.Name, ErrUnsupportedType, field.Type))
+				continue
+			}
+
+			if err := bindStruct(prefix+nested, fv); err != nil {
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup(TagEnv)
+		if !ok || tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		key := prefix + name
+
+		var required bool
+
+		if opts != "" {
+			for opt := range strings.SplitSeq(opts, ",") {
+				if opt != "required" {
+					errs = append(errs, fmt.Errorf("%s: %w: %s", key, ErrUnknownTagOption, opt))
+					continue
+				}
+
+				required = true
+			}
+		}
+
+		fallback, hasFallback := field.Tag.Lookup(TagDefault)
+
+		// register for the readme
+		if _, ok := types.Load(key); !ok {
+			types.Store(key, field.Type.String())
+		}
+
+		if required {
+			requiredKeys.Store(key, true)
+		} else if _, ok := defaults.Load(key); !ok {
+			defaults.Store(key, fallback)
+		}
+
+		value, exists := os.LookupEnv(key)
+
+		switch {
+		case exists:
+		case required:
+			errs = append(errs, fmt.Errorf("%s: %w", key, ErrRequired))
+			continue
+		case hasFallback:
+			value = fallback
+		default:
+			continue
+		}
+
+		separator := ","
+		if v, ok := field.Tag.Lookup(TagSeparator); ok {
+			separator = v
+		}
+
+		if err := bindValue(fv, value, separator); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func bindValue(fv reflect.Value, value, separator string) error {
+	if fv.Kind() == reflect.Pointer {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := bindValue(ptr.Elem(), value, separator); err != nil {
+			return err
+		}
+
+		fv.Set(ptr)
+
+		return nil
+	}
+
+	if fv.CanAddr() && fv.Addr().Type().Implements(typeTextUnmarshaler) {
+		if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(value))
+		}
+	}
+
+	switch fv.Type() {
+	case typeDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(int64(d))
+
+		return nil
+	case typeURL:
+		u, err := url.Parse(value)
+		if err != nil {
+			return err
+		}
+
+		fv.Set(reflect.ValueOf(*u))
+
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetFloat(n)
+	case reflect.Slice:
+		if value == "" {
+			fv.Set(reflect.MakeSlice(fv.Type(), 0, 0))
+			return nil
+		}
+
+		parts := strings.Split(value, separator)
+		slice := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+
+		for i, part := range parts {
+			if err := bindValue(slice.Index(i), strings.TrimSpace(part), separator); err != nil {
+				return err
+			}
+		}
+
+		fv.Set(slice)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, fv.Type())
+	}
+
+	return nil
+}
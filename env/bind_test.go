@@ -0,0 +1,79 @@
+package env_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/foomo/keel/env"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBind(t *testing.T) {
+	t.Setenv("TEST_BIND_STRING", "test")
+	t.Setenv("TEST_BIND_REQUIRED", "required")
+	t.Setenv("TEST_BIND_INTS", "1; 2;3")
+	t.Setenv("TEST_BIND_URL", "https://foomo.org/keel")
+	t.Setenv("TEST_BIND_DB_PORT", "5432")
+
+	type db struct {
+		Host string `env:"HOST" default:"localhost"`
+		Port uint16 `env:"PORT"`
+	}
+
+	var cfg struct {
+		String   string        `env:"TEST_BIND_STRING" default:"fallback"`
+		Required string        `env:"TEST_BIND_REQUIRED,required"`
+		Bool     bool          `env:"TEST_BIND_BOOL" default:"true"`
+		Duration time.Duration `env:"TEST_BIND_DURATION" default:"5s"`
+		Strings  []string      `env:"TEST_BIND_STRINGS" default:"a,b"`
+		Ints     []int         `env:"TEST_BIND_INTS" envSeparator:";"`
+		URL      *url.URL      `env:"TEST_BIND_URL"`
+		Unset    *int          `env:"TEST_BIND_UNSET"`
+		Ignored  string
+		DB       db `envPrefix:"TEST_BIND_DB_"`
+	}
+
+	require.NoError(t, env.Bind(&cfg))
+	assert.Equal(t, "test", cfg.String)
+	assert.Equal(t, "required", cfg.Required)
+	assert.True(t, cfg.Bool)
+	assert.Equal(t, 5*time.Second, cfg.Duration)
+	assert.Equal(t, []string{"a", "b"}, cfg.Strings)
+	assert.Equal(t, []int{1, 2, 3}, cfg.Ints)
+	require.NotNil(t, cfg.URL)
+	assert.Equal(t, "foomo.org", cfg.URL.Host)
+	assert.Nil(t, cfg.Unset)
+	assert.Equal(t, "localhost", cfg.DB.Host)
+	assert.Equal(t, uint16(5432), cfg.DB.Port)
+
+	assert.Contains(t, env.RequiredKeys(), "TEST_BIND_REQUIRED")
+	assert.Equal(t, "5s", env.Defaults()["TEST_BIND_DURATION"])
+	assert.Equal(t, "time.Duration", env.TypeOf("TEST_BIND_DURATION"))
+	assert.Contains(t, env.Readme(), "TEST_BIND_DB_HOST")
+}
+
+func TestBind_errors(t *testing.T) {
+	t.Setenv("TEST_BIND_ERR_INT", "foo")
+	t.Setenv("TEST_BIND_ERR_DURATION", "10")
+
+	var cfg struct {
+		Int      int               `env:"TEST_BIND_ERR_INT"`
+		Duration time.Duration     `env:"TEST_BIND_ERR_DURATION"`
+		Required string            `env:"TEST_BIND_ERR_REQUIRED,required"`
+		Option   string            `env:"TEST_BIND_ERR_OPTION,requried"`
+		Map      map[string]string `env:"TEST_BIND_ERR_MAP" default:"a"`
+	}
+
+	err := env.Bind(&cfg)
+	require.Error(t, err)
+	require.ErrorIs(t, err, env.ErrRequired)
+	require.ErrorIs(t, err, env.ErrUnsupportedType)
+	assert.Contains(t, err.Error(), "TEST_BIND_ERR_INT")
+	assert.Contains(t, err.Error(), "TEST_BIND_ERR_DURATION")
+	require.ErrorIs(t, err, env.ErrUnknownTagOption)
+	assert.Contains(t, err.Error(), "TEST_BIND_ERR_OPTION")
+
+	require.ErrorIs(t, env.Bind(cfg), env.ErrInvalidBindTarget)
+}
@@ -0,0 +1,169 @@
+package env
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultDotEnvFilename is loaded by Load when no filenames are given
+const DefaultDotEnvFilename = ".env"
+
+// Load sets environment variables from .env files for local development.
+//
+// Variables that already exist in the environment are never overridden,
+// so real environment variables always win over file values. When
+// called without filenames, DefaultDotEnvFilename is loaded if present;
+// explicitly given files must exist.
+//
+// Supported syntax:
+//
+//	# comment
+//	KEY=value
+//	export KEY=value
+//	KEY="double quoted with \n escapes"
+//	KEY='single quoted, taken literally'
+//	KEY=value # trailing comment
+func Load(filenames ...string) error {
+	if len(filenames) == 0 {
+		if err := loadFile(DefaultDotEnvFilename); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		return nil
+	}
+
+	for _, filename := range filenames {
+		if err := loadFile(filename); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MustLoad calls Load and panics on error
+func MustLoad(filenames ...string) {
+	if err := Load(filenames...); err != nil {
+		panic(err)
+	}
+}
+
+// Parse reads KEY=value pairs in .env syntax
+func Parse(r io.Reader) (map[string]string, error) {
+	ret := map[string]string{}
+	scanner := bufio.NewScanner(r)
+
+	var i int
+	for scanner.Scan() {
+		i++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing '='", i)
+		}
+
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key", i)
+		}
+
+		value, err := parseValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i, err)
+		}
+
+		ret[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func loadFile(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	values, err := Parse(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	for key, value := range values {
+		if Exists(key) {
+			continue
+		}
+
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+
+	return nil
+}
+
+func parseValue(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+
+	switch quote := v[0]; quote {
+	case '"', '\'':
+		end := closingQuote(v, quote)
+		if end < 0 {
+			return "", errors.New("unterminated quote")
+		}
+
+		value := v[1:end]
+		if quote == '"' {
+			value = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(value)
+		}
+
+		return value, nil
+	default:
+		if before, _, ok := strings.Cut(v, " #"); ok {
+			v = before
+		}
+
+		return strings.TrimSpace(v), nil
+	}
+}
+
+// closingQuote returns the index of the quote closing the value or -1,
+// skipping escaped quotes within double quotes
+func closingQuote(v string, quote byte) int {
+	if quote == '\'' {
+		if i := strings.IndexByte(v[1:], quote); i >= 0 {
+			return i + 1
+		}
+
+		return -1
+	}
+
+	for i := 1; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+
+	return -1
+}
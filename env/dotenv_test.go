@@ -0,0 +1,58 @@
+package env_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/foomo/keel/env"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	values, err := env.Parse(strings.NewReader(`
+# comment
+FOO=bar
+export EXPORTED=yes
+DOUBLE="line\nbreak"
+SINGLE='literal\n'
+TRAILING=value # comment
+QUOTED_COMMENT='a' # it's
+ESCAPED="say \"hi\"" # "quoted"
+EMPTY=
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"FOO":            "bar",
+		"EXPORTED":       "yes",
+		"DOUBLE":         "line\nbreak",
+		"SINGLE":         `literal\n`,
+		"TRAILING":       "value",
+		"QUOTED_COMMENT": "a",
+		"ESCAPED":        `say "hi"`,
+		"EMPTY":          "",
+	}, values)
+
+	_, err = env.Parse(strings.NewReader("INVALID"))
+	require.Error(t, err)
+
+	_, err = env.Parse(strings.NewReader(`UNTERMINATED="value\"`))
+	require.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(filename, []byte("TEST_DOTENV_NEW=file\nTEST_DOTENV_EXISTING=file\n"), 0o600))
+
+	t.Setenv("TEST_DOTENV_EXISTING", "env")
+	t.Setenv("TEST_DOTENV_NEW", "")
+	require.NoError(t, os.Unsetenv("TEST_DOTENV_NEW"))
+
+	require.NoError(t, env.Load(filename))
+	assert.Equal(t, "file", os.Getenv("TEST_DOTENV_NEW"))
+	assert.Equal(t, "env", os.Getenv("TEST_DOTENV_EXISTING"))
+
+	require.ErrorIs(t, env.Load(filepath.Join(t.TempDir(), ".env")), os.ErrNotExist)
+}
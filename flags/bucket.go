@@ -0,0 +1,42 @@
+package flags
+
+import (
+	"hash/fnv"
+)
+
+// bucketSize defines the resolution of percentage rollouts (0.01%).
+const bucketSize = 10000
+
+// bucket deterministically maps the salt and value onto [0, bucketSize)
+// so a subject keeps its variant as long as the rollout weights only
+// grow in its favour.
+func bucket(salt, value string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(salt))
+	_, _ = h.Write([]byte{':'})
+	_, _ = h.Write([]byte(value))
+
+	return int(h.Sum32() % bucketSize)
+}
+
+// split picks the variant whose cumulative weight covers the bucket.
+func split(splits []Split, b int) (string, bool) {
+	var total int
+	for _, s := range splits {
+		total += s.Weight
+	}
+
+	if total <= 0 {
+		return "", false
+	}
+
+	var cumulative int
+	for _, s := range splits {
+		cumulative += s.Weight
+		if b*total < cumulative*bucketSize {
+			return s.Variant, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,202 @@
+package flags
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// compiledFlag is a validated Flag with its variants indexed by name.
+type compiledFlag struct {
+	raw      Flag
+	variants map[string]any
+}
+
+// compile validates the flag and applies the type specific defaults.
+func compile(f Flag) (*compiledFlag, error) {
+	if f.Key == "" {
+		return nil, fmt.Errorf("flags: flag has empty key")
+	}
+
+	if f.Type == "" {
+		f.Type = TypeBool
+	}
+
+	switch f.Type {
+	case TypeBool:
+		if len(f.Variants) == 0 {
+			f.Variants = []Variant{{Name: "on", Value: true}, {Name: "off", Value: false}}
+		}
+
+		if f.DefaultVariant == "" {
+			f.DefaultVariant = "off"
+		}
+	case TypeVariant:
+		if len(f.Variants) == 0 {
+			return nil, fmt.Errorf("flags: flag %q has no variants", f.Key)
+		}
+
+		if f.DefaultVariant == "" {
+			return nil, fmt.Errorf("flags: flag %q has no default variant", f.Key)
+		}
+	default:
+		return nil, fmt.Errorf("flags: flag %q has invalid type %q (want %q or %q)", f.Key, f.Type, TypeBool, TypeVariant)
+	}
+
+	if f.OffVariant == "" {
+		f.OffVariant = f.DefaultVariant
+	}
+
+	if f.Salt == "" {
+		f.Salt = f.Key
+	}
+
+	if f.BucketBy == "" {
+		f.BucketBy = AttributeTargetingKey
+	}
+
+	c := &compiledFlag{
+		raw:      f,
+		variants: make(map[string]any, len(f.Variants)),
+	}
+
+	for _, v := range f.Variants {
+		if v.Name == "" {
+			return nil, fmt.Errorf("flags: flag %q has variant with empty name", f.Key)
+		}
+
+		if _, dup := c.variants[v.Name]; dup {
+			return nil, fmt.Errorf("flags: flag %q has duplicate variant %q", f.Key, v.Name)
+		}
+
+		if _, ok := v.Value.(bool); f.Type == TypeBool && !ok {
+			return nil, fmt.Errorf("flags: bool flag %q has non bool variant %q", f.Key, v.Name)
+		}
+
+		c.variants[v.Name] = v.Value
+	}
+
+	if err := c.validateVariant(f.DefaultVariant); err != nil {
+		return nil, err
+	}
+
+	if err := c.validateVariant(f.OffVariant); err != nil {
+		return nil, err
+	}
+
+	if err := c.validateSplits(f.Rollout); err != nil {
+		return nil, err
+	}
+
+	for i, r := range f.Rules {
+		switch {
+		case r.Variant != "" && len(r.Rollout) > 0:
+			return nil, fmt.Errorf("flags: flag %q rule #%d has both variant and rollout", f.Key, i)
+		case r.Variant != "":
+			if err := c.validateVariant(r.Variant); err != nil {
+				return nil, err
+			}
+		case len(r.Rollout) > 0:
+			if err := c.validateSplits(r.Rollout); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("flags: flag %q rule #%d has neither variant nor rollout", f.Key, i)
+		}
+
+		for _, cond := range r.Conditions {
+			if cond.Attribute == "" {
+				return nil, fmt.Errorf("flags: flag %q rule #%d has condition with empty attribute", f.Key, i)
+			}
+
+			if !cond.Operator.valid() {
+				return nil, fmt.Errorf("flags: flag %q rule #%d has invalid operator %q", f.Key, i, cond.Operator)
+			}
+		}
+	}
+
+	return c, nil
+}
+
+func (c *compiledFlag) validateVariant(name string) error {
+	if _, ok := c.variants[name]; !ok {
+		return fmt.Errorf("flags: flag %q references unknown variant %q: %w", c.raw.Key, name, ErrVariantNotFound)
+	}
+
+	return nil
+}
+
+func (c *compiledFlag) validateSplits(splits []Split) error {
+	for _, s := range splits {
+		if s.Weight < 0 {
+			return fmt.Errorf("flags: flag %q has negative weight for variant %q", c.raw.Key, s.Variant)
+		}
+
+		if err := c.validateVariant(s.Variant); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// evaluate resolves the variant for the evaluation context.
+func (c *compiledFlag) evaluate(ec EvaluationContext) Evaluation {
+	if c.raw.Disabled {
+		return c.result(c.raw.OffVariant, ReasonDisabled, "")
+	}
+
+	for i, r := range c.raw.Rules {
+		if !slices.ContainsFunc(r.Conditions, func(cond Condition) bool {
+			return !cond.Operator.match(ec.Values(cond.Attribute), cond.Values)
+		}) {
+			name := r.Name
+			if name == "" {
+				name = strconv.Itoa(i)
+			}
+
+			if r.Variant != "" {
+				return c.result(r.Variant, ReasonTargetingMatch, name)
+			}
+
+			if variant, ok := c.split(r.Rollout, ec); ok {
+				return c.result(variant, ReasonTargetingMatch, name)
+			}
+		}
+	}
+
+	if variant, ok := c.split(c.raw.Rollout, ec); ok {
+		return c.result(variant, ReasonSplit, "")
+	}
+
+	if len(c.raw.Rules) == 0 && len(c.raw.Rollout) == 0 {
+		return c.result(c.raw.DefaultVariant, ReasonStatic, "")
+	}
+
+	return c.result(c.raw.DefaultVariant, ReasonDefault, "")
+}
+
+// split buckets the context by the configured attribute. Subjects
+// without a bucketing value are not part of any rollout.
+func (c *compiledFlag) split(splits []Split, ec EvaluationContext) (string, bool) {
+	if len(splits) == 0 {
+		return "", false
+	}
+
+	value, ok := ec.Value(c.raw.BucketBy)
+	if !ok {
+		return "", false
+	}
+
+	return split(splits, bucket(c.raw.Salt, value))
+}
+
+func (c *compiledFlag) result(variant string, reason Reason, rule string) Evaluation {
+	return Evaluation{
+		Key:     c.raw.Key,
+		Variant: variant,
+		Value:   c.variants[variant],
+		Reason:  reason,
+		Rule:    rule,
+	}
+}
@@ -0,0 +1,116 @@
+package flags
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// Config is the file-shape configuration of all feature flags.
+//
+// Flags, variants and rules are declared as lists rather than maps
+// because viper lower-cases map keys; keeping names in values preserves
+// their case.
+type Config struct {
+	// List of feature flags
+	Flags []Flag `json:"flags,omitempty" yaml:"flags" mapstructure:"flags"`
+}
+
+// Flag declares a single feature flag.
+//
+// Evaluation order:
+//
+//  1. a runtime override set through Flags.SetOverride
+//  2. OffVariant if the flag is disabled
+//  3. the first Rule whose conditions all match
+//  4. the flag level Rollout
+//  5. DefaultVariant
+type Flag struct {
+	// Unique key used to look up the flag
+	Key string `json:"key" yaml:"key" mapstructure:"key" jsonschema:"required,minLength=1"`
+	// Human readable description shown in the readme
+	Description string `json:"description,omitempty" yaml:"description" mapstructure:"description"`
+	// Flag type, defaults to bool
+	Type Type `json:"type,omitempty" yaml:"type" mapstructure:"type" jsonschema:"enum=bool,enum=variant"`
+	// Disabled flags always serve the OffVariant
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled" mapstructure:"disabled"`
+	// Named values of the flag. Bool flags default to "on" (true) and "off" (false)
+	Variants []Variant `json:"variants,omitempty" yaml:"variants" mapstructure:"variants"`
+	// Variant served when no rule or rollout applies. Bool flags default to "off"
+	DefaultVariant string `json:"defaultVariant,omitempty" yaml:"defaultVariant" mapstructure:"defaultVariant"`
+	// Variant served when the flag is disabled. Defaults to the DefaultVariant
+	OffVariant string `json:"offVariant,omitempty" yaml:"offVariant" mapstructure:"offVariant"`
+	// Targeting rules evaluated in order; the first match wins
+	Rules []Rule `json:"rules,omitempty" yaml:"rules" mapstructure:"rules"`
+	// Percentage rollout applied when no rule matches
+	Rollout []Split `json:"rollout,omitempty" yaml:"rollout" mapstructure:"rollout"`
+	// Attribute used for sticky bucketing, defaults to the targeting key
+	BucketBy string `json:"bucketBy,omitempty" yaml:"bucketBy" mapstructure:"bucketBy"`
+	// Salt mixed into the bucketing hash, defaults to the flag key
+	Salt string `json:"salt,omitempty" yaml:"salt" mapstructure:"salt"`
+}
+
+// Variant is a named flag value.
+type Variant struct {
+	// Name of the variant
+	Name string `json:"name" yaml:"name" mapstructure:"name" jsonschema:"required,minLength=1"`
+	// Value served for the variant
+	Value any `json:"value,omitempty" yaml:"value" mapstructure:"value"`
+}
+
+// Rule serves a variant or rollout when all of its conditions match.
+type Rule struct {
+	// Optional name used in logs and the readme
+	Name string `json:"name,omitempty" yaml:"name" mapstructure:"name"`
+	// Conditions that must all match
+	Conditions []Condition `json:"conditions,omitempty" yaml:"conditions" mapstructure:"conditions"`
+	// Variant served on match. Mutually exclusive with Rollout
+	Variant string `json:"variant,omitempty" yaml:"variant" mapstructure:"variant"`
+	// Percentage rollout served on match. Mutually exclusive with Variant
+	Rollout []Split `json:"rollout,omitempty" yaml:"rollout" mapstructure:"rollout"`
+}
+
+// Condition matches an evaluation context attribute.
+type Condition struct {
+	// Attribute name e.g. "trackingId", "header.X-Country" or "claim.sub"
+	Attribute string `json:"attribute,omitempty" yaml:"attribute" mapstructure:"attribute" jsonschema:"required,minLength=1"`
+	// Comparison operator
+	Operator Operator `json:"operator,omitempty" yaml:"operator" mapstructure:"operator" jsonschema:"required"`
+	// Values compared against the attribute
+	Values []string `json:"values,omitempty" yaml:"values" mapstructure:"values"`
+}
+
+// Split assigns a relative weight to a variant within a rollout.
+type Split struct {
+	// Variant served for this share of the traffic
+	Variant string `json:"variant,omitempty" yaml:"variant" mapstructure:"variant" jsonschema:"required,minLength=1"`
+	// Relative weight of the variant
+	Weight int `json:"weight,omitempty" yaml:"weight" mapstructure:"weight" jsonschema:"minimum=0"`
+}
+
+// LoadConfig decodes the list of flags stored under key.
+//
+// Expected shape for key "flags":
+//
+//	flags:
+//	  - key: newCheckout
+//	    rules:
+//	      - conditions:
+//	          - attribute: claim.roles
+//	            operator: in
+//	            values: [beta]
+//	        variant: "on"
+//	    rollout:
+//	      - variant: "on"
+//	        weight: 10
+//	      - variant: "off"
+//	        weight: 90
+func LoadConfig(c *viper.Viper, key string) (Config, error) {
+	var cfg Config
+
+	if err := c.UnmarshalKey(key, &cfg.Flags); err != nil {
+		return cfg, fmt.Errorf("flags: decode %q: %w", key, err)
+	}
+
+	return cfg, nil
+}
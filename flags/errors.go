@@ -0,0 +1,11 @@
+package flags
+
+import (
+	"errors"
+)
+
+var (
+	ErrFlagNotFound    = errors.New("flag not found")
+	ErrVariantNotFound = errors.New("variant not found")
+	ErrTypeMismatch    = errors.New("type mismatch")
+)
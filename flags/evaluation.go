@@ -0,0 +1,12 @@
+package flags
+
+// Evaluation is the result of evaluating a single flag.
+type Evaluation struct {
+	Key     string
+	Variant string
+	Value   any
+	Reason  Reason
+	// Rule is the name or index of the matched rule
+	Rule string
+	Err  error
+}
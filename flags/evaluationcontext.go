@@ -0,0 +1,85 @@
+package flags
+
+import (
+	"context"
+	"maps"
+)
+
+// Well known attribute names. Header and claim attributes are prefixed
+// with AttributeHeaderPrefix and AttributeClaimPrefix respectively.
+const (
+	AttributeTargetingKey = "targetingKey"
+	AttributeTrackingID   = "trackingId"
+	AttributeSessionID    = "sessionId"
+	AttributeHeaderPrefix = "header."
+	AttributeClaimPrefix  = "claim."
+)
+
+type contextKey string
+
+const contextKeyEvaluationContext contextKey = "evaluationContext"
+
+// EvaluationContext holds the attributes rules are evaluated against.
+//
+// TargetingKey identifies the subject for sticky bucketing (e.g. the
+// tracking id). Attributes are multi-valued so list claims such as
+// roles can be matched directly.
+type EvaluationContext struct {
+	TargetingKey string
+	Attributes   map[string][]string
+}
+
+// NewEvaluationContext returns a new EvaluationContext
+func NewEvaluationContext(targetingKey string) EvaluationContext {
+	return EvaluationContext{
+		TargetingKey: targetingKey,
+		Attributes:   map[string][]string{},
+	}
+}
+
+// With returns a copy with the given attribute values set.
+func (c EvaluationContext) With(attribute string, values ...string) EvaluationContext {
+	attributes := make(map[string][]string, len(c.Attributes)+1)
+	maps.Copy(attributes, c.Attributes)
+	attributes[attribute] = values
+	c.Attributes = attributes
+
+	return c
+}
+
+// Values returns the attribute values. AttributeTargetingKey resolves to
+// the TargetingKey.
+func (c EvaluationContext) Values(attribute string) []string {
+	if attribute == AttributeTargetingKey {
+		if c.TargetingKey == "" {
+			return nil
+		}
+
+		return []string{c.TargetingKey}
+	}
+
+	return c.Attributes[attribute]
+}
+
+// Value returns the first attribute value.
+func (c EvaluationContext) Value(attribute string) (string, bool) {
+	if values := c.Values(attribute); len(values) > 0 {
+		return values[0], true
+	}
+
+	return "", false
+}
+
+// WithEvaluationContext stores the evaluation context.
+func WithEvaluationContext(ctx context.Context, ec EvaluationContext) context.Context {
+	return context.WithValue(ctx, contextKeyEvaluationContext, ec)
+}
+
+// EvaluationContextFromContext returns the stored evaluation context.
+func EvaluationContextFromContext(ctx context.Context) (EvaluationContext, bool) {
+	if value, ok := ctx.Value(contextKeyEvaluationContext).(EvaluationContext); ok {
+		return value, true
+	}
+
+	return EvaluationContext{}, false
+}
@@ -0,0 +1,290 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/foomo/keel/log"
+	"github.com/foomo/keel/markdown"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ProviderName is reported as feature_flag.provider.name on span events.
+const ProviderName = "keel"
+
+// Flags evaluates a set of feature flags.
+//
+// Flags is safe for concurrent use. Load swaps the whole flag set
+// atomically; runtime overrides survive a reload as long as the flag
+// and variant still exist.
+type Flags struct {
+	l             *zap.Logger
+	flags         map[string]*compiledFlag
+	overrides     map[string]string
+	syncFlagsLock sync.RWMutex
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// New validates cfg and returns the flags.
+func New(l *zap.Logger, cfg Config) (*Flags, error) {
+	if l == nil {
+		l = log.Logger()
+	}
+
+	inst := &Flags{
+		l:         l,
+		flags:     map[string]*compiledFlag{},
+		overrides: map[string]string{},
+	}
+
+	if err := inst.Load(cfg); err != nil {
+		return nil, err
+	}
+
+	return inst, nil
+}
+
+// NewFromConfig returns the flags declared under the given config key.
+func NewFromConfig(l *zap.Logger, c *viper.Viper, key string) (*Flags, error) {
+	cfg, err := LoadConfig(c, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(l, cfg)
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// Load validates cfg and replaces all flags.
+func (f *Flags) Load(cfg Config) error {
+	flags := make(map[string]*compiledFlag, len(cfg.Flags))
+
+	for _, v := range cfg.Flags {
+		c, err := compile(v)
+		if err != nil {
+			return err
+		}
+
+		if _, dup := flags[c.raw.Key]; dup {
+			return fmt.Errorf("flags: duplicate flag %q", c.raw.Key)
+		}
+
+		flags[c.raw.Key] = c
+	}
+
+	f.syncFlagsLock.Lock()
+	defer f.syncFlagsLock.Unlock()
+
+	f.flags = flags
+
+	for key, variant := range f.overrides {
+		if c, ok := flags[key]; !ok || c.validateVariant(variant) != nil {
+			f.l.Info("dropping flag override", zap.String("flag", key), zap.String("variant", variant))
+			delete(f.overrides, key)
+		}
+	}
+
+	return nil
+}
+
+// Flags returns the flag declarations with the defaults applied.
+func (f *Flags) Flags() []Flag {
+	f.syncFlagsLock.RLock()
+	defer f.syncFlagsLock.RUnlock()
+
+	ret := make([]Flag, 0, len(f.flags))
+	for _, key := range slices.Sorted(maps.Keys(f.flags)) {
+		ret = append(ret, f.flags[key].raw)
+	}
+
+	return ret
+}
+
+// Overrides returns a copy of the runtime overrides.
+func (f *Flags) Overrides() map[string]string {
+	f.syncFlagsLock.RLock()
+	defer f.syncFlagsLock.RUnlock()
+
+	return maps.Clone(f.overrides)
+}
+
+// SetOverride forces the variant for all evaluations of the flag.
+func (f *Flags) SetOverride(key, variant string) error {
+	f.syncFlagsLock.Lock()
+	defer f.syncFlagsLock.Unlock()
+
+	c, ok := f.flags[key]
+	if !ok {
+		return fmt.Errorf("flags: %q: %w", key, ErrFlagNotFound)
+	}
+
+	if err := c.validateVariant(variant); err != nil {
+		return err
+	}
+
+	f.overrides[key] = variant
+	f.l.Info("setting flag override", zap.String("flag", key), zap.String("variant", variant))
+
+	return nil
+}
+
+// DeleteOverride removes the runtime override of the flag.
+func (f *Flags) DeleteOverride(key string) {
+	f.syncFlagsLock.Lock()
+	defer f.syncFlagsLock.Unlock()
+
+	if _, ok := f.overrides[key]; ok {
+		delete(f.overrides, key)
+		f.l.Info("deleting flag override", zap.String("flag", key))
+	}
+}
+
+// Evaluate resolves the flag against the evaluation context stored in
+// ctx and records a feature_flag.evaluation event on the current span.
+func (f *Flags) Evaluate(ctx context.Context, key string) Evaluation {
+	ec, _ := EvaluationContextFromContext(ctx)
+
+	ret := f.evaluate(ec, key)
+
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		attrs := []attribute.KeyValue{
+			semconv.FeatureFlagKey(ret.Key),
+			semconv.FeatureFlagProviderName(ProviderName),
+			semconv.FeatureFlagResultReasonKey.String(ret.Reason.String()),
+		}
+		if ret.Variant != "" {
+			attrs = append(attrs, semconv.FeatureFlagResultVariant(ret.Variant))
+		}
+
+		if ec.TargetingKey != "" {
+			attrs = append(attrs, semconv.FeatureFlagContextID(ec.TargetingKey))
+		}
+
+		if ret.Err != nil {
+			attrs = append(attrs, semconv.ErrorTypeOther, semconv.FeatureFlagErrorMessage(ret.Err.Error()))
+		}
+
+		span.AddEvent("feature_flag.evaluation", trace.WithAttributes(attrs...))
+	}
+
+	if ret.Err != nil {
+		log.WithError(f.l, ret.Err).Debug("failed to evaluate flag", zap.String("flag", key))
+	}
+
+	return ret
+}
+
+// Bool returns the value of a bool flag or the fallback on error.
+func (f *Flags) Bool(ctx context.Context, key string, fallback bool) bool {
+	return value(f, ctx, key, fallback)
+}
+
+// String returns the string value of a variant flag or the fallback on error.
+func (f *Flags) String(ctx context.Context, key, fallback string) string {
+	return value(f, ctx, key, fallback)
+}
+
+// Variant returns the evaluated variant name or the fallback on error.
+func (f *Flags) Variant(ctx context.Context, key, fallback string) string {
+	if ret := f.Evaluate(ctx, key); ret.Err == nil {
+		return ret.Variant
+	}
+
+	return fallback
+}
+
+// Readme returns the self-documenting string
+func (f *Flags) Readme() string {
+	md := &markdown.Markdown{}
+	flags := f.Flags()
+	overrides := f.Overrides()
+
+	rows := make([][]string, 0, len(flags))
+	for _, v := range flags {
+		variants := make([]string, 0, len(v.Variants))
+		for _, variant := range v.Variants {
+			variants = append(variants, variant.Name)
+		}
+
+		rollout := make([]string, 0, len(v.Rollout))
+		for _, s := range v.Rollout {
+			rollout = append(rollout, fmt.Sprintf("%s:%d", s.Variant, s.Weight))
+		}
+
+		rows = append(rows, []string{
+			markdown.Code(v.Key),
+			markdown.Code(v.Type.String()),
+			markdown.Code(fmt.Sprintf("%t", !v.Disabled)),
+			markdown.Code(v.DefaultVariant),
+			markdown.Code(strings.Join(variants, ", ")),
+			markdown.Code(fmt.Sprintf("%d", len(v.Rules))),
+			markdown.Code(strings.Join(rollout, ", ")),
+			markdown.Code(overrides[v.Key]),
+			v.Description,
+		})
+	}
+
+	if len(rows) > 0 {
+		md.Println("### Feature Flags")
+		md.Println("")
+		md.Println("List of all registered feature flags.")
+		md.Println("")
+		md.Table([]string{"Key", "Type", "Enabled", "Default", "Variants", "Rules", "Rollout", "Override", "Description"}, rows)
+		md.Println("")
+	}
+
+	return md.String()
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func (f *Flags) evaluate(ec EvaluationContext, key string) Evaluation {
+	f.syncFlagsLock.RLock()
+	c, ok := f.flags[key]
+	override, overridden := f.overrides[key]
+	f.syncFlagsLock.RUnlock()
+
+	if !ok {
+		return Evaluation{Key: key, Reason: ReasonError, Err: fmt.Errorf("flags: %q: %w", key, ErrFlagNotFound)}
+	}
+
+	if overridden {
+		return c.result(override, ReasonOverride, "")
+	}
+
+	return c.evaluate(ec)
+}
+
+func value[T any](f *Flags, ctx context.Context, key string, fallback T) T {
+	ret := f.Evaluate(ctx, key)
+	if ret.Err != nil {
+		return fallback
+	}
+
+	if v, ok := ret.Value.(T); ok {
+		return v
+	}
+
+	log.WithError(f.l, ErrTypeMismatch).Debug("failed to evaluate flag",
+		zap.String("flag", key),
+		zap.String("type", fmt.Sprintf("%T", ret.Value)),
+	)
+
+	return fallback
+}
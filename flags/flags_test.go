@@ -0,0 +1,259 @@
+package flags_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/foomo/keel/flags"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFlags_Evaluate(t *testing.T) {
+	t.Parallel()
+
+	f, err := flags.New(zap.NewNop(), flags.Config{
+		Flags: []flags.Flag{
+			{Key: "static"},
+			{Key: "disabled", Disabled: true, DefaultVariant: "on", OffVariant: "off"},
+			{
+				Key: "beta",
+				Rules: []flags.Rule{
+					{
+						Name:       "beta-testers",
+						Conditions: []flags.Condition{{Attribute: "claim.roles", Operator: flags.OperatorIn, Values: []string{"beta"}}},
+						Variant:    "on",
+					},
+				},
+			},
+			{
+				Key:            "color",
+				Type:           flags.TypeVariant,
+				Variants:       []flags.Variant{{Name: "red", Value: "#ff0000"}, {Name: "blue", Value: "#0000ff"}},
+				DefaultVariant: "red",
+				Rules: []flags.Rule{
+					{
+						Conditions: []flags.Condition{{Attribute: "header.X-Country", Operator: flags.OperatorIn, Values: []string{"DE"}}},
+						Variant:    "blue",
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		key         string
+		ec          flags.EvaluationContext
+		wantVariant string
+		wantReason  flags.Reason
+		wantErr     error
+	}{
+		{
+			name:        "static default",
+			key:         "static",
+			wantVariant: "off",
+			wantReason:  flags.ReasonStatic,
+		},
+		{
+			name:        "disabled",
+			key:         "disabled",
+			wantVariant: "off",
+			wantReason:  flags.ReasonDisabled,
+		},
+		{
+			name:        "rule match",
+			key:         "beta",
+			ec:          flags.NewEvaluationContext("foo").With("claim.roles", "admin", "beta"),
+			wantVariant: "on",
+			wantReason:  flags.ReasonTargetingMatch,
+		},
+		{
+			name:        "rule miss",
+			key:         "beta",
+			ec:          flags.NewEvaluationContext("foo").With("claim.roles", "admin"),
+			wantVariant: "off",
+			wantReason:  flags.ReasonDefault,
+		},
+		{
+			name:        "variant rule match",
+			key:         "color",
+			ec:          flags.NewEvaluationContext("foo").With("header.X-Country", "DE"),
+			wantVariant: "blue",
+			wantReason:  flags.ReasonTargetingMatch,
+		},
+		{
+			name:       "unknown flag",
+			key:        "unknown",
+			wantReason: flags.ReasonError,
+			wantErr:    flags.ErrFlagNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := flags.WithEvaluationContext(context.Background(), tt.ec)
+			ret := f.Evaluate(ctx, tt.key)
+			assert.Equal(t, tt.wantVariant, ret.Variant)
+			assert.Equal(t, tt.wantReason, ret.Reason)
+			assert.ErrorIs(t, ret.Err, tt.wantErr)
+		})
+	}
+
+	assert.Equal(t, "#0000ff", f.String(flags.WithEvaluationContext(context.Background(), flags.NewEvaluationContext("").With("header.X-Country", "DE")), "color", ""))
+	assert.True(t, f.Bool(context.Background(), "unknown", true))
+	assert.Equal(t, "fallback", f.String(context.Background(), "beta", "fallback"))
+}
+
+func TestFlags_Rollout(t *testing.T) {
+	t.Parallel()
+
+	newFlags := func(on int) *flags.Flags {
+		f, err := flags.New(zap.NewNop(), flags.Config{
+			Flags: []flags.Flag{
+				{Key: "rollout", Rollout: []flags.Split{{Variant: "on", Weight: on}, {Variant: "off", Weight: 100 - on}}},
+			},
+		})
+		require.NoError(t, err)
+
+		return f
+	}
+
+	f10, f50 := newFlags(10), newFlags(50)
+
+	var on10, on50 int
+
+	for i := range 10000 {
+		ctx := flags.WithEvaluationContext(context.Background(), flags.NewEvaluationContext(fmt.Sprintf("user-%d", i)))
+
+		ret := f10.Evaluate(ctx, "rollout")
+		assert.Equal(t, flags.ReasonSplit, ret.Reason)
+
+		if ret.Variant == "on" {
+			on10++
+			// sticky: increasing the rollout keeps enabled subjects enabled
+			assert.True(t, f50.Bool(ctx, "rollout", false))
+		}
+
+		if f50.Bool(ctx, "rollout", false) {
+			on50++
+		}
+
+		// deterministic for the same subject
+		assert.Equal(t, ret.Variant, f10.Evaluate(ctx, "rollout").Variant)
+	}
+
+	assert.InDelta(t, 1000, on10, 150)
+	assert.InDelta(t, 5000, on50, 250)
+
+	// subjects without a bucketing value are not part of the rollout
+	ret := f10.Evaluate(context.Background(), "rollout")
+	assert.Equal(t, "off", ret.Variant)
+	assert.Equal(t, flags.ReasonDefault, ret.Reason)
+}
+
+func TestFlags_Override(t *testing.T) {
+	t.Parallel()
+
+	f, err := flags.New(zap.NewNop(), flags.Config{Flags: []flags.Flag{{Key: "foo"}}})
+	require.NoError(t, err)
+
+	require.NoError(t, f.SetOverride("foo", "on"))
+	require.ErrorIs(t, f.SetOverride("foo", "unknown"), flags.ErrVariantNotFound)
+	require.ErrorIs(t, f.SetOverride("bar", "on"), flags.ErrFlagNotFound)
+
+	ret := f.Evaluate(context.Background(), "foo")
+	assert.Equal(t, "on", ret.Variant)
+	assert.Equal(t, flags.ReasonOverride, ret.Reason)
+	assert.Contains(t, f.Readme(), "`foo`")
+
+	f.DeleteOverride("foo")
+	assert.False(t, f.Bool(context.Background(), "foo", true))
+
+	// overrides of removed flags are dropped on reload
+	require.NoError(t, f.SetOverride("foo", "on"))
+	require.NoError(t, f.Load(flags.Config{Flags: []flags.Flag{{Key: "bar"}}}))
+	assert.Empty(t, f.Overrides())
+}
+
+func TestNew_invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		flag flags.Flag
+	}{
+		{name: "empty key", flag: flags.Flag{}},
+		{name: "invalid type", flag: flags.Flag{Key: "foo", Type: "int"}},
+		{name: "variant without default", flag: flags.Flag{Key: "foo", Type: flags.TypeVariant, Variants: []flags.Variant{{Name: "a"}}}},
+		{name: "non bool variant", flag: flags.Flag{Key: "foo", Variants: []flags.Variant{{Name: "on", Value: "yes"}}, DefaultVariant: "on"}},
+		{name: "unknown default variant", flag: flags.Flag{Key: "foo", DefaultVariant: "maybe"}},
+		{name: "unknown rollout variant", flag: flags.Flag{Key: "foo", Rollout: []flags.Split{{Variant: "maybe", Weight: 1}}}},
+		{name: "rule without variant", flag: flags.Flag{Key: "foo", Rules: []flags.Rule{{}}}},
+		{name: "invalid operator", flag: flags.Flag{Key: "foo", Rules: []flags.Rule{{Variant: "on", Conditions: []flags.Condition{{Attribute: "a", Operator: "like"}}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := flags.New(zap.NewNop(), flags.Config{Flags: []flags.Flag{tt.flag}})
+			require.Error(t, err)
+		})
+	}
+
+	_, err := flags.New(zap.NewNop(), flags.Config{Flags: []flags.Flag{{Key: "foo"}, {Key: "foo"}}})
+	require.Error(t, err)
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	c := viper.New()
+	c.SetConfigType("yaml")
+	require.NoError(t, c.ReadConfig(bytes.NewBufferString(`
+flags:
+  - key: newCheckout
+    description: New checkout flow
+    rules:
+      - conditions:
+          - attribute: claim.roles
+            operator: in
+            values: [beta]
+        variant: "on"
+    rollout:
+      - variant: "on"
+        weight: 10
+      - variant: "off"
+        weight: 90
+  - key: buttonColor
+    type: variant
+    defaultVariant: Red
+    variants:
+      - name: Red
+        value: "#ff0000"
+      - name: Blue
+        value: "#0000ff"
+`)))
+
+	f, err := flags.NewFromConfig(zap.NewNop(), c, "flags")
+	require.NoError(t, err)
+
+	list := f.Flags()
+	require.Len(t, list, 2)
+	assert.Equal(t, "buttonColor", list[0].Key)
+	assert.Equal(t, "Red", list[0].DefaultVariant)
+	assert.Equal(t, "newCheckout", list[1].Key)
+	assert.Len(t, list[1].Rollout, 2)
+
+	ctx := flags.WithEvaluationContext(context.Background(), flags.NewEvaluationContext("foo").With("claim.roles", "beta"))
+	assert.True(t, f.Bool(ctx, "newCheckout", false))
+	assert.Equal(t, "#ff0000", f.String(ctx, "buttonColor", ""))
+}
@@ -0,0 +1,71 @@
+package flags
+
+import (
+	"slices"
+	"strings"
+)
+
+// Operator compares an attribute against the condition values.
+type Operator string
+
+const (
+	// OperatorIn matches if any attribute value equals any condition value.
+	OperatorIn Operator = "in"
+	// OperatorNotIn matches if no attribute value equals any condition value.
+	OperatorNotIn Operator = "notIn"
+	// OperatorPrefix matches if any attribute value starts with any condition value.
+	OperatorPrefix Operator = "prefix"
+	// OperatorSuffix matches if any attribute value ends with any condition value.
+	OperatorSuffix Operator = "suffix"
+	// OperatorContains matches if any attribute value contains any condition value.
+	OperatorContains Operator = "contains"
+	// OperatorExists matches if the attribute is set.
+	OperatorExists Operator = "exists"
+	// OperatorNotExists matches if the attribute is not set.
+	OperatorNotExists Operator = "notExists"
+)
+
+// String interface
+func (o Operator) String() string {
+	return string(o)
+}
+
+// valid returns true for all known operators.
+func (o Operator) valid() bool {
+	switch o {
+	case OperatorIn, OperatorNotIn, OperatorPrefix, OperatorSuffix, OperatorContains, OperatorExists, OperatorNotExists:
+		return true
+	default:
+		return false
+	}
+}
+
+// match applies the operator to the attribute values.
+func (o Operator) match(attributes, values []string) bool {
+	switch o {
+	case OperatorExists:
+		return len(attributes) > 0
+	case OperatorNotExists:
+		return len(attributes) == 0
+	case OperatorIn:
+		return o.any(attributes, values, func(a, v string) bool { return a == v })
+	case OperatorNotIn:
+		return !OperatorIn.match(attributes, values)
+	case OperatorPrefix:
+		return o.any(attributes, values, strings.HasPrefix)
+	case OperatorSuffix:
+		return o.any(attributes, values, strings.HasSuffix)
+	case OperatorContains:
+		return o.any(attributes, values, strings.Contains)
+	default:
+		return false
+	}
+}
+
+func (o Operator) any(attributes, values []string, fn func(a, v string) bool) bool {
+	return slices.ContainsFunc(attributes, func(a string) bool {
+		return slices.ContainsFunc(values, func(v string) bool {
+			return fn(a, v)
+		})
+	})
+}
@@ -0,0 +1,28 @@
+package flags
+
+// Reason describes why an evaluation resolved to its variant. The
+// values follow the OpenTelemetry feature_flag.result.reason enum with
+// the addition of ReasonOverride.
+type Reason string
+
+const (
+	// ReasonStatic is used when the flag has neither rules nor a rollout.
+	ReasonStatic Reason = "static"
+	// ReasonDefault is used when no rule or rollout applied.
+	ReasonDefault Reason = "default"
+	// ReasonTargetingMatch is used when a rule matched.
+	ReasonTargetingMatch Reason = "targeting_match"
+	// ReasonSplit is used when a percentage rollout assigned the variant.
+	ReasonSplit Reason = "split"
+	// ReasonDisabled is used when the flag is disabled.
+	ReasonDisabled Reason = "disabled"
+	// ReasonOverride is used when a runtime override is set.
+	ReasonOverride Reason = "override"
+	// ReasonError is used when the flag could not be evaluated.
+	ReasonError Reason = "error"
+)
+
+// String interface
+func (r Reason) String() string {
+	return string(r)
+}
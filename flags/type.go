@@ -0,0 +1,17 @@
+package flags
+
+// Type of a flag. Encoded as a string so YAML / JSON configuration
+// decodes directly without a custom unmarshaler.
+type Type string
+
+const (
+	// TypeBool flags serve the "on" (true) and "off" (false) variants.
+	TypeBool Type = "bool"
+	// TypeVariant flags serve arbitrary named variants.
+	TypeVariant Type = "variant"
+)
+
+// String interface
+func (t Type) String() string {
+	return string(t)
+}
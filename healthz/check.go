@@ -0,0 +1,42 @@
+package healthz
+
+import (
+	"context"
+	"errors"
+
+	"github.com/foomo/keel/interfaces"
+)
+
+var (
+	ErrProbeFailed    = errors.New("probe failed")
+	ErrUnhandledProbe = errors.New("unhandled healthz probe")
+)
+
+// Check calls the probe depending on the interface it implements.
+// Bool probes returning false are reported as ErrProbeFailed.
+func Check(ctx context.Context, probe any) error {
+	switch h := probe.(type) {
+	case BoolHealthzer:
+		if !h.Healthz() {
+			return ErrProbeFailed
+		}
+
+		return nil
+	case BoolHealthzerWithContext:
+		if !h.Healthz(ctx) {
+			return ErrProbeFailed
+		}
+
+		return nil
+	case ErrorHealthzer:
+		return h.Healthz()
+	case ErrorHealthzWithContext:
+		return h.Healthz(ctx)
+	case interfaces.ErrorPinger:
+		return h.Ping()
+	case interfaces.ErrorPingerWithContext:
+		return h.Ping(ctx)
+	default:
+		return ErrUnhandledProbe
+	}
+}
@@ -1 +1,18 @@
+// Package healthz defines the health probe types and interfaces.
+//
+// Probes are registered with a Type and exposed by service.NewHealthz:
+//
+//	/healthz            all probes except startup
+//	/healthz/liveness   always and liveness probes
+//	/healthz/readiness  always, readiness and degraded probes
+//	/healthz/startup    always and startup probes
+//	/healthz/degraded   degraded probes only
+//
+// Startup probes latch: once a startup probe succeeded it is not called
+// again and its last result is reported for the lifetime of the process.
+//
+// Degraded probes watch non-critical dependencies. Their failures are
+// reported with StatusDegraded in verbose and json output and in the
+// metrics, but they never fail the root, liveness or readiness checks.
+// Only /healthz/degraded responds with 503 if a degraded probe fails.
 package healthz
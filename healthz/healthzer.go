@@ -1,6 +1,9 @@
 package healthz
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type healther struct {
 	handle func(context.Context) error
@@ -39,3 +42,8 @@ type ErrorHealthzer interface {
 type ErrorHealthzWithContext interface {
 	Healthz(ctx context.Context) error
 }
+
+// TimeoutHealthzer interface overrides the default probe timeout
+type TimeoutHealthzer interface {
+	HealthzTimeout() time.Duration
+}
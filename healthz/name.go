@@ -0,0 +1,17 @@
+package healthz
+
+import (
+	"fmt"
+
+	"github.com/foomo/keel/interfaces"
+)
+
+// Name returns the name of the probe as exposed by interfaces.Namer or
+// its type name as a fallback
+func Name(probe any) string {
+	if v, ok := interfaces.IsNamer(probe); ok && v.Name() != "" {
+		return v.Name()
+	}
+
+	return fmt.Sprintf("%T", probe)
+}
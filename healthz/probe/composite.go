@@ -0,0 +1,92 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/foomo/keel/healthz"
+)
+
+// Composite probe combines probes
+type Composite struct {
+	name   string
+	any    bool
+	probes []any
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewAllOf returns a probe that is healthy if all probes are healthy
+func NewAllOf(name string, probes ...any) *Composite {
+	return &Composite{
+		name:   name,
+		probes: probes,
+	}
+}
+
+// NewAnyOf returns a probe that is healthy if at least one probe is
+// healthy e.g. for replicated upstreams
+func NewAnyOf(name string, probes ...any) *Composite {
+	return &Composite{
+		name:   name,
+		any:    true,
+		probes: probes,
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (p *Composite) Name() string {
+	return p.name
+}
+
+func (p *Composite) String() string {
+	names := make([]string, len(p.probes))
+	for i, probe := range p.probes {
+		names[i] = healthz.Name(probe)
+	}
+
+	op := "all of"
+	if p.any {
+		op = "any of"
+	}
+
+	return fmt.Sprintf("%s: %s", op, strings.Join(names, ", "))
+}
+
+func (p *Composite) Healthz(ctx context.Context) error {
+	if len(p.probes) == 0 {
+		return ErrNoProbes
+	}
+
+	errs := make([]error, len(p.probes))
+
+	var wg sync.WaitGroup
+	for i, probe := range p.probes {
+		wg.Go(func() {
+			if err := healthz.Check(ctx, probe); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", healthz.Name(probe), err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	if p.any && err != nil {
+		for _, e := range errs {
+			if e == nil {
+				return nil
+			}
+		}
+	}
+
+	return err
+}
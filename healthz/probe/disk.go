@@ -0,0 +1,51 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+)
+
+// Disk probe checks the free space of the file system containing path
+type Disk struct {
+	name    string
+	path    string
+	minFree uint64
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewDisk returns a probe failing once less than minFree bytes are available
+func NewDisk(name, path string, minFree uint64) *Disk {
+	return &Disk{
+		name:    name,
+		path:    path,
+		minFree: minFree,
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (p *Disk) Name() string {
+	return p.name
+}
+
+func (p *Disk) String() string {
+	return fmt.Sprintf("at least %d bytes free on `%s`", p.minFree, p.path)
+}
+
+func (p *Disk) Healthz(ctx context.Context) error {
+	free, err := diskFree(p.path)
+	if err != nil {
+		return err
+	}
+
+	if free < p.minFree {
+		return fmt.Errorf("%w: %d < %d bytes", ErrDiskSpace, free, p.minFree)
+	}
+
+	return nil
+}
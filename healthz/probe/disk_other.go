@@ -0,0 +1,7 @@
+//go:build windows || plan9 || js || wasip1
+
+package probe
+
+func diskFree(path string) (uint64, error) {
+	return 0, ErrUnsupportedSystem
+}
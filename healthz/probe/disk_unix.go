@@ -0,0 +1,17 @@
+//go:build !windows && !plan9 && !js && !wasip1
+
+package probe
+
+import (
+	"syscall"
+)
+
+// diskFree returns the bytes available to unprivileged users
+func diskFree(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil //nolint:unconvert
+}
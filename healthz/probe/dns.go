@@ -0,0 +1,56 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// DNS probe resolves a host
+type DNS struct {
+	name     string
+	host     string
+	resolver *net.Resolver
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewDNS returns a probe resolving host with the default resolver
+func NewDNS(name, host string) *DNS {
+	return NewDNSWithResolver(name, host, net.DefaultResolver)
+}
+
+func NewDNSWithResolver(name, host string, resolver *net.Resolver) *DNS {
+	return &DNS{
+		name:     name,
+		host:     host,
+		resolver: resolver,
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (p *DNS) Name() string {
+	return p.name
+}
+
+func (p *DNS) String() string {
+	return fmt.Sprintf("resolve `%s`", p.host)
+}
+
+func (p *DNS) Healthz(ctx context.Context) error {
+	addrs, err := p.resolver.LookupHost(ctx, p.host)
+	if err != nil {
+		return err
+	}
+
+	if len(addrs) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoAddresses, p.host)
+	}
+
+	return nil
+}
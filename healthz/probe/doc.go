@@ -0,0 +1,14 @@
+// Package probe provides ready-made healthz probes for common dependencies.
+//
+// All probes implement interfaces.Namer, fmt.Stringer and
+// healthz.ErrorHealthzWithContext so they can be registered directly and
+// describe themselves in the readme:
+//
+//	svr.AddReadinessHealthzers(
+//		probe.NewHTTP("catalog", "http://catalog/healthz"),
+//		probe.NewTCP("redis", "redis:6379"),
+//	)
+//	svr.AddLivenessHealthzers(
+//		probe.NewGoroutines("goroutines", 10000),
+//	)
+package probe
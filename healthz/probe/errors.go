@@ -0,0 +1,20 @@
+package probe
+
+import (
+	"errors"
+)
+
+var (
+	ErrUnexpectedStatus  = errors.New("unexpected status")
+	ErrUnexpectedBody    = errors.New("unexpected body")
+	ErrNoAddresses       = errors.New("no addresses resolved")
+	ErrDiskSpace         = errors.New("insufficient disk space")
+	ErrMemory            = errors.New("memory threshold exceeded")
+	ErrGoroutines        = errors.New("goroutine threshold exceeded")
+	ErrFileStale         = errors.New("file is stale")
+	ErrNoProbes          = errors.New("no probes")
+	ErrUnsupportedSystem = errors.New("unsupported system")
+)
+
+// maxBodySize limits the response body read by the http probe
+const maxBodySize = 1 << 20
@@ -0,0 +1,59 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// File probe checks that a file exists and optionally that it has been
+// modified recently e.g. a heartbeat or cache file written by a worker
+type File struct {
+	name   string
+	path   string
+	maxAge time.Duration
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewFile returns a probe failing if path does not exist or, if maxAge is
+// set, has not been modified within maxAge
+func NewFile(name, path string, maxAge time.Duration) *File {
+	return &File{
+		name:   name,
+		path:   path,
+		maxAge: maxAge,
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (p *File) Name() string {
+	return p.name
+}
+
+func (p *File) String() string {
+	if p.maxAge > 0 {
+		return fmt.Sprintf("`%s` modified within %s", p.path, p.maxAge)
+	}
+
+	return fmt.Sprintf("`%s` exists", p.path)
+}
+
+func (p *File) Healthz(ctx context.Context) error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+
+	if age := time.Since(info.ModTime()); p.maxAge > 0 && age > p.maxAge {
+		return fmt.Errorf("%w: %s modified %s ago", ErrFileStale, p.path, age.Truncate(time.Second))
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	keelhttp "github.com/foomo/keel/net/http"
+)
+
+type (
+	// HTTP probe requests an upstream and checks the response
+	HTTP struct {
+		name string
+		url  string
+		opts HTTPOptions
+	}
+	HTTPOptions struct {
+		// Client used for the request, defaults to the keel http client
+		Client *http.Client
+		// Method of the request
+		Method string
+		// Header sent with the request
+		Header http.Header
+		// Status codes considered healthy, defaults to any 2xx
+		Status []int
+		// Body must be contained in the response body if set
+		Body string
+	}
+	HTTPOption func(*HTTPOptions)
+)
+
+// GetDefaultHTTPOptions returns the default options
+func GetDefaultHTTPOptions() HTTPOptions {
+	return HTTPOptions{
+		Method: http.MethodGet,
+		Header: http.Header{},
+	}
+}
+
+// HTTPWithClient option
+func HTTPWithClient(v *http.Client) HTTPOption {
+	return func(o *HTTPOptions) {
+		o.Client = v
+	}
+}
+
+// HTTPWithMethod option
+func HTTPWithMethod(v string) HTTPOption {
+	return func(o *HTTPOptions) {
+		o.Method = v
+	}
+}
+
+// HTTPWithHeader option
+func HTTPWithHeader(key, value string) HTTPOption {
+	return func(o *HTTPOptions) {
+		o.Header.Add(key, value)
+	}
+}
+
+// HTTPWithStatus option
+func HTTPWithStatus(v ...int) HTTPOption {
+	return func(o *HTTPOptions) {
+		o.Status = v
+	}
+}
+
+// HTTPWithBody option
+func HTTPWithBody(v string) HTTPOption {
+	return func(o *HTTPOptions) {
+		o.Body = v
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+func NewHTTP(name, url string, opts ...HTTPOption) *HTTP {
+	options := GetDefaultHTTPOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	if options.Client == nil {
+		options.Client = keelhttp.NewHTTPClient()
+	}
+
+	return &HTTP{
+		name: name,
+		url:  url,
+		opts: options,
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (p *HTTP) Name() string {
+	return p.name
+}
+
+func (p *HTTP) String() string {
+	ret := fmt.Sprintf("%s `%s`", p.opts.Method, p.url)
+	if len(p.opts.Status) > 0 {
+		ret += fmt.Sprintf(" expecting status %v", p.opts.Status)
+	}
+
+	if p.opts.Body != "" {
+		ret += fmt.Sprintf(" and body containing %q", p.opts.Body)
+	}
+
+	return ret
+}
+
+func (p *HTTP) Healthz(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, p.opts.Method, p.url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header = p.opts.Header.Clone()
+
+	resp, err := p.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if len(p.opts.Status) > 0 && !slices.Contains(p.opts.Status, resp.StatusCode) ||
+		len(p.opts.Status) == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	if p.opts.Body == "" {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(string(body), p.opts.Body) {
+		return ErrUnexpectedBody
+	}
+
+	return nil
+}
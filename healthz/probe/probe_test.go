@@ -0,0 +1,126 @@
+package probe_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/foomo/keel/healthz"
+	"github.com/foomo/keel/healthz/probe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP(t *testing.T) {
+	t.Parallel()
+
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(svr.Close)
+
+	tests := []struct {
+		name    string
+		probe   *probe.HTTP
+		wantErr error
+	}{
+		{name: "ok", probe: probe.NewHTTP("up", svr.URL)},
+		{name: "status", probe: probe.NewHTTP("down", svr.URL+"/down"), wantErr: probe.ErrUnexpectedStatus},
+		{name: "expected status", probe: probe.NewHTTP("down", svr.URL+"/down", probe.HTTPWithStatus(http.StatusServiceUnavailable))},
+		{name: "body", probe: probe.NewHTTP("body", svr.URL, probe.HTTPWithBody(`"ok"`))},
+		{name: "unexpected body", probe: probe.NewHTTP("body", svr.URL, probe.HTTPWithBody(`"failed"`)), wantErr: probe.ErrUnexpectedBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.ErrorIs(t, tt.probe.Healthz(t.Context()), tt.wantErr)
+		})
+	}
+}
+
+func TestTCP(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := ln.Addr().String()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			_ = conn.Close()
+		}
+	}()
+
+	require.NoError(t, probe.NewTCP("tcp", addr).Healthz(t.Context()))
+	require.NoError(t, ln.Close())
+	require.Error(t, probe.NewTCP("tcp", addr).Healthz(t.Context()))
+}
+
+func TestDNS(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, probe.NewDNS("dns", "localhost").Healthz(t.Context()))
+	require.Error(t, probe.NewDNS("dns", "does-not-exist.invalid").Healthz(t.Context()))
+}
+
+func TestDisk(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, probe.NewDisk("disk", t.TempDir(), 1).Healthz(t.Context()))
+	require.ErrorIs(t, probe.NewDisk("disk", t.TempDir(), 1<<62).Healthz(t.Context()), probe.ErrDiskSpace)
+}
+
+func TestRuntime(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, probe.NewMemory("memory").Healthz(t.Context()))
+	require.ErrorIs(t, probe.NewMemory("memory", probe.MemoryWithMaxHeapAlloc(1)).Healthz(t.Context()), probe.ErrMemory)
+	require.NoError(t, probe.NewGoroutines("goroutines", 100000).Healthz(t.Context()))
+	require.ErrorIs(t, probe.NewGoroutines("goroutines", 0).Healthz(t.Context()), probe.ErrGoroutines)
+}
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), "heartbeat")
+
+	require.ErrorIs(t, probe.NewFile("file", filename, 0).Healthz(t.Context()), os.ErrNotExist)
+	require.NoError(t, os.WriteFile(filename, nil, 0o600))
+	require.NoError(t, probe.NewFile("file", filename, time.Minute).Healthz(t.Context()))
+	require.NoError(t, os.Chtimes(filename, time.Now(), time.Now().Add(-time.Hour)))
+	require.ErrorIs(t, probe.NewFile("file", filename, time.Minute).Healthz(t.Context()), probe.ErrFileStale)
+}
+
+func TestComposite(t *testing.T) {
+	t.Parallel()
+
+	ok := healthz.NewHealthzerFn(func(ctx context.Context) error { return nil })
+	failed := healthz.NewHealthzerFn(func(ctx context.Context) error { return errors.New("failed") })
+
+	require.NoError(t, probe.NewAllOf("all", ok, ok).Healthz(t.Context()))
+	require.Error(t, probe.NewAllOf("all", ok, failed).Healthz(t.Context()))
+	require.NoError(t, probe.NewAnyOf("any", failed, ok).Healthz(t.Context()))
+	require.Error(t, probe.NewAnyOf("any", failed, failed).Healthz(t.Context()))
+	require.ErrorIs(t, probe.NewAnyOf("any").Healthz(t.Context()), probe.ErrNoProbes)
+
+	assert.Equal(t, "any of: tcp, dns", probe.NewAnyOf("any", probe.NewTCP("tcp", ":0"), probe.NewDNS("dns", "localhost")).String())
+}
@@ -0,0 +1,112 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+type (
+	// Memory probe checks the heap size and the GC CPU fraction
+	Memory struct {
+		name string
+		opts MemoryOptions
+	}
+	MemoryOptions struct {
+		// MaxHeapAlloc in bytes, 0 disables the check
+		MaxHeapAlloc uint64
+		// MaxGCCPUFraction of the available CPU time used by the GC since
+		// the program started, 0 disables the check
+		MaxGCCPUFraction float64
+	}
+	MemoryOption func(*MemoryOptions)
+)
+
+// MemoryWithMaxHeapAlloc option
+func MemoryWithMaxHeapAlloc(v uint64) MemoryOption {
+	return func(o *MemoryOptions) {
+		o.MaxHeapAlloc = v
+	}
+}
+
+// MemoryWithMaxGCCPUFraction option
+func MemoryWithMaxGCCPUFraction(v float64) MemoryOption {
+	return func(o *MemoryOptions) {
+		o.MaxGCCPUFraction = v
+	}
+}
+
+// Goroutines probe checks the number of goroutines
+type Goroutines struct {
+	name string
+	max  int
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+func NewMemory(name string, opts ...MemoryOption) *Memory {
+	var options MemoryOptions
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return &Memory{
+		name: name,
+		opts: options,
+	}
+}
+
+func NewGoroutines(name string, maxGoroutines int) *Goroutines {
+	return &Goroutines{
+		name: name,
+		max:  maxGoroutines,
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (p *Memory) Name() string {
+	return p.name
+}
+
+func (p *Memory) String() string {
+	return fmt.Sprintf("heap alloc <= %d bytes, gc cpu fraction <= %g", p.opts.MaxHeapAlloc, p.opts.MaxGCCPUFraction)
+}
+
+func (p *Memory) Healthz(ctx context.Context) error {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	if p.opts.MaxHeapAlloc > 0 && stats.HeapAlloc > p.opts.MaxHeapAlloc {
+		return fmt.Errorf("%w: heap alloc %d > %d bytes", ErrMemory, stats.HeapAlloc, p.opts.MaxHeapAlloc)
+	}
+
+	if p.opts.MaxGCCPUFraction > 0 && stats.GCCPUFraction > p.opts.MaxGCCPUFraction {
+		return fmt.Errorf("%w: gc cpu fraction %g > %g", ErrMemory, stats.GCCPUFraction, p.opts.MaxGCCPUFraction)
+	}
+
+	return nil
+}
+
+func (p *Goroutines) Name() string {
+	return p.name
+}
+
+func (p *Goroutines) String() string {
+	return fmt.Sprintf("at most %d goroutines", p.max)
+}
+
+func (p *Goroutines) Healthz(ctx context.Context) error {
+	if n := runtime.NumGoroutine(); n > p.max {
+		return fmt.Errorf("%w: %d > %d", ErrGoroutines, n, p.max)
+	}
+
+	return nil
+}
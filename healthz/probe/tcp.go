@@ -0,0 +1,47 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// TCP probe dials an address
+type TCP struct {
+	name   string
+	addr   string
+	dialer *net.Dialer
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+func NewTCP(name, addr string) *TCP {
+	return &TCP{
+		name:   name,
+		addr:   addr,
+		dialer: &net.Dialer{},
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (p *TCP) Name() string {
+	return p.name
+}
+
+func (p *TCP) String() string {
+	return fmt.Sprintf("dial tcp `%s`", p.addr)
+}
+
+func (p *TCP) Healthz(ctx context.Context) error {
+	conn, err := p.dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
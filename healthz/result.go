@@ -0,0 +1,56 @@
+package healthz
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProbeResult is the outcome of a single probe call
+type ProbeResult struct {
+	// Name of the probe, see Name
+	Name string `json:"name"`
+	// Type the probe has been registered with
+	Type Type `json:"type"`
+	// Status of the probe
+	Status Status `json:"status"`
+	// Latency of the probe call
+	Latency time.Duration `json:"latency"`
+	// Error message if the probe failed
+	Error string `json:"error,omitempty"`
+	// Failures is the number of consecutive failed calls
+	Failures int `json:"failures,omitempty"`
+	// Timestamp of the probe call
+	Timestamp time.Time `json:"timestamp"`
+	// Age of a cached result
+	Age time.Duration `json:"age,omitempty"`
+}
+
+// Result is the aggregated outcome of all probes of a check
+type Result struct {
+	// Status is StatusFailed if any probe failed or StatusDegraded if
+	// only degraded probes failed
+	Status Status `json:"status"`
+	// Probes lists every probe that has been called
+	Probes []ProbeResult `json:"probes"`
+}
+
+// MarshalJSON interface encodes the latency as a duration string
+func (r ProbeResult) MarshalJSON() ([]byte, error) {
+	type alias ProbeResult
+
+	var age string
+	if r.Age > 0 {
+		age = r.Age.String()
+	}
+
+	return json.Marshal(struct {
+		alias
+
+		Latency string `json:"latency"`
+		Age     string `json:"age,omitempty"`
+	}{
+		alias:   alias(r),
+		Latency: r.Latency.String(),
+		Age:     age,
+	})
+}
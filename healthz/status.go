@@ -0,0 +1,18 @@
+package healthz
+
+// Status of a probe or an aggregated check
+type Status string
+
+const (
+	// StatusOK the probe passed
+	StatusOK Status = "ok"
+	// StatusFailed the probe failed
+	StatusFailed Status = "failed"
+	// StatusDegraded a non-critical probe failed
+	StatusDegraded Status = "degraded"
+)
+
+// String interface
+func (s Status) String() string {
+	return string(s)
+}
@@ -23,6 +23,9 @@ const (
 	// > a deadlock, where an application is running, but unable to make progress. Restarting a container in such a state
 	// > can help to make the application more available despite bugs.
 	TypeLiveness Type = "liveness"
+	// TypeDegraded will run on /healthz/degraded checks and is reported on readiness checks
+	// without failing them. Use it for non-critical dependencies the service can operate without.
+	TypeDegraded Type = "degraded"
 )
 
 // String interface
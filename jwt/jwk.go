@@ -0,0 +1,167 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// JSONWebKey represents a public key as defined in RFC 7517
+	JSONWebKey struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid,omitempty"`
+		Use string `json:"use,omitempty"`
+		Alg string `json:"alg,omitempty"`
+		// RSA
+		N string `json:"n,omitempty"`
+		E string `json:"e,omitempty"`
+		// EC and OKP
+		Crv string `json:"crv,omitempty"`
+		X   string `json:"x,omitempty"`
+		Y   string `json:"y,omitempty"`
+	}
+	// JSONWebKeySet represents a set of public keys as defined in RFC 7517
+	JSONWebKeySet struct {
+		Keys []JSONWebKey `json:"keys"`
+	}
+)
+
+// NewJSONWebKey returns the JSON Web Key of the rsa, ecdsa or ed25519 public key
+func NewJSONWebKey(key Key) (JSONWebKey, error) {
+	ret := JSONWebKey{
+		Kid: key.ID,
+		Use: "sig",
+		Alg: key.Algorithm,
+	}
+
+	switch v := key.Public.(type) {
+	case *rsa.PublicKey:
+		ret.Kty = "RSA"
+		ret.N = base64.RawURLEncoding.EncodeToString(v.N.Bytes())
+		ret.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(v.E)).Bytes())
+	case *ecdsa.PublicKey:
+		b, err := v.Bytes()
+		if err != nil {
+			return JSONWebKey{}, errors.Wrap(err, "invalid ecdsa key")
+		}
+
+		size := (len(b) - 1) / 2
+		ret.Kty = "EC"
+		ret.Crv = v.Curve.Params().Name
+		ret.X = base64.RawURLEncoding.EncodeToString(b[1 : 1+size])
+		ret.Y = base64.RawURLEncoding.EncodeToString(b[1+size:])
+	case ed25519.PublicKey:
+		ret.Kty = "OKP"
+		ret.Crv = "Ed25519"
+		ret.X = base64.RawURLEncoding.EncodeToString(v)
+	default:
+		return JSONWebKey{}, errors.Errorf("unsupported public key type %T", key.Public)
+	}
+
+	return ret, nil
+}
+
+// PublicKey returns the rsa, ecdsa or ed25519 public key
+func (k JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid rsa modulus")
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid rsa exponent")
+		}
+
+		if len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("unsupported curve: " + k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid ec x coordinate")
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid ec y coordinate")
+		}
+
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid ec coordinate size")
+		}
+
+		return ecdsa.ParseUncompressedPublicKey(curve, slices.Concat([]byte{4}, x, y))
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, errors.New("unsupported curve: " + k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid ed25519 key")
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key size")
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, errors.New("unsupported key type: " + k.Kty)
+	}
+}
+
+// Algorithms returns the signing algorithms the key may be used with
+func (k JSONWebKey) Algorithms() []string {
+	if k.Alg != "" {
+		return []string{k.Alg}
+	}
+
+	switch k.Kty {
+	case "RSA":
+		return []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	case "EC":
+		switch k.Crv {
+		case "P-256":
+			return []string{"ES256"}
+		case "P-384":
+			return []string{"ES384"}
+		case "P-521":
+			return []string{"ES512"}
+		}
+	case "OKP":
+		return []string{"EdDSA"}
+	}
+
+	return nil
+}
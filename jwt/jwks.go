@@ -0,0 +1,266 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/foomo/keel/log"
+)
+
+// DefaultJWKSTimeout bounds fetching the key set and the oidc discovery
+const DefaultJWKSTimeout = 10 * time.Second
+
+var ErrUnknownKey = errors.New("unknown key identifier")
+
+type (
+	// JWKS is a key source backed by a JSON Web Key Set. Keys are cached for
+	// the TTL and refreshed once a token references an unknown key id, at
+	// most once per MinRefreshInterval.
+	JWKS struct {
+		l                  *zap.Logger
+		fetch              func(ctx context.Context) ([]byte, error)
+		httpClient         *http.Client
+		ttl                time.Duration
+		minRefreshInterval time.Duration
+		refreshTimeout     time.Duration
+		clock              func() time.Time
+		keys               map[string]jwksKey
+		fetched            time.Time
+		refreshed          time.Time
+		keysLock           sync.RWMutex
+		group              singleflight.Group
+	}
+	JWKSOption func(*JWKS)
+	jwksKey    struct {
+		public     crypto.PublicKey
+		algorithms []string
+	}
+)
+
+// JWKSWithHTTPClient option
+func JWKSWithHTTPClient(v *http.Client) JWKSOption {
+	return func(o *JWKS) {
+		o.httpClient = v
+	}
+}
+
+// JWKSWithTTL option sets how long fetched keys are cached
+func JWKSWithTTL(v time.Duration) JWKSOption {
+	return func(o *JWKS) {
+		o.ttl = v
+	}
+}
+
+// JWKSWithMinRefreshInterval option limits refreshes triggered by unknown key ids
+func JWKSWithMinRefreshInterval(v time.Duration) JWKSOption {
+	return func(o *JWKS) {
+		o.minRefreshInterval = v
+	}
+}
+
+// JWKSWithRefreshTimeout option bounds a refresh, which all requests with an unknown key id wait on
+func JWKSWithRefreshTimeout(v time.Duration) JWKSOption {
+	return func(o *JWKS) {
+		o.refreshTimeout = v
+	}
+}
+
+// JWKSWithClock option sets the clock, e.g. for testing
+func JWKSWithClock(v func() time.Time) JWKSOption {
+	return func(o *JWKS) {
+		o.clock = v
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewJWKSFromURL returns a key source fetching the key set from the url
+func NewJWKSFromURL(l *zap.Logger, url string, opts ...JWKSOption) *JWKS {
+	inst := newJWKS(l, opts...)
+	inst.fetch = func(ctx context.Context) ([]byte, error) {
+		return httpGet(ctx, inst.httpClient, url)
+	}
+
+	return inst
+}
+
+// NewJWKSFromFile returns a key source reading the key set from the file
+func NewJWKSFromFile(l *zap.Logger, filename string, opts ...JWKSOption) *JWKS {
+	inst := newJWKS(l, opts...)
+	inst.fetch = func(ctx context.Context) ([]byte, error) {
+		return os.ReadFile(filename)
+	}
+
+	return inst
+}
+
+func newJWKS(l *zap.Logger, opts ...JWKSOption) *JWKS {
+	inst := &JWKS{
+		l:                  l,
+		httpClient:         &http.Client{Timeout: DefaultJWKSTimeout},
+		ttl:                time.Hour,
+		minRefreshInterval: time.Minute,
+		refreshTimeout:     DefaultJWKSTimeout,
+		clock:              time.Now,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(inst)
+		}
+	}
+
+	return inst
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// Refresh fetches the key set
+func (k *JWKS) Refresh(ctx context.Context) error {
+	_, err, _ := k.group.Do("refresh", func() (any, error) {
+		if k.refreshTimeout > 0 {
+			var cancel context.CancelFunc
+
+			ctx, cancel = context.WithTimeout(ctx, k.refreshTimeout)
+			defer cancel()
+		}
+
+		return nil, k.refresh(ctx)
+	})
+
+	return err
+}
+
+// Key returns the public key and its algorithms by key id, refreshing the
+// key set if it expired or the key id is unknown
+func (k *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, []string, error) {
+	now := k.clock()
+
+	k.keysLock.RLock()
+	key, ok := k.keys[kid]
+	expired := k.fetched.IsZero() || now.Sub(k.fetched) > k.ttl
+	throttled := now.Sub(k.refreshed) < k.minRefreshInterval
+	k.keysLock.RUnlock()
+
+	if (expired || !ok) && !throttled {
+		if err := k.Refresh(ctx); err != nil {
+			// keep serving the cached keys
+			log.WithError(k.l, err).Warn("failed to refresh jwks")
+		}
+
+		k.keysLock.RLock()
+		key, ok = k.keys[kid]
+		k.keysLock.RUnlock()
+	}
+
+	if !ok {
+		return nil, nil, errors.Wrap(ErrUnknownKey, kid)
+	}
+
+	return key.public, key.algorithms, nil
+}
+
+// KeyFunc returns a jwt.Keyfunc looking up the key by the token's key id and
+// enforcing its algorithms
+func (k *JWKS) KeyFunc() jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("missing key identifier")
+		}
+
+		public, algorithms, err := k.Key(context.Background(), kid)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(algorithms, token.Method.Alg()) {
+			return nil, errors.New("unexpected jwt signing method: " + token.Method.Alg())
+		}
+
+		return public, nil
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func (k *JWKS) refresh(ctx context.Context) error {
+	k.keysLock.Lock()
+	k.refreshed = k.clock()
+	k.keysLock.Unlock()
+
+	body, err := k.fetch(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch jwks")
+	}
+
+	var set JSONWebKeySet
+	if err := json.Unmarshal(body, &set); err != nil {
+		return errors.Wrap(err, "failed to decode jwks")
+	}
+
+	keys := make(map[string]jwksKey, len(set.Keys))
+
+	for _, value := range set.Keys {
+		if value.Use != "" && value.Use != "sig" {
+			continue
+		}
+
+		public, err := value.PublicKey()
+		if err != nil {
+			log.WithError(k.l, err).Warn("skipping invalid jwk", log.FValue(value.Kid))
+			continue
+		}
+
+		keys[value.Kid] = jwksKey{
+			public:     public,
+			algorithms: value.Algorithms(),
+		}
+	}
+
+	k.keysLock.Lock()
+	k.keys = keys
+	k.fetched = k.clock()
+	k.keysLock.Unlock()
+
+	k.l.Debug("refreshed jwks", log.FNum(len(keys)))
+
+	return nil
+}
+
+func httpGet(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d: %s", resp.StatusCode, url)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
@@ -0,0 +1,213 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	keeljwt "github.com/foomo/keel/jwt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// jwksServer is a stand-in identity provider
+type jwksServer struct {
+	*httptest.Server
+
+	keys     []keeljwt.JSONWebKey
+	keysLock sync.Mutex
+	requests atomic.Int32
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(keeljwt.OIDCConfiguration{
+			Issuer:  s.URL,
+			JWKSURI: s.URL + "/jwks.json",
+		})
+	})
+	mux.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		s.keysLock.Lock()
+		defer s.keysLock.Unlock()
+
+		_ = json.NewEncoder(w).Encode(keeljwt.JSONWebKeySet{Keys: s.keys})
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *jwksServer) add(key keeljwt.JSONWebKey) {
+	s.keysLock.Lock()
+	defer s.keysLock.Unlock()
+
+	s.keys = append(s.keys, key)
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) keeljwt.JSONWebKey {
+	return keeljwt.JSONWebKey{
+		Kty: "EC",
+		Kid: kid,
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) keeljwt.JSONWebKey {
+	return keeljwt.JSONWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	ret, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return ret
+}
+
+func TestNewJWKSFromOIDC(t *testing.T) {
+	t.Parallel()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	server := newJWKSServer(t)
+	server.add(ecJWK("ec", ecKey))
+
+	jwks, config, err := keeljwt.NewJWKSFromOIDC(t.Context(), zap.NewNop(), server.URL,
+		keeljwt.JWKSWithMinRefreshInterval(time.Hour),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/jwks.json", config.JWKSURI)
+
+	inst := keeljwt.NewWithJWKS(jwks,
+		keeljwt.WithIssuer(server.URL),
+		keeljwt.WithAudience("keel"),
+		keeljwt.WithLeeway(time.Minute),
+	)
+
+	claims := func(issuer, audience string) jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-30 * time.Second)),
+		}
+	}
+
+	// expired within the leeway
+	token, err := inst.ParseWithClaims(sign(t, jwt.SigningMethodES256, "ec", ecKey, claims(server.URL, "keel")), &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+	assert.True(t, token.Valid)
+
+	_, err = inst.ParseWithClaims(sign(t, jwt.SigningMethodES256, "ec", ecKey, claims("other", "keel")), &jwt.RegisteredClaims{})
+	require.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+	_, err = inst.ParseWithClaims(sign(t, jwt.SigningMethodES256, "ec", ecKey, claims(server.URL, "other")), &jwt.RegisteredClaims{})
+	require.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+
+	// algorithm confusion is rejected
+	_, err = inst.ParseWithClaims(sign(t, jwt.SigningMethodHS256, "ec", []byte("secret"), claims(server.URL, "keel")), &jwt.RegisteredClaims{})
+	require.Error(t, err)
+
+	// unknown keys are rate limited
+	server.add(rsaJWK("rsa", rsaKey))
+	_, err = inst.ParseWithClaims(sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(server.URL, "keel")), &jwt.RegisteredClaims{})
+	require.ErrorIs(t, err, keeljwt.ErrUnknownKey)
+	assert.Equal(t, int32(1), server.requests.Load())
+}
+
+func TestJWKSRefresh(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	server := newJWKSServer(t)
+
+	var now atomic.Int64
+	now.Store(time.Now().UnixNano())
+
+	jwks := keeljwt.NewJWKSFromURL(zap.NewNop(), server.URL+"/jwks.json",
+		keeljwt.JWKSWithTTL(time.Hour),
+		keeljwt.JWKSWithMinRefreshInterval(time.Minute),
+		keeljwt.JWKSWithClock(func() time.Time { return time.Unix(0, now.Load()) }),
+	)
+	require.NoError(t, jwks.Refresh(t.Context()))
+
+	// unknown key id after rotation refreshes once the interval passed
+	server.add(rsaJWK("rotated", rsaKey))
+
+	_, _, err = jwks.Key(t.Context(), "rotated")
+	require.ErrorIs(t, err, keeljwt.ErrUnknownKey)
+
+	now.Add(int64(time.Minute))
+
+	_, algorithms, err := jwks.Key(t.Context(), "rotated")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"RS256"}, algorithms)
+	assert.Equal(t, int32(2), server.requests.Load())
+
+	// known keys are served from the cache until the ttl expires
+	_, _, err = jwks.Key(t.Context(), "rotated")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), server.requests.Load())
+
+	now.Add(int64(2 * time.Hour))
+
+	_, _, err = jwks.Key(t.Context(), "rotated")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), server.requests.Load())
+}
+
+func TestJWKSRefreshTimeout(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	jwks := keeljwt.NewJWKSFromURL(zap.NewNop(), server.URL, keeljwt.JWKSWithRefreshTimeout(50*time.Millisecond))
+
+	// a stuck endpoint does not block the key lookup
+	start := time.Now()
+	_, _, err := jwks.Key(t.Context(), "unknown")
+	require.ErrorIs(t, err, keeljwt.ErrUnknownKey)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,74 @@
+package jwt
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	httputils "github.com/foomo/keel/utils/net/http"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/foomo/keel/log"
+)
+
+// ContentTypeJWKSet of the published key set
+const ContentTypeJWKSet = "application/jwk-set+json"
+
+// JWKS returns the public keys of the current and deprecated keys, leaving out hmac keys
+func (j *JWT) JWKS() JSONWebKeySet {
+	ret := JSONWebKeySet{
+		Keys: []JSONWebKey{},
+	}
+
+	j.keysLock.RLock()
+	keys := make([]Key, 0, len(j.DeprecatedKeys)+1)
+	if j.Key.Public != nil {
+		keys = append(keys, j.Key)
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(j.DeprecatedKeys)) {
+		keys = append(keys, j.DeprecatedKeys[id])
+	}
+	j.keysLock.RUnlock()
+
+	for _, key := range keys {
+		if key.Symmetric() {
+			continue
+		}
+
+		if value, err := NewJSONWebKey(key); err == nil {
+			ret.Keys = append(ret.Keys, value)
+		}
+	}
+
+	return ret
+}
+
+// NewJWKSHandler returns a handler publishing the public keys of the JWT so
+// that other services can verify its tokens e.g. through NewJWKSFromURL
+func NewJWKSHandler(l *zap.Logger, j *JWT, maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			httputils.MethodNotAllowedServerError(l, w, r, errors.Errorf("method %s not allowed", r.Method))
+			return
+		}
+
+		body, err := json.Marshal(j.JWKS())
+		if err != nil {
+			httputils.InternalServerError(l, w, r, err)
+			return
+		}
+
+		w.Header().Set(keelhttp.HeaderContentType, ContentTypeJWKSet)
+		w.Header().Set(keelhttp.HeaderCacheControl, "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+
+		if _, err := w.Write(body); err != nil {
+			log.WithError(l, err).Debug("failed to write jwks")
+		}
+	})
+}
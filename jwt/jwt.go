@@ -1,6 +1,9 @@
 package jwt
 
 import (
+	"sync"
+	"time"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -12,6 +15,11 @@ type (
 		KeyFunc jwt.Keyfunc
 		// DeprecatedKeys  e.g. due to rotation
 		DeprecatedKeys map[string]Key
+		// ParserOptions e.g. issuer, audience and leeway validation
+		ParserOptions []jwt.ParserOption
+		// keysLock guards the keys swapped through SetKeys
+		keysLock       sync.RWMutex
+		defaultKeyFunc bool
 	}
 	Option func(*JWT)
 )
@@ -38,6 +46,34 @@ func WithDeprecatedKeys(v ...Key) Option {
 	}
 }
 
+// WithIssuer middleware option validates the iss claim
+func WithIssuer(v string) Option {
+	return func(o *JWT) {
+		o.ParserOptions = append(o.ParserOptions, jwt.WithIssuer(v))
+	}
+}
+
+// WithAudience middleware option validates that the aud claim contains any of the given values
+func WithAudience(v ...string) Option {
+	return func(o *JWT) {
+		o.ParserOptions = append(o.ParserOptions, jwt.WithAudience(v...))
+	}
+}
+
+// WithLeeway middleware option allows for clock skew when validating the time based claims
+func WithLeeway(v time.Duration) Option {
+	return func(o *JWT) {
+		o.ParserOptions = append(o.ParserOptions, jwt.WithLeeway(v))
+	}
+}
+
+// WithParserOptions middleware option
+func WithParserOptions(v ...jwt.ParserOption) Option {
+	return func(o *JWT) {
+		o.ParserOptions = append(o.ParserOptions, v...)
+	}
+}
+
 // New returns a new JWT for the given key and optional old keys e.g. due to rotation
 func New(key Key, opts ...Option) *JWT {
 	inst := &JWT{
@@ -52,19 +88,57 @@ func New(key Key, opts ...Option) *JWT {
 
 	if inst.KeyFunc == nil {
 		inst.KeyFunc = DefaultKeyFunc(key, inst.DeprecatedKeys)
+		inst.defaultKeyFunc = true
 	}
 
 	return inst
 }
 
+// NewWithJWKS returns a new JWT validating tokens with the keys of the given key set
+func NewWithJWKS(jwks *JWKS, opts ...Option) *JWT {
+	return New(Key{}, append([]Option{WithKeyFun(jwks.KeyFunc())}, opts...)...)
+}
+
+// SetKeys atomically replaces the signing and deprecated keys, e.g. on rotation.
+// A custom KeyFunc is kept as it is.
+func (j *JWT) SetKeys(key Key, deprecatedKeys ...Key) {
+	keys := make(map[string]Key, len(deprecatedKeys))
+	for _, value := range deprecatedKeys {
+		keys[value.ID] = value
+	}
+
+	j.keysLock.Lock()
+	defer j.keysLock.Unlock()
+
+	j.Key = key
+	j.DeprecatedKeys = keys
+
+	if j.defaultKeyFunc {
+		j.KeyFunc = DefaultKeyFunc(key, keys)
+	}
+}
+
 func (j *JWT) GetSignedToken(claims jwt.Claims) (string, error) {
+	j.keysLock.RLock()
+	key := j.Key
+	j.keysLock.RUnlock()
+
+	method, err := key.SigningMethod()
+	if err != nil {
+		return "", err
+	}
+
 	// create token
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	token.Header["kid"] = j.Key.ID
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.ID
 
-	return token.SignedString(j.Key.Private)
+	return token.SignedString(key.Private)
 }
 
 func (j *JWT) ParseWithClaims(token string, claims jwt.Claims) (*jwt.Token, error) {
-	return jwt.ParseWithClaims(token, claims, j.KeyFunc)
+	j.keysLock.RLock()
+	keyFunc := j.KeyFunc
+	j.keysLock.RUnlock()
+
+	return jwt.ParseWithClaims(token, claims, keyFunc, j.ParserOptions...)
 }
@@ -2,6 +2,10 @@ package jwt
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/hex"
@@ -15,27 +19,51 @@ import (
 type Key struct {
 	// ID (required) represents the key identifier e.g. the md5 representation of the public key
 	ID string
-	// Public (required) rsa key
-	Public *rsa.PublicKey
-	// Private (optional) rsa key
-	Private *rsa.PrivateKey
+	// Algorithm (required) the key is used with e.g. RS256, ES256, EdDSA or HS256
+	Algorithm string
+	// Public (required) rsa, ecdsa or ed25519 key or the hmac secret
+	Public crypto.PublicKey
+	// Private (optional) rsa, ecdsa or ed25519 key or the hmac secret
+	Private crypto.PrivateKey
 }
 
-// NewKey return a new Key
-func NewKey(id string, public *rsa.PublicKey, private *rsa.PrivateKey) Key {
+// NewKey return a new Key with the default algorithm of the public key type
+func NewKey(id string, public crypto.PublicKey, private crypto.PrivateKey) Key {
 	return Key{
-		ID:      id,
-		Public:  public,
-		Private: private,
+		ID:        id,
+		Algorithm: defaultAlgorithm(public),
+		Public:    public,
+		Private:   private,
 	}
 }
 
-// NewKeyFromFilenames returns a new Key from the given file names
+// NewKeyWithAlgorithm returns a new Key for the given algorithm
+func NewKeyWithAlgorithm(id, algorithm string, public crypto.PublicKey, private crypto.PrivateKey) (Key, error) {
+	key := Key{
+		ID:        id,
+		Algorithm: algorithm,
+		Public:    public,
+		Private:   private,
+	}
+
+	if err := key.Validate(); err != nil {
+		return Key{}, err
+	}
+
+	return key, nil
+}
+
+// NewHMACKey returns a new symmetric Key for HS256, HS384 or HS512
+func NewHMACKey(id, algorithm string, secret []byte) (Key, error) {
+	return NewKeyWithAlgorithm(id, algorithm, secret, secret)
+}
+
+// NewKeyFromFilenames returns a new Key from the given rsa, ecdsa or ed25519 pem file names
 func NewKeyFromFilenames(publicKeyPemFilename, privateKeyPemFilename string) (Key, error) {
 	var (
 		id      string
-		public  *rsa.PublicKey
-		private *rsa.PrivateKey
+		public  crypto.PublicKey
+		private crypto.PrivateKey
 	)
 
 	// load private key
@@ -43,7 +71,7 @@ func NewKeyFromFilenames(publicKeyPemFilename, privateKeyPemFilename string) (Ke
 	if privateKeyPemFilename != "" {
 		if value, err := os.ReadFile(privateKeyPemFilename); err != nil {
 			return Key{}, errors.Wrap(err, "failed to read private key: "+privateKeyPemFilename)
-		} else if key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(strings.ReplaceAll(string(value), `\n`, "\n"))); err != nil {
+		} else if key, err := parsePrivateKeyFromPEM([]byte(strings.ReplaceAll(string(value), `\n`, "\n"))); err != nil {
 			return Key{}, errors.Wrap(err, "failed to parse private key: "+privateKeyPemFilename)
 		} else {
 			private = key
@@ -53,7 +81,7 @@ func NewKeyFromFilenames(publicKeyPemFilename, privateKeyPemFilename string) (Ke
 	// load public key
 	if v, err := os.ReadFile(publicKeyPemFilename); err != nil {
 		return Key{}, errors.Wrap(err, "failed to read public key: "+publicKeyPemFilename)
-	} else if key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(strings.ReplaceAll(string(v), `\n`, "\n"))); err != nil {
+	} else if key, err := parsePublicKeyFromPEM([]byte(strings.ReplaceAll(string(v), `\n`, "\n"))); err != nil {
 		return Key{}, errors.Wrap(err, "failed to parse public key: "+publicKeyPemFilename)
 	} else {
 		hasher := sha256.New()
@@ -93,3 +121,125 @@ func NewKeysFromFilenames(publicKeyPemFilename, privateKeyPemFilename string, de
 
 	return key, deprecatedKeys, nil
 }
+
+// SigningMethod returns the signing method of the key's algorithm
+func (k Key) SigningMethod() (jwt.SigningMethod, error) {
+	method := jwt.GetSigningMethod(k.Algorithm)
+	if method == nil || method == jwt.SigningMethodNone {
+		return nil, errors.New("unsupported jwt signing method: " + k.Algorithm)
+	}
+
+	return method, nil
+}
+
+// Validate returns an error if the keys do not match the algorithm
+func (k Key) Validate() error {
+	if _, err := k.SigningMethod(); err != nil {
+		return err
+	}
+
+	var ok bool
+
+	switch k.Algorithm {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		_, ok = k.Public.(*rsa.PublicKey)
+		if k.Private != nil {
+			_, privateOK := k.Private.(*rsa.PrivateKey)
+			ok = ok && privateOK
+		}
+	case "ES256", "ES384", "ES512":
+		var public *ecdsa.PublicKey
+		if public, ok = k.Public.(*ecdsa.PublicKey); ok {
+			ok = public.Curve == curveOf(k.Algorithm)
+		}
+
+		if k.Private != nil {
+			_, privateOK := k.Private.(*ecdsa.PrivateKey)
+			ok = ok && privateOK
+		}
+	case "EdDSA":
+		_, ok = k.Public.(ed25519.PublicKey)
+		if k.Private != nil {
+			_, privateOK := k.Private.(ed25519.PrivateKey)
+			ok = ok && privateOK
+		}
+	case "HS256", "HS384", "HS512":
+		var secret []byte
+		if secret, ok = k.Public.([]byte); ok {
+			ok = len(secret) > 0
+		}
+	}
+
+	if !ok {
+		return errors.Errorf("invalid key type %T for jwt signing method %s", k.Public, k.Algorithm)
+	}
+
+	return nil
+}
+
+// Symmetric returns true for hmac keys which must not be published
+func (k Key) Symmetric() bool {
+	_, ok := k.Public.([]byte)
+	return ok
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func defaultAlgorithm(public crypto.PublicKey) string {
+	switch v := public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256.Alg()
+	case *ecdsa.PublicKey:
+		switch v.Curve {
+		case elliptic.P384():
+			return jwt.SigningMethodES384.Alg()
+		case elliptic.P521():
+			return jwt.SigningMethodES512.Alg()
+		default:
+			return jwt.SigningMethodES256.Alg()
+		}
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA.Alg()
+	case []byte:
+		return jwt.SigningMethodHS256.Alg()
+	default:
+		return ""
+	}
+}
+
+func curveOf(algorithm string) elliptic.Curve {
+	switch algorithm {
+	case "ES384":
+		return elliptic.P384()
+	case "ES512":
+		return elliptic.P521()
+	default:
+		return elliptic.P256()
+	}
+}
+
+func parsePublicKeyFromPEM(v []byte) (crypto.PublicKey, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(v); err == nil {
+		return key, nil
+	} else if key, err := jwt.ParseECPublicKeyFromPEM(v); err == nil {
+		return key, nil
+	} else if key, err := jwt.ParseEdPublicKeyFromPEM(v); err == nil {
+		return key, nil
+	} else {
+		return nil, errors.New("unsupported public key type")
+	}
+}
+
+func parsePrivateKeyFromPEM(v []byte) (crypto.PrivateKey, error) {
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(v); err == nil {
+		return key, nil
+	} else if key, err := jwt.ParseECPrivateKeyFromPEM(v); err == nil {
+		return key, nil
+	} else if key, err := jwt.ParseEdPrivateKeyFromPEM(v); err == nil {
+		return key, nil
+	} else {
+		return nil, errors.New("unsupported private key type")
+	}
+}
@@ -0,0 +1,158 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	keeljwt "github.com/foomo/keel/jwt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestKey(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ec256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ec384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hmacKey, err := keeljwt.NewHMACKey("hmac", "HS512", []byte("secret"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		key       keeljwt.Key
+		algorithm string
+	}{
+		{key: keeljwt.NewKey("rsa", &rsaKey.PublicKey, rsaKey), algorithm: "RS256"},
+		{key: keeljwt.NewKey("es256", &ec256Key.PublicKey, ec256Key), algorithm: "ES256"},
+		{key: keeljwt.NewKey("es384", &ec384Key.PublicKey, ec384Key), algorithm: "ES384"},
+		{key: keeljwt.NewKey("eddsa", edPublic, edPrivate), algorithm: "EdDSA"},
+		{key: hmacKey, algorithm: "HS512"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			t.Parallel()
+
+			require.NoError(t, tt.key.Validate())
+			assert.Equal(t, tt.algorithm, tt.key.Algorithm)
+
+			inst := keeljwt.New(tt.key)
+			token, err := inst.GetSignedToken(keeljwt.NewRegisteredClaimsWithLifetime(time.Minute))
+			require.NoError(t, err)
+
+			parsed, err := inst.ParseWithClaims(token, &jwt.RegisteredClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.algorithm, parsed.Method.Alg())
+		})
+	}
+
+	_, err = keeljwt.NewKeyWithAlgorithm("invalid", "ES384", &ec256Key.PublicKey, ec256Key)
+	require.Error(t, err)
+	_, err = keeljwt.NewKeyWithAlgorithm("invalid", "none", &rsaKey.PublicKey, rsaKey)
+	require.Error(t, err)
+}
+
+func TestDefaultKeyFunc(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	key, err := keeljwt.NewKeyWithAlgorithm("rsa", "PS256", &rsaKey.PublicKey, rsaKey)
+	require.NoError(t, err)
+
+	inst := keeljwt.New(key)
+
+	// the same key with another algorithm is rejected
+	_, err = inst.ParseWithClaims(sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.RegisteredClaims{}), &jwt.RegisteredClaims{})
+	require.Error(t, err)
+
+	_, err = inst.ParseWithClaims(sign(t, jwt.SigningMethodPS256, "rsa", rsaKey, jwt.RegisteredClaims{}), &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+}
+
+func TestNewKeyFromFilenames(t *testing.T) {
+	t.Parallel()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	public, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	private, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	publicFilename := filepath.Join(dir, "public.pem")
+	privateFilename := filepath.Join(dir, "private.pem")
+	require.NoError(t, os.WriteFile(publicFilename, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}), 0o600))
+	require.NoError(t, os.WriteFile(privateFilename, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: private}), 0o600))
+
+	key, err := keeljwt.NewKeyFromFilenames(publicFilename, privateFilename)
+	require.NoError(t, err)
+	assert.Equal(t, "ES384", key.Algorithm)
+	require.NoError(t, key.Validate())
+}
+
+func TestNewJWKSHandler(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hmacKey, err := keeljwt.NewHMACKey("hmac", "HS256", []byte("secret"))
+	require.NoError(t, err)
+
+	minter := keeljwt.New(
+		keeljwt.NewKey("current", edPublic, edPrivate),
+		keeljwt.WithDeprecatedKeys(
+			keeljwt.NewKey("rsa", &rsaKey.PublicKey, nil),
+			keeljwt.NewKey("ec", &ecKey.PublicKey, nil),
+			hmacKey,
+		),
+	)
+
+	server := httptest.NewServer(keeljwt.NewJWKSHandler(zap.NewNop(), minter, time.Minute))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL) //nolint:noctx
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, keeljwt.ContentTypeJWKSet, resp.Header.Get("Content-Type"))
+	assert.Len(t, minter.JWKS().Keys, 3)
+
+	verifier := keeljwt.NewWithJWKS(keeljwt.NewJWKSFromURL(zap.NewNop(), server.URL))
+
+	token, err := minter.GetSignedToken(keeljwt.NewRegisteredClaimsWithLifetime(time.Minute))
+	require.NoError(t, err)
+	_, err = verifier.ParseWithClaims(token, &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+
+	_, err = verifier.ParseWithClaims(sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.RegisteredClaims{}), &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+	_, err = verifier.ParseWithClaims(sign(t, jwt.SigningMethodES256, "ec", ecKey, jwt.RegisteredClaims{}), &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+	_, err = verifier.ParseWithClaims(sign(t, jwt.SigningMethodHS256, "hmac", []byte("secret"), jwt.RegisteredClaims{}), &jwt.RegisteredClaims{})
+	require.ErrorIs(t, err, keeljwt.ErrUnknownKey)
+}
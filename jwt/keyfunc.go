@@ -5,22 +5,28 @@ import (
 	"github.com/pkg/errors"
 )
 
+// DefaultKeyFunc looks up the key by the token's key identifier and only
+// accepts tokens signed with the algorithm of that key
 func DefaultKeyFunc(key Key, deprecatedKeys map[string]Key) jwt.Keyfunc {
 	return func(token *jwt.Token) (any, error) {
-		if token.Method.Alg() != jwt.SigningMethodRS256.Name {
-			return nil, errors.New("unexpected jwt signing method: " + token.Method.Alg())
-		}
+		var match Key
 
 		if kid, ok := token.Header["kid"]; !ok {
 			return nil, errors.New("missing key identifier")
 		} else if kidString, ok := kid.(string); !ok {
 			return nil, errors.New("invalid key identifier type")
 		} else if oldKey, ok := deprecatedKeys[kidString]; ok {
-			return oldKey.Public, nil
+			match = oldKey
 		} else if kidString == key.ID {
-			return key.Public, nil
+			match = key
 		} else {
 			return nil, errors.New("unknown key identifier: " + kidString + " (" + key.ID + ")")
 		}
+
+		if token.Method.Alg() != match.Algorithm {
+			return nil, errors.New("unexpected jwt signing method: " + token.Method.Alg())
+		}
+
+		return match.Public, nil
 	}
 }
@@ -0,0 +1,452 @@
+package jwt
+
+import (
+	"cmp"
+	"context"
+	"crypto"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foomo/keel/telemetry"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+
+	"github.com/foomo/keel/log"
+)
+
+const (
+	// KeyManagerPrivateKeySuffix of private key pem files
+	KeyManagerPrivateKeySuffix = ".pem"
+	// KeyManagerPublicKeySuffix of public key pem files
+	KeyManagerPublicKeySuffix = ".pub.pem"
+	// KeyManagerMetadataSuffix of key metadata files
+	KeyManagerMetadataSuffix = ".json"
+)
+
+const keyIDKey = attribute.Key("keel.jwt.key_id")
+
+type (
+	// KeyMetadata is read from the optional <name>.json file next to a key
+	KeyMetadata struct {
+		// ID of the key, defaults to the file name
+		ID string `json:"id,omitempty" yaml:"id,omitempty"`
+		// Algorithm of the key, defaults to the default of the key type
+		Algorithm string `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+		// ValidFrom is the time from which on the key may sign, defaults to the file's modification time
+		ValidFrom time.Time `json:"validFrom,omitzero" yaml:"validFrom,omitempty"`
+		// RetiredAt is the time from which on the key only verifies until the grace period ends
+		RetiredAt time.Time `json:"retiredAt,omitzero" yaml:"retiredAt,omitempty"`
+		// Signing designates the key as signing key regardless of its ValidFrom
+		Signing bool `json:"signing,omitempty" yaml:"signing,omitempty"`
+	}
+	KeyManagerOptions struct {
+		// ReloadInterval at which the directory is read
+		ReloadInterval time.Duration
+		// GracePeriod for which retired and removed keys still verify, should exceed the token lifetime
+		GracePeriod time.Duration
+		// Clock e.g. for testing
+		Clock func() time.Time
+	}
+	KeyManagerOption func(*KeyManagerOptions)
+	// KeyManager keeps the keys of a JWT in sync with a directory, e.g. a
+	// mounted secret. Each key consists of a private <name>.pem and/or public
+	// <name>.pub.pem file and optional <name>.json KeyMetadata.
+	//
+	// The signing key is the key designated by its metadata or else the one
+	// with the newest ValidFrom that has a private key and is not retired. All
+	// other keys only verify, retired and removed ones until their grace
+	// period ends.
+	KeyManager struct {
+		l                *zap.Logger
+		jwt              *JWT
+		dir              string
+		opts             KeyManagerOptions
+		signingID        string
+		keyIDs           []string
+		present          map[string]Key
+		removed          map[string]removedKey
+		loadLock         sync.Mutex
+		rotationsCounter metric.Int64Counter
+		keysGauge        metric.Int64Gauge
+	}
+	managedKey struct {
+		Key
+
+		name      string
+		private   bool
+		validFrom time.Time
+		retiredAt time.Time
+		signing   bool
+	}
+	removedKey struct {
+		key   Key
+		until time.Time
+	}
+)
+
+// GetDefaultKeyManagerOptions returns the default options
+func GetDefaultKeyManagerOptions() KeyManagerOptions {
+	return KeyManagerOptions{
+		ReloadInterval: time.Minute,
+		GracePeriod:    24 * time.Hour,
+		Clock:          time.Now,
+	}
+}
+
+// KeyManagerWithReloadInterval option
+func KeyManagerWithReloadInterval(v time.Duration) KeyManagerOption {
+	return func(o *KeyManagerOptions) {
+		o.ReloadInterval = v
+	}
+}
+
+// KeyManagerWithGracePeriod option
+func KeyManagerWithGracePeriod(v time.Duration) KeyManagerOption {
+	return func(o *KeyManagerOptions) {
+		o.GracePeriod = v
+	}
+}
+
+// KeyManagerWithClock option
+func KeyManagerWithClock(v func() time.Time) KeyManagerOption {
+	return func(o *KeyManagerOptions) {
+		o.Clock = v
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewKeyManager returns a manager swapping the keys of the JWT. Call Load
+// before use and Watch to pick up changes.
+func NewKeyManager(l *zap.Logger, jwt *JWT, dir string, opts ...KeyManagerOption) *KeyManager {
+	if l == nil {
+		l = log.Logger()
+	}
+
+	options := GetDefaultKeyManagerOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return &KeyManager{
+		l:       l.With(zap.String("dir", dir)),
+		jwt:     jwt,
+		dir:     dir,
+		opts:    options,
+		present: map[string]Key{},
+		removed: map[string]removedKey{},
+		rotationsCounter: telemetry.NewIntCounter("keel.jwt.key.rotations",
+			metric.WithDescription("Number of jwt signing key rotations"),
+		),
+		keysGauge: telemetry.NewIntGauge("keel.jwt.keys",
+			metric.WithDescription("Number of jwt keys used for verification"),
+		),
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// JWT returns the managed JWT
+func (m *KeyManager) JWT() *JWT {
+	return m.jwt
+}
+
+// SigningKeyID returns the id of the current signing key
+func (m *KeyManager) SigningKeyID() string {
+	m.loadLock.Lock()
+	defer m.loadLock.Unlock()
+
+	return m.signingID
+}
+
+// Load reads the directory and swaps the keys of the JWT atomically. On
+// error, the JWT keeps its previous keys.
+func (m *KeyManager) Load() error {
+	m.loadLock.Lock()
+	defer m.loadLock.Unlock()
+
+	now := m.opts.Clock()
+
+	keys, err := m.read()
+	if err != nil {
+		return err
+	}
+
+	var (
+		signing   *managedKey
+		verifying []Key
+		present   = map[string]Key{}
+		expired   = map[string]bool{}
+	)
+
+	for _, key := range keys {
+		if !key.retiredAt.IsZero() && !now.Before(key.retiredAt.Add(m.opts.GracePeriod)) {
+			expired[key.ID] = true
+			continue
+		}
+
+		present[key.ID] = key.Key
+
+		// designated keys sign regardless of their ValidFrom
+		if !key.private || (!key.signing && key.validFrom.After(now)) || (!key.retiredAt.IsZero() && !now.Before(key.retiredAt)) {
+			continue
+		}
+
+		if signing == nil || compareSigningKeys(key, signing) > 0 {
+			signing = key
+		}
+	}
+
+	// keep removed keys verifying until their grace period ends
+	for id, key := range m.present {
+		if _, ok := present[id]; !ok && !expired[id] {
+			if _, ok := m.removed[id]; !ok {
+				m.removed[id] = removedKey{key: key, until: now.Add(m.opts.GracePeriod)}
+			}
+		}
+	}
+
+	for id, removed := range m.removed {
+		switch {
+		case present[id].Public != nil:
+			delete(m.removed, id)
+		case !now.Before(removed.until):
+			delete(m.removed, id)
+		default:
+			present[id] = removed.key
+		}
+	}
+
+	var signingKey Key
+	if signing != nil {
+		signingKey = signing.Key
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(present)) {
+		if id != signingKey.ID {
+			verifying = append(verifying, present[id])
+		}
+	}
+
+	m.jwt.SetKeys(signingKey, verifying...)
+	m.present = present
+
+	// log and record changes
+	keyIDs := slices.Sorted(maps.Keys(present))
+	if signingKey.ID != m.signingID {
+		if signingKey.ID == "" {
+			m.l.Warn("no valid jwt signing key", zap.String("previous_key_id", m.signingID))
+		} else {
+			m.l.Info("rotated jwt signing key", zap.String("key_id", signingKey.ID), zap.String("previous_key_id", m.signingID))
+		}
+
+		m.rotationsCounter.Add(context.Background(), 1, metric.WithAttributes(keyIDKey.String(signingKey.ID)))
+		m.signingID = signingKey.ID
+	}
+
+	if !slices.Equal(keyIDs, m.keyIDs) {
+		for _, id := range m.keyIDs {
+			if !slices.Contains(keyIDs, id) {
+				m.l.Info("dropped jwt key", zap.String("key_id", id))
+			}
+		}
+
+		for _, id := range keyIDs {
+			if !slices.Contains(m.keyIDs, id) {
+				m.l.Info("added jwt key", zap.String("key_id", id))
+			}
+		}
+
+		m.keyIDs = keyIDs
+	}
+
+	m.keysGauge.Record(context.Background(), int64(len(keyIDs)))
+
+	return nil
+}
+
+// Watch reloads the directory until ctx is done. The directory is polled so
+// that symlink swaps of mounted secrets and time based rotations are picked up.
+func (m *KeyManager) Watch(ctx context.Context) {
+	if m.opts.ReloadInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.opts.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if err := m.Load(); err != nil {
+			// keep the previous keys; files may be mid-rotation
+			log.WithError(m.l, err).Warn("failed to reload jwt keys")
+		}
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// read loads all keys of the directory
+func (m *KeyManager) read() ([]*managedKey, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read jwt key directory")
+	}
+
+	names := map[string]struct{}{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		// skip hidden files e.g. the ..data link of mounted secrets
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(name, KeyManagerPublicKeySuffix):
+			names[strings.TrimSuffix(name, KeyManagerPublicKeySuffix)] = struct{}{}
+		case strings.HasSuffix(name, KeyManagerPrivateKeySuffix):
+			names[strings.TrimSuffix(name, KeyManagerPrivateKeySuffix)] = struct{}{}
+		}
+	}
+
+	ret := make([]*managedKey, 0, len(names))
+	ids := map[string]string{}
+
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		key, err := m.readKey(name)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load jwt key: "+name)
+		}
+
+		if other, ok := ids[key.ID]; ok {
+			return nil, errors.Errorf("duplicate jwt key id %s: %s, %s", key.ID, other, name)
+		}
+
+		ids[key.ID] = name
+		ret = append(ret, key)
+	}
+
+	return ret, nil
+}
+
+// readKey loads the key files of the given name
+func (m *KeyManager) readKey(name string) (*managedKey, error) {
+	var (
+		public  crypto.PublicKey
+		private crypto.PrivateKey
+		modTime time.Time
+	)
+
+	filename := filepath.Join(m.dir, name)
+
+	if v, info, err := readFile(filename + KeyManagerPrivateKeySuffix); err != nil {
+		return nil, err
+	} else if v != nil {
+		if private, err = parsePrivateKeyFromPEM(v); err != nil {
+			return nil, err
+		}
+
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return nil, errors.Errorf("unsupported private key type %T", private)
+		}
+
+		public = signer.Public()
+		modTime = info.ModTime()
+	}
+
+	if v, info, err := readFile(filename + KeyManagerPublicKeySuffix); err != nil {
+		return nil, err
+	} else if v != nil {
+		if public, err = parsePublicKeyFromPEM(v); err != nil {
+			return nil, err
+		}
+
+		if modTime.IsZero() {
+			modTime = info.ModTime()
+		}
+	}
+
+	var metadata KeyMetadata
+
+	if v, _, err := readFile(filename + KeyManagerMetadataSuffix); err != nil {
+		return nil, err
+	} else if v != nil {
+		if err := json.Unmarshal(v, &metadata); err != nil {
+			return nil, errors.Wrap(err, "invalid metadata")
+		}
+	}
+
+	key := NewKey(cmp.Or(metadata.ID, name), public, private)
+	key.Algorithm = cmp.Or(metadata.Algorithm, key.Algorithm)
+
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &managedKey{
+		Key:       key,
+		name:      name,
+		private:   private != nil,
+		validFrom: cmp.Or(metadata.ValidFrom, modTime),
+		retiredAt: metadata.RetiredAt,
+		signing:   metadata.Signing,
+	}, nil
+}
+
+// readFile returns nil if the file does not exist
+func readFile(filename string) ([]byte, os.FileInfo, error) {
+	info, err := os.Stat(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	v, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []byte(strings.ReplaceAll(string(v), `\n`, "\n")), info, nil
+}
+
+// compareSigningKeys prefers designated keys, then the newest ValidFrom and finally the name
+func compareSigningKeys(a, b *managedKey) int {
+	if a.signing != b.signing {
+		if a.signing {
+			return 1
+		}
+
+		return -1
+	}
+
+	if c := a.validFrom.Compare(b.validFrom); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a.name, b.name)
+}
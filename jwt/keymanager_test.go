@@ -0,0 +1,121 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	keeljwt "github.com/foomo/keel/jwt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func writeKey(t *testing.T, dir, name string, metadata *keeljwt.KeyMetadata) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	private, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+keeljwt.KeyManagerPrivateKeySuffix), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: private}), 0o600))
+
+	if metadata != nil {
+		v, err := json.Marshal(metadata)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+keeljwt.KeyManagerMetadataSuffix), v, 0o600))
+	}
+}
+
+func TestKeyManager(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	writeKey(t, dir, "2025-12", &keeljwt.KeyMetadata{ValidFrom: now.Add(-30 * 24 * time.Hour)})
+	writeKey(t, dir, "2026-01", &keeljwt.KeyMetadata{ValidFrom: now.Add(-time.Hour)})
+	writeKey(t, dir, "2026-02", &keeljwt.KeyMetadata{ValidFrom: now.Add(31 * 24 * time.Hour)})
+
+	inst := keeljwt.New(keeljwt.Key{})
+	manager := keeljwt.NewKeyManager(zap.NewNop(), inst, dir,
+		keeljwt.KeyManagerWithGracePeriod(time.Hour),
+		keeljwt.KeyManagerWithClock(func() time.Time { return now }),
+	)
+
+	require.NoError(t, manager.Load())
+	assert.Equal(t, "2026-01", manager.SigningKeyID())
+	// future keys are published ahead of time
+	assert.Len(t, inst.JWKS().Keys, 3)
+
+	token, err := inst.GetSignedToken(jwt.RegisteredClaims{})
+	require.NoError(t, err)
+
+	// designated by metadata
+	writeKey(t, dir, "manual", &keeljwt.KeyMetadata{ID: "manual-id", Signing: true, ValidFrom: now.Add(-48 * time.Hour)})
+	require.NoError(t, manager.Load())
+	assert.Equal(t, "manual-id", manager.SigningKeyID())
+
+	// the previous signing key still verifies
+	_, err = inst.ParseWithClaims(token, &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+
+	// removed keys verify until the grace period ends
+	require.NoError(t, os.Remove(filepath.Join(dir, "2026-01.pem")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "2026-01.json")))
+	require.NoError(t, manager.Load())
+	_, err = inst.ParseWithClaims(token, &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, manager.Load())
+	_, err = inst.ParseWithClaims(token, &jwt.RegisteredClaims{})
+	require.Error(t, err)
+
+	// retired keys stop signing and are dropped after the grace period
+	writeKey(t, dir, "manual", &keeljwt.KeyMetadata{ID: "manual-id", Signing: true, RetiredAt: now})
+	require.NoError(t, manager.Load())
+	assert.Equal(t, "2025-12", manager.SigningKeyID())
+	assert.Len(t, inst.JWKS().Keys, 3)
+
+	now = now.Add(time.Hour)
+	require.NoError(t, manager.Load())
+	assert.Len(t, inst.JWKS().Keys, 2)
+
+	// the previous keys are kept on invalid files
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("broken"), 0o600))
+	require.Error(t, manager.Load())
+	assert.Equal(t, "2025-12", manager.SigningKeyID())
+
+	// time based rotation
+	require.NoError(t, os.Remove(filepath.Join(dir, "broken.pem")))
+	now = now.Add(31 * 24 * time.Hour)
+	require.NoError(t, manager.Load())
+	assert.Equal(t, "2026-02", manager.SigningKeyID())
+}
+
+func TestKeyManager_signingValidFrom(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	writeKey(t, dir, "2025-12", &keeljwt.KeyMetadata{ValidFrom: now.Add(-30 * 24 * time.Hour)})
+	writeKey(t, dir, "manual", &keeljwt.KeyMetadata{ID: "manual-id", Signing: true, ValidFrom: now.Add(24 * time.Hour)})
+
+	inst := keeljwt.New(keeljwt.Key{})
+	manager := keeljwt.NewKeyManager(zap.NewNop(), inst, dir,
+		keeljwt.KeyManagerWithClock(func() time.Time { return now }),
+	)
+
+	require.NoError(t, manager.Load())
+	assert.Equal(t, "manual-id", manager.SigningKeyID())
+}
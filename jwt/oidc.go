@@ -0,0 +1,62 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// OIDCDiscoveryPath of the OpenID Connect discovery document relative to the issuer
+const OIDCDiscoveryPath = "/.well-known/openid-configuration"
+
+// OIDCConfiguration is the subset of the OpenID Connect discovery document
+// required to validate tokens
+type OIDCConfiguration struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported,omitempty"`
+}
+
+// DiscoverOIDC fetches the discovery document of the issuer
+func DiscoverOIDC(ctx context.Context, client *http.Client, issuer string) (*OIDCConfiguration, error) {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultJWKSTimeout}
+	}
+
+	body, err := httpGet(ctx, client, strings.TrimSuffix(issuer, "/")+OIDCDiscoveryPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch oidc discovery document")
+	}
+
+	var ret OIDCConfiguration
+	if err := json.Unmarshal(body, &ret); err != nil {
+		return nil, errors.Wrap(err, "failed to decode oidc discovery document")
+	}
+
+	// see https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderConfigurationValidation
+	if ret.Issuer != issuer {
+		return nil, errors.Errorf("oidc issuer mismatch: %s != %s", ret.Issuer, issuer)
+	}
+
+	if ret.JWKSURI == "" {
+		return nil, errors.New("missing oidc jwks_uri")
+	}
+
+	return &ret, nil
+}
+
+// NewJWKSFromOIDC discovers the jwks url of the issuer and returns a key source for it
+func NewJWKSFromOIDC(ctx context.Context, l *zap.Logger, issuer string, opts ...JWKSOption) (*JWKS, *OIDCConfiguration, error) {
+	inst := newJWKS(l, opts...)
+
+	config, err := DiscoverOIDC(ctx, inst.httpClient, issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return NewJWKSFromURL(l, config.JWKSURI, opts...), config, nil
+}
@@ -0,0 +1,129 @@
+package jwt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrTokenRevoked  = errors.New("token revoked")
+	ErrTokenReplayed = errors.New("token replayed")
+	ErrTokenNoID     = errors.New("token without jti")
+)
+
+// RevocationStore keeps revoked token ids and subjects until the revoked
+// tokens expire
+type RevocationStore interface {
+	// RevokeID revokes the token with the jti
+	RevokeID(ctx context.Context, id string, expiresAt time.Time) error
+	// RevokeSubject revokes all tokens of the subject issued before the given time,
+	// truncated to the second precision of the iat claim
+	RevokeSubject(ctx context.Context, subject string, issuedBefore, expiresAt time.Time) error
+	// Revoked returns true if the token's jti or subject was revoked
+	Revoked(ctx context.Context, id, subject string, issuedAt time.Time) (bool, error)
+	// Consume marks the jti as used and returns false if it was used before
+	Consume(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+}
+
+// RevokeToken revokes the parsed token by its jti until it expires
+func RevokeToken(ctx context.Context, store RevocationStore, token *jwt.Token) error {
+	id := TokenID(token)
+	if id == "" {
+		return ErrTokenNoID
+	}
+
+	return store.RevokeID(ctx, id, tokenExpiresAt(token))
+}
+
+// RevokeSubject revokes all tokens of the subject issued until now. The
+// revocation is kept for the maximum lifetime of the tokens.
+func RevokeSubject(ctx context.Context, store RevocationStore, subject string, maxLifetime time.Duration) error {
+	now := time.Now()
+	return store.RevokeSubject(ctx, subject, now, now.Add(maxLifetime))
+}
+
+// CheckRevocation returns ErrTokenRevoked if the token was revoked and, for
+// one-time tokens, ErrTokenReplayed if it was used before
+func CheckRevocation(ctx context.Context, store RevocationStore, token *jwt.Token, oneTime bool) error {
+	var (
+		id       = TokenID(token)
+		subject  string
+		issuedAt time.Time
+	)
+
+	if token.Claims != nil {
+		subject, _ = token.Claims.GetSubject()
+		if v, err := token.Claims.GetIssuedAt(); err == nil && v != nil {
+			issuedAt = v.Time
+		}
+	}
+
+	if revoked, err := store.Revoked(ctx, id, subject, issuedAt); err != nil {
+		return errors.Wrap(err, "failed to check token revocation")
+	} else if revoked {
+		return ErrTokenRevoked
+	}
+
+	if !oneTime {
+		return nil
+	}
+
+	if id == "" {
+		return ErrTokenNoID
+	}
+
+	if ok, err := store.Consume(ctx, id, tokenExpiresAt(token)); err != nil {
+		return errors.Wrap(err, "failed to consume token")
+	} else if !ok {
+		return ErrTokenReplayed
+	}
+
+	return nil
+}
+
+// TokenID returns the jti of the parsed token regardless of its claims type
+func TokenID(token *jwt.Token) string {
+	if v, ok := token.Claims.(*jwt.RegisteredClaims); ok {
+		return v.ID
+	}
+
+	parts := strings.Split(token.Raw, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	var claims struct {
+		ID string `json:"jti"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+
+	return claims.ID
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// tokenExpiresAt returns the expiry of the token or a day from now for tokens without one
+func tokenExpiresAt(token *jwt.Token) time.Time {
+	if token.Claims != nil {
+		if v, err := token.Claims.GetExpirationTime(); err == nil && v != nil {
+			return v.Time
+		}
+	}
+
+	return time.Now().Add(24 * time.Hour)
+}
@@ -0,0 +1,172 @@
+package jwt
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// MemoryRevocationStore keeps the revocations in memory and evicts them
+	// once the revoked tokens expired
+	MemoryRevocationStore struct {
+		clock         func() time.Time
+		cleanupPeriod time.Duration
+		cleanupAt     time.Time
+		ids           map[string]time.Time
+		used          map[string]time.Time
+		subjects      map[string]memoryRevokedSubject
+		lock          sync.Mutex
+	}
+	MemoryRevocationStoreOption func(*MemoryRevocationStore)
+	memoryRevokedSubject        struct {
+		issuedBefore time.Time
+		expires      time.Time
+	}
+)
+
+// MemoryRevocationStoreWithClock sets the clock, e.g. for testing
+func MemoryRevocationStoreWithClock(v func() time.Time) MemoryRevocationStoreOption {
+	return func(o *MemoryRevocationStore) {
+		o.clock = v
+	}
+}
+
+// MemoryRevocationStoreWithCleanupPeriod sets the interval at which expired revocations are evicted
+func MemoryRevocationStoreWithCleanupPeriod(v time.Duration) MemoryRevocationStoreOption {
+	return func(o *MemoryRevocationStore) {
+		o.cleanupPeriod = v
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+func NewMemoryRevocationStore(opts ...MemoryRevocationStoreOption) *MemoryRevocationStore {
+	inst := &MemoryRevocationStore{
+		clock:         time.Now,
+		cleanupPeriod: time.Minute,
+		ids:           map[string]time.Time{},
+		used:          map[string]time.Time{},
+		subjects:      map[string]memoryRevokedSubject{},
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(inst)
+		}
+	}
+
+	return inst
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (s *MemoryRevocationStore) RevokeID(ctx context.Context, id string, expiresAt time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cleanup()
+
+	if expiresAt.After(s.ids[id]) {
+		s.ids[id] = expiresAt
+	}
+
+	return nil
+}
+
+func (s *MemoryRevocationStore) RevokeSubject(ctx context.Context, subject string, issuedBefore, expiresAt time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cleanup()
+
+	// iat has second precision only
+	issuedBefore = issuedBefore.Truncate(time.Second)
+
+	value := s.subjects[subject]
+	if issuedBefore.After(value.issuedBefore) {
+		value.issuedBefore = issuedBefore
+	}
+
+	if expiresAt.After(value.expires) {
+		value.expires = expiresAt
+	}
+
+	s.subjects[subject] = value
+
+	return nil
+}
+
+func (s *MemoryRevocationStore) Revoked(ctx context.Context, id, subject string, issuedAt time.Time) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock()
+
+	if expires, ok := s.ids[id]; ok && id != "" && now.Before(expires) {
+		return true, nil
+	}
+
+	// tokens without iat can not prove to be issued after the revocation
+	if value, ok := s.subjects[subject]; ok && subject != "" && now.Before(value.expires) &&
+		(issuedAt.IsZero() || issuedAt.Before(value.issuedBefore)) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (s *MemoryRevocationStore) Consume(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cleanup()
+
+	if expires, ok := s.used[id]; ok && s.clock().Before(expires) {
+		return false, nil
+	}
+
+	s.used[id] = expiresAt
+
+	return true, nil
+}
+
+// Len returns the number of revoked ids, subjects and used ids
+func (s *MemoryRevocationStore) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.ids) + len(s.subjects) + len(s.used)
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// cleanup evicts expired revocations, expects the lock to be held
+func (s *MemoryRevocationStore) cleanup() {
+	now := s.clock()
+	if now.Before(s.cleanupAt) {
+		return
+	}
+
+	s.cleanupAt = now.Add(s.cleanupPeriod)
+
+	for _, m := range []map[string]time.Time{s.ids, s.used} {
+		for id, expires := range m {
+			if !now.Before(expires) {
+				delete(m, id)
+			}
+		}
+	}
+
+	for subject, value := range s.subjects {
+		if !now.Before(value.expires) {
+			delete(s.subjects, subject)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package jwt_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/foomo/keel/jwt"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRevocationStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := jwt.NewMemoryRevocationStore(
+		jwt.MemoryRevocationStoreWithClock(func() time.Time { return now }),
+		jwt.MemoryRevocationStoreWithCleanupPeriod(0),
+	)
+
+	// revoke by id
+	require.NoError(t, store.RevokeID(ctx, "a", now.Add(time.Hour)))
+	revoked, err := store.Revoked(ctx, "a", "", time.Time{})
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = store.Revoked(ctx, "b", "", time.Time{})
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// revoke by subject issued before
+	require.NoError(t, store.RevokeSubject(ctx, "user", now, now.Add(time.Hour)))
+	revoked, err = store.Revoked(ctx, "b", "user", now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = store.Revoked(ctx, "b", "user", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	revoked, err = store.Revoked(ctx, "b", "user", time.Time{})
+	require.NoError(t, err)
+	assert.True(t, revoked, "tokens without iat")
+
+	// tokens issued in the second of the revocation remain valid as iat has second precision
+	require.NoError(t, store.RevokeSubject(ctx, "relogin", now.Add(700*time.Millisecond), now.Add(time.Hour)))
+	revoked, err = store.Revoked(ctx, "b", "relogin", now)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	revoked, err = store.Revoked(ctx, "b", "relogin", now.Add(-time.Second))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	// consume once
+	ok, err := store.Consume(ctx, "c", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = store.Consume(ctx, "c", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	revoked, err = store.Revoked(ctx, "c", "", time.Time{})
+	require.NoError(t, err)
+	assert.False(t, revoked, "used tokens are not revoked")
+
+	// expired revocations are evicted
+	assert.Equal(t, 4, store.Len())
+	now = now.Add(2 * time.Hour)
+	revoked, err = store.Revoked(ctx, "a", "user", time.Time{})
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	require.NoError(t, store.RevokeID(ctx, "d", now.Add(time.Hour)))
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestCheckRevocation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := jwt.NewMemoryRevocationStore()
+	token := &gojwt.Token{Claims: &gojwt.RegisteredClaims{
+		ID:        "id",
+		Subject:   "user",
+		IssuedAt:  gojwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}
+
+	require.NoError(t, jwt.CheckRevocation(ctx, store, token, true))
+	require.ErrorIs(t, jwt.CheckRevocation(ctx, store, token, true), jwt.ErrTokenReplayed)
+	require.NoError(t, jwt.CheckRevocation(ctx, store, token, false))
+
+	require.NoError(t, jwt.RevokeSubject(ctx, store, "user", time.Hour))
+	require.ErrorIs(t, jwt.CheckRevocation(ctx, store, token, false), jwt.ErrTokenRevoked)
+
+	other := &gojwt.Token{Claims: &gojwt.MapClaims{}, Raw: "e30.eyJqdGkiOiJvdGhlciJ9.c2ln"}
+	assert.Equal(t, "other", jwt.TokenID(other))
+	require.NoError(t, jwt.RevokeToken(ctx, store, other))
+	require.ErrorIs(t, jwt.CheckRevocation(ctx, store, other, false), jwt.ErrTokenRevoked)
+	require.ErrorIs(t, jwt.RevokeToken(ctx, store, &gojwt.Token{Claims: &gojwt.RegisteredClaims{}}), jwt.ErrTokenNoID)
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Directives of a Cache-Control header by lower case name
+type Directives map[string]string
+
+// ParseCacheControl parses the directives of the Cache-Control header values
+func ParseCacheControl(values ...string) Directives {
+	ret := Directives{}
+
+	for _, value := range values {
+		for part := range strings.SplitSeq(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+
+			ret[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+
+	return ret
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// Has returns true if the directive is set
+func (d Directives) Has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+// Seconds returns the delta seconds argument of the directive e.g. max-age
+func (d Directives) Seconds(name string) (time.Duration, bool) {
+	v, ok := d[name]
+	if !ok {
+		return 0, false
+	}
+
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || i < 0 {
+		return 0, false
+	}
+
+	return time.Duration(i) * time.Second, true
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	// Entry of a cache key holding a response per variant of the Vary headers
+	Entry struct {
+		// Vary lists the canonical request header names selecting the variant
+		Vary []string
+		// Variants by the values of the Vary headers
+		Variants map[string]*Response
+		// Tags i.e. surrogate keys of all variants to purge by
+		Tags []string
+	}
+	// Response stored for a variant
+	Response struct {
+		StatusCode int
+		Header     http.Header
+		Body       []byte
+		// Stored is the time the response was stored
+		Stored time.Time
+		// Expires is the time the response becomes stale
+		Expires time.Time
+	}
+)
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// Variant returns the variant key of the request
+func (e *Entry) Variant(r *http.Request) string {
+	if len(e.Vary) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, name := range e.Vary {
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+// Expires returns the time the last variant becomes stale
+func (e *Entry) Expires() time.Time {
+	var ret time.Time
+
+	for _, v := range e.Variants {
+		if v.Expires.After(ret) {
+			ret = v.Expires
+		}
+	}
+
+	return ret
+}
+
+// Size returns the approximate memory size in bytes
+func (e *Entry) Size() int {
+	var ret int
+
+	for variant, v := range e.Variants {
+		ret += len(variant) + len(v.Body)
+
+		for name, values := range v.Header {
+			ret += len(name)
+			for _, value := range values {
+				ret += len(value)
+			}
+		}
+	}
+
+	return ret
+}
+
+// Fresh returns true if the response is not stale
+func (r *Response) Fresh(now time.Time) bool {
+	return now.Before(r.Expires)
+}
+
+// Age returns the age of the response
+func (r *Response) Age(now time.Time) time.Duration {
+	return max(0, now.Sub(r.Stored))
+}
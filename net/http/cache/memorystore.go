@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// MemoryStore keeps the entries in memory and evicts the least recently
+	// used ones once the size limits are exceeded
+	MemoryStore struct {
+		clock      func() time.Time
+		maxBytes   int
+		maxEntries int
+		size       int
+		lru        *list.List
+		entries    map[string]*list.Element
+		tags       map[string]map[string]struct{}
+		lock       sync.Mutex
+	}
+	MemoryStoreOption func(*MemoryStore)
+	memoryEntry       struct {
+		key     string
+		entry   *Entry
+		size    int
+		expires time.Time
+	}
+)
+
+// MemoryStoreWithClock sets the clock, e.g. for testing
+func MemoryStoreWithClock(v func() time.Time) MemoryStoreOption {
+	return func(o *MemoryStore) {
+		o.clock = v
+	}
+}
+
+// MemoryStoreWithMaxBytes sets the maximum size of all entries, 0 disables the limit
+func MemoryStoreWithMaxBytes(v int) MemoryStoreOption {
+	return func(o *MemoryStore) {
+		o.maxBytes = v
+	}
+}
+
+// MemoryStoreWithMaxEntries sets the maximum number of entries, 0 disables the limit
+func MemoryStoreWithMaxEntries(v int) MemoryStoreOption {
+	return func(o *MemoryStore) {
+		o.maxEntries = v
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+func NewMemoryStore(opts ...MemoryStoreOption) *MemoryStore {
+	inst := &MemoryStore{
+		clock:      time.Now,
+		maxBytes:   64 << 20,
+		maxEntries: 10000,
+		lru:        list.New(),
+		entries:    map[string]*list.Element{},
+		tags:       map[string]map[string]struct{}{},
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(inst)
+		}
+	}
+
+	return inst
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Getter
+// ------------------------------------------------------------------------------------------------
+
+// Len returns the number of entries
+func (s *MemoryStore) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.lru.Len()
+}
+
+// Size returns the approximate size of all entries in bytes
+func (s *MemoryStore) Size() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.size
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (*Entry, bool, error) {
+	now := s.clock()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	item, _ := elem.Value.(*memoryEntry)
+	if !now.Before(item.expires) {
+		s.remove(elem)
+		return nil, false, nil
+	}
+
+	s.lru.MoveToFront(elem)
+
+	return item.entry, true, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key string, entry *Entry) error {
+	item := &memoryEntry{
+		key:     key,
+		entry:   entry,
+		size:    len(key) + entry.Size(),
+		expires: entry.Expires(),
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+
+	// entries exceeding the whole cache are not stored
+	if s.maxBytes > 0 && item.size > s.maxBytes {
+		return nil
+	}
+
+	s.entries[key] = s.lru.PushFront(item)
+	s.size += item.size
+
+	for _, tag := range entry.Tags {
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = map[string]struct{}{}
+			s.tags[tag] = keys
+		}
+
+		keys[key] = struct{}{}
+	}
+
+	for (s.maxBytes > 0 && s.size > s.maxBytes) || (s.maxEntries > 0 && s.lru.Len() > s.maxEntries) {
+		s.remove(s.lru.Back())
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) Purge(ctx context.Context, keys ...string) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var ret int
+
+	for _, key := range keys {
+		if elem, ok := s.entries[key]; ok {
+			s.remove(elem)
+			ret++
+		}
+	}
+
+	return ret, nil
+}
+
+func (s *MemoryStore) PurgeTags(ctx context.Context, tags ...string) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var ret int
+
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			if elem, ok := s.entries[key]; ok {
+				s.remove(elem)
+				ret++
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func (s *MemoryStore) remove(elem *list.Element) {
+	item, _ := s.lru.Remove(elem).(*memoryEntry)
+	delete(s.entries, item.key)
+	s.size -= item.size
+
+	for _, tag := range item.entry.Tags {
+		if keys, ok := s.tags[tag]; ok {
+			delete(keys, item.key)
+
+			if len(keys) == 0 {
+				delete(s.tags, tag)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package cache_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/foomo/keel/net/http/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	t.Parallel()
+
+	directives := cache.ParseCacheControl(`public, Max-Age=60`, `s-maxage="120", no-transform`)
+
+	assert.True(t, directives.Has("public"))
+	assert.True(t, directives.Has("no-transform"))
+	assert.False(t, directives.Has("private"))
+
+	v, ok := directives.Seconds("max-age")
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, v)
+
+	v, ok = directives.Seconds("s-maxage")
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, v)
+
+	_, ok = directives.Seconds("public")
+	assert.False(t, ok)
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	store := cache.NewMemoryStore(
+		cache.MemoryStoreWithClock(func() time.Time { return now }),
+		cache.MemoryStoreWithMaxBytes(20),
+	)
+
+	entry := func(body string, ttl time.Duration, tags ...string) *cache.Entry {
+		return &cache.Entry{
+			Variants: map[string]*cache.Response{
+				"": {StatusCode: http.StatusOK, Body: []byte(body), Stored: now, Expires: now.Add(ttl)},
+			},
+			Tags: tags,
+		}
+	}
+
+	require.NoError(t, store.Set(t.Context(), "/a", entry("aaaa", time.Minute, "x")))
+	require.NoError(t, store.Set(t.Context(), "/b", entry("bbbb", time.Minute, "x", "y")))
+	require.NoError(t, store.Set(t.Context(), "/c", entry("cccc", time.Second)))
+	assert.Equal(t, 3, store.Len())
+	assert.Equal(t, 18, store.Size())
+
+	// the least recently used entry is evicted
+	_, ok, err := store.Get(t.Context(), "/a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, store.Set(t.Context(), "/d", entry("dddd", time.Minute)))
+	_, ok, _ = store.Get(t.Context(), "/b")
+	assert.False(t, ok)
+	assert.Equal(t, 3, store.Len())
+
+	// entries too large for the store are not stored
+	require.NoError(t, store.Set(t.Context(), "/e", entry("012345678901234567890", time.Minute)))
+	_, ok, _ = store.Get(t.Context(), "/e")
+	assert.False(t, ok)
+
+	// stale entries are evicted
+	now = now.Add(2 * time.Second)
+	_, ok, _ = store.Get(t.Context(), "/c")
+	assert.False(t, ok)
+
+	// purge by tag and key
+	n, err := store.PurgeTags(t.Context(), "x")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = store.Purge(t.Context(), "/d", "/unknown")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 0, store.Len())
+	assert.Equal(t, 0, store.Size())
+}
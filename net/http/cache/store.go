@@ -0,0 +1,17 @@
+package cache
+
+import (
+	"context"
+)
+
+// Store of cache entries
+type Store interface {
+	// Get returns the entry of the key
+	Get(ctx context.Context, key string) (*Entry, bool, error)
+	// Set stores the entry of the key replacing an existing one
+	Set(ctx context.Context, key string, entry *Entry) error
+	// Purge removes the entries of the keys and returns the number of removed entries
+	Purge(ctx context.Context, keys ...string) (int, error)
+	// PurgeTags removes the entries tagged with any of the tags and returns the number of removed entries
+	PurgeTags(ctx context.Context, tags ...string) (int, error)
+}
@@ -0,0 +1,188 @@
+package compress
+
+import (
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	stdhttp "github.com/foomo/gostandards/http"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported encoding")
+
+type (
+	// Writer of an encoding
+	Writer interface {
+		io.WriteCloser
+		Flush() error
+		Reset(w io.Writer)
+	}
+	// Encoders pools the writers of the encodings
+	Encoders struct {
+		pools map[stdhttp.Encoding]*sync.Pool
+	}
+	// reader creates the decoder on the first read so that empty bodies do not fail
+	reader struct {
+		encoding stdhttp.Encoding
+		r        io.Reader
+		rc       io.ReadCloser
+		err      error
+	}
+)
+
+// DefaultLevels of the encodings suited for compressing on the fly
+var DefaultLevels = map[stdhttp.Encoding]int{
+	stdhttp.EncodingBr:   4,
+	EncodingZstd:         3,
+	stdhttp.EncodingGzip: gzip.DefaultCompression,
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewEncoders returns pooled writers with the given levels, falling back to the DefaultLevels
+func NewEncoders(levels map[stdhttp.Encoding]int) *Encoders {
+	inst := &Encoders{
+		pools: map[stdhttp.Encoding]*sync.Pool{},
+	}
+
+	for _, encoding := range DefaultEncodings {
+		level, ok := levels[encoding]
+		if !ok {
+			level = DefaultLevels[encoding]
+		}
+
+		inst.pools[encoding] = &sync.Pool{
+			New: func() any {
+				w, err := NewWriter(encoding, io.Discard, level)
+				if err != nil {
+					return err
+				}
+
+				return w
+			},
+		}
+	}
+
+	return inst
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// Get returns a pooled writer of the encoding writing to w
+func (e *Encoders) Get(encoding stdhttp.Encoding, w io.Writer) (Writer, error) {
+	pool, ok := e.pools[encoding]
+	if !ok {
+		return nil, errors.Wrap(ErrUnsupportedEncoding, encoding.String())
+	}
+
+	switch v := pool.Get().(type) {
+	case Writer:
+		v.Reset(w)
+		return v, nil
+	case error:
+		return nil, v
+	default:
+		return nil, errors.Wrap(ErrUnsupportedEncoding, encoding.String())
+	}
+}
+
+// Put returns the closed writer to the pool
+func (e *Encoders) Put(encoding stdhttp.Encoding, w Writer) {
+	if pool, ok := e.pools[encoding]; ok {
+		w.Reset(io.Discard)
+		pool.Put(w)
+	}
+}
+
+// NewWriter returns a writer of the encoding at the level of the underlying library
+func NewWriter(encoding stdhttp.Encoding, w io.Writer, level int) (Writer, error) {
+	switch encoding {
+	case stdhttp.EncodingBr:
+		return brotli.NewWriterLevel(w, level), nil
+	case EncodingZstd:
+		ret, err := zstd.NewWriter(w,
+			zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithWindowSize(1<<20),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create zstd writer")
+		}
+
+		return ret, nil
+	case stdhttp.EncodingGzip:
+		ret, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create gzip writer")
+		}
+
+		return ret, nil
+	default:
+		return nil, errors.Wrap(ErrUnsupportedEncoding, encoding.String())
+	}
+}
+
+// NewReader returns a reader decoding the encoding
+func NewReader(encoding stdhttp.Encoding, r io.Reader) (io.ReadCloser, error) {
+	if !Supported(encoding) {
+		return nil, errors.Wrap(ErrUnsupportedEncoding, encoding.String())
+	}
+
+	return &reader{encoding: encoding, r: r}, nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.rc == nil && r.err == nil {
+		r.rc, r.err = newReader(r.encoding, r.r)
+	}
+
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	return r.rc.Read(p)
+}
+
+func (r *reader) Close() error {
+	if r.rc == nil {
+		return nil
+	}
+
+	return r.rc.Close()
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func newReader(encoding stdhttp.Encoding, r io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case stdhttp.EncodingBr:
+		return io.NopCloser(brotli.NewReader(r)), nil
+	case EncodingZstd:
+		ret, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create zstd reader")
+		}
+
+		return ret.IOReadCloser(), nil
+	case stdhttp.EncodingGzip:
+		ret, err := gzip.NewReader(r)
+		if errors.Is(err, io.EOF) {
+			return io.NopCloser(r), nil
+		} else if err != nil {
+			return nil, errors.Wrap(err, "failed to create gzip reader")
+		}
+
+		return ret, nil
+	default:
+		return nil, errors.Wrap(ErrUnsupportedEncoding, encoding.String())
+	}
+}
@@ -0,0 +1,92 @@
+package compress
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	stdhttp "github.com/foomo/gostandards/http"
+)
+
+// EncodingZstd Zstandard compression
+const EncodingZstd stdhttp.Encoding = "zstd"
+
+// DefaultEncodings in order of preference
+var DefaultEncodings = []stdhttp.Encoding{stdhttp.EncodingBr, EncodingZstd, stdhttp.EncodingGzip}
+
+// Negotiate returns the supported encoding with the highest quality value in
+// the Accept-Encoding header, preferring the order of the supported encodings
+// on ties. It returns EncodingIdentity if no supported encoding is acceptable.
+func Negotiate(acceptEncoding string, supported []stdhttp.Encoding) stdhttp.Encoding {
+	qualities := map[stdhttp.Encoding]float64{}
+	wildcard := -1.0
+
+	for part := range strings.SplitSeq(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+
+		for param := range strings.SplitSeq(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+
+		if name == "*" {
+			wildcard = q
+		} else {
+			qualities[stdhttp.Encoding(name)] = q
+		}
+	}
+
+	ret, best := stdhttp.EncodingIdentity, 0.0
+
+	for _, encoding := range supported {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = wildcard
+		}
+
+		if q > best {
+			ret, best = encoding, q
+		}
+	}
+
+	return ret
+}
+
+// AcceptEncoding returns the Accept-Encoding header value of the encodings
+func AcceptEncoding(encodings []stdhttp.Encoding) string {
+	values := make([]string, 0, len(encodings))
+	for _, encoding := range encodings {
+		values = append(values, encoding.String())
+	}
+
+	return strings.Join(values, ", ")
+}
+
+// Supported returns true if the encoding can be encoded and decoded
+func Supported(encoding stdhttp.Encoding) bool {
+	return slices.Contains(DefaultEncodings, encoding)
+}
+
+// Extension returns the file extension of precompressed variants
+func Extension(encoding stdhttp.Encoding) string {
+	switch encoding {
+	case stdhttp.EncodingBr:
+		return ".br"
+	case EncodingZstd:
+		return ".zst"
+	case stdhttp.EncodingGzip:
+		return ".gz"
+	default:
+		return ""
+	}
+}
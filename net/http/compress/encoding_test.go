@@ -0,0 +1,108 @@
+package compress_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	stdhttp "github.com/foomo/gostandards/http"
+	"github.com/foomo/keel/net/http/compress"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		acceptEncoding string
+		want           stdhttp.Encoding
+	}{
+		{"", stdhttp.EncodingIdentity},
+		{"gzip", stdhttp.EncodingGzip},
+		{"gzip, deflate, br, zstd", stdhttp.EncodingBr},
+		{"gzip;q=1.0, br;q=0.5", stdhttp.EncodingGzip},
+		{"ZSTD, gzip;q=0.9", compress.EncodingZstd},
+		{"br;q=0, *", compress.EncodingZstd},
+		{"*;q=0", stdhttp.EncodingIdentity},
+		{"deflate", stdhttp.EncodingIdentity},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, compress.Negotiate(tt.acceptEncoding, compress.DefaultEncodings), tt.acceptEncoding)
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	t.Parallel()
+
+	encoders := compress.NewEncoders(nil)
+	payload := strings.Repeat("keel ", 1000)
+
+	for _, encoding := range compress.DefaultEncodings {
+		var buf bytes.Buffer
+
+		w, err := encoders.Get(encoding, &buf)
+		require.NoError(t, err)
+
+		_, err = w.Write([]byte(payload))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		encoders.Put(encoding, w)
+
+		assert.Less(t, buf.Len(), len(payload), encoding)
+
+		r, err := compress.NewReader(encoding, &buf)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		assert.Equal(t, payload, string(b), encoding)
+	}
+
+	_, err := compress.NewReader(stdhttp.EncodingDeflate, nil)
+	require.ErrorIs(t, err, compress.ErrUnsupportedEncoding)
+}
+
+func TestFileServer(t *testing.T) {
+	t.Parallel()
+
+	handler := compress.FileServer(fstest.MapFS{
+		"app.js":    {Data: []byte("console.log('plain')")},
+		"app.js.br": {Data: []byte("brotli")},
+		"app.js.gz": {Data: []byte("gzip")},
+		"style.css": {Data: []byte("body{}")},
+	})
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(stdhttp.HeaderAcceptEncoding.String(), acceptEncoding)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := get("/app.js", "gzip, br")
+	assert.Equal(t, "brotli", w.Body.String())
+	assert.Equal(t, "br", w.Header().Get(stdhttp.HeaderContentEncoding.String()))
+	assert.Contains(t, w.Header().Get(stdhttp.HeaderContentType.String()), "javascript")
+	assert.Equal(t, "Accept-Encoding", w.Header().Get(stdhttp.HeaderVary.String()))
+
+	w = get("/app.js", "gzip, zstd")
+	assert.Equal(t, "gzip", w.Body.String())
+
+	w = get("/app.js", "")
+	assert.Equal(t, "console.log('plain')", w.Body.String())
+	assert.Empty(t, w.Header().Get(stdhttp.HeaderContentEncoding.String()))
+
+	w = get("/style.css", "br")
+	assert.Equal(t, "body{}", w.Body.String())
+	assert.Empty(t, w.Header().Get(stdhttp.HeaderContentEncoding.String()))
+}
@@ -0,0 +1,82 @@
+package compress
+
+import (
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	stdhttp "github.com/foomo/gostandards/http"
+)
+
+// FileServer serves the files of fsys like http.FileServerFS but prefers
+// precompressed variants next to the requested file e.g. "app.js.br",
+// "app.js.zst" or "app.js.gz" if they are acceptable to the client
+func FileServer(fsys fs.FS, encodings ...stdhttp.Encoding) http.Handler {
+	if len(encodings) == 0 {
+		encodings = DefaultEncodings
+	}
+
+	fileServer := http.FileServerFS(fsys)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || strings.HasSuffix(r.URL.Path, "/") {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+
+		var available []stdhttp.Encoding
+
+		for _, encoding := range encodings {
+			if info, err := fs.Stat(fsys, name+Extension(encoding)); err == nil && info.Mode().IsRegular() {
+				available = append(available, encoding)
+			}
+		}
+
+		if len(available) == 0 {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add(stdhttp.HeaderVary.String(), stdhttp.HeaderAcceptEncoding.String())
+
+		encoding := Negotiate(r.Header.Get(stdhttp.HeaderAcceptEncoding.String()), available)
+		if encoding == stdhttp.EncodingIdentity {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		f, err := fsys.Open(name + Extension(encoding))
+		if err != nil {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		content, ok := f.(io.ReadSeeker)
+		if !ok {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		// the content type must not be sniffed from the compressed content
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		w.Header().Set(stdhttp.HeaderContentType.String(), contentType)
+		w.Header().Set(stdhttp.HeaderContentEncoding.String(), encoding.String())
+		http.ServeContent(w, r, name, info.ModTime(), content)
+	})
+}
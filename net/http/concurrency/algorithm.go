@@ -0,0 +1,139 @@
+package concurrency
+
+import (
+	"math"
+	"time"
+)
+
+type (
+	// Algorithm adapts the concurrency limit to the observed samples. It is
+	// called by the Limiter while holding its lock.
+	Algorithm interface {
+		// Initial returns the initial limit
+		Initial() float64
+		// Update returns the new limit for a sample of a request that
+		// started with inflight requests and took rtt
+		Update(limit float64, inflight int, rtt time.Duration, dropped bool) float64
+	}
+	// AIMD increases the limit additively for successful requests and
+	// decreases it multiplicatively for dropped or slow ones
+	AIMD struct {
+		// MinLimit and MaxLimit bound the limit
+		MinLimit int
+		MaxLimit int
+		// InitialLimit to start with
+		InitialLimit int
+		// BackoffRatio the limit is multiplied with on drops
+		BackoffRatio float64
+		// Timeout after which a request counts as dropped
+		Timeout time.Duration
+	}
+	// Gradient adjusts the limit by the ratio of the long term to the
+	// current latency, allowing a small queue on top
+	Gradient struct {
+		// MinLimit and MaxLimit bound the limit
+		MinLimit int
+		MaxLimit int
+		// InitialLimit to start with
+		InitialLimit int
+		// Smoothing of limit changes between 0 and 1
+		Smoothing float64
+		// Tolerance of latency increases before reducing the limit
+		Tolerance float64
+		// LongWindow is the number of samples averaged into the long term latency
+		LongWindow int
+		// longRTT is the exponentially weighted long term latency
+		longRTT float64
+	}
+)
+
+// NewAIMD returns an AIMD algorithm with default settings
+func NewAIMD() *AIMD {
+	return &AIMD{
+		MinLimit:     10,
+		MaxLimit:     1000,
+		InitialLimit: 100,
+		BackoffRatio: 0.9,
+		Timeout:      5 * time.Second,
+	}
+}
+
+// NewGradient returns a gradient algorithm with default settings
+func NewGradient() *Gradient {
+	return &Gradient{
+		MinLimit:     10,
+		MaxLimit:     1000,
+		InitialLimit: 100,
+		Smoothing:    0.2,
+		Tolerance:    1.5,
+		LongWindow:   600,
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (a *AIMD) Initial() float64 {
+	return float64(a.InitialLimit)
+}
+
+func (a *AIMD) Update(limit float64, inflight int, rtt time.Duration, dropped bool) float64 {
+	switch {
+	case dropped || (a.Timeout > 0 && rtt > a.Timeout):
+		limit *= a.BackoffRatio
+	case float64(inflight)*2 >= limit:
+		// only grow while the limit is actually used
+		limit++
+	}
+
+	return clamp(limit, a.MinLimit, a.MaxLimit)
+}
+
+func (g *Gradient) Initial() float64 {
+	return float64(g.InitialLimit)
+}
+
+func (g *Gradient) Update(limit float64, inflight int, rtt time.Duration, dropped bool) float64 {
+	if rtt <= 0 {
+		return limit
+	}
+
+	shortRTT := float64(rtt)
+	factor := 2 / float64(max(1, g.LongWindow)+1)
+
+	if g.longRTT == 0 {
+		g.longRTT = shortRTT
+	} else {
+		g.longRTT = g.longRTT*(1-factor) + shortRTT*factor
+	}
+
+	// recover quickly once a latency spike is over
+	if g.longRTT/shortRTT > 2 {
+		g.longRTT *= 0.95
+	}
+
+	// only grow while the limit is actually used
+	if float64(inflight)*2 < limit && !dropped {
+		return limit
+	}
+
+	gradient := math.Max(0.5, math.Min(1, g.Tolerance*g.longRTT/shortRTT))
+	if dropped {
+		gradient = 0.5
+	}
+
+	queueSize := math.Sqrt(limit)
+	next := limit*gradient + queueSize
+	next = limit*(1-g.Smoothing) + next*g.Smoothing
+
+	return clamp(next, g.MinLimit, g.MaxLimit)
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func clamp(v float64, minLimit, maxLimit int) float64 {
+	return math.Max(float64(max(1, minLimit)), math.Min(float64(maxLimit), v))
+}
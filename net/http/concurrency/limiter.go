@@ -0,0 +1,135 @@
+package concurrency
+
+import (
+	"sync"
+	"time"
+)
+
+// Priority of a request
+type Priority int
+
+const (
+	// PriorityLow requests are shed first
+	PriorityLow Priority = iota - 1
+	// PriorityNormal requests are admitted up to the limit
+	PriorityNormal
+	// PriorityCritical requests are never shed
+	PriorityCritical
+)
+
+type (
+	// Limiter admits requests up to an adaptive concurrency limit
+	Limiter struct {
+		algorithm Algorithm
+		// lowRatio of the limit up to which low priority requests are admitted
+		lowRatio float64
+		clock    func() time.Time
+		limit    float64
+		inflight int
+		lock     sync.Mutex
+	}
+	LimiterOption func(*Limiter)
+	// Token of an admitted request
+	Token struct {
+		limiter  *Limiter
+		start    time.Time
+		inflight int
+		once     sync.Once
+	}
+)
+
+// LimiterWithLowPriorityRatio sets the ratio of the limit up to which low
+// priority requests are admitted
+func LimiterWithLowPriorityRatio(v float64) LimiterOption {
+	return func(o *Limiter) {
+		o.lowRatio = v
+	}
+}
+
+// LimiterWithClock sets the clock, e.g. for testing
+func LimiterWithClock(v func() time.Time) LimiterOption {
+	return func(o *Limiter) {
+		o.clock = v
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+func NewLimiter(algorithm Algorithm, opts ...LimiterOption) *Limiter {
+	inst := &Limiter{
+		algorithm: algorithm,
+		lowRatio:  0.8,
+		clock:     time.Now,
+		limit:     algorithm.Initial(),
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(inst)
+		}
+	}
+
+	return inst
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Getter
+// ------------------------------------------------------------------------------------------------
+
+// Limit returns the current limit
+func (l *Limiter) Limit() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return int(l.limit)
+}
+
+// Inflight returns the number of admitted requests
+func (l *Limiter) Inflight() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.inflight
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// Acquire admits a request of the given priority and returns its token,
+// which must be released once the request is done, or false if it
+// should be shed
+func (l *Limiter) Acquire(priority Priority) (*Token, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	limit := l.limit
+	if priority < PriorityNormal {
+		limit *= l.lowRatio
+	}
+
+	if priority < PriorityCritical && float64(l.inflight) >= limit {
+		return nil, false
+	}
+
+	l.inflight++
+
+	return &Token{limiter: l, start: l.clock(), inflight: l.inflight}, true
+}
+
+// Release reports the outcome of the request to the algorithm. Dropped
+// requests e.g. timed out or were rejected by an overloaded dependency.
+func (t *Token) Release(dropped bool) {
+	t.once.Do(func() {
+		l := t.limiter
+		rtt := l.clock().Sub(t.start)
+
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		l.inflight--
+		l.limit = l.algorithm.Update(l.limit, t.inflight, rtt, dropped)
+	})
+}
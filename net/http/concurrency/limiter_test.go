@@ -0,0 +1,132 @@
+package concurrency_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/foomo/keel/net/http/concurrency"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_priorities(t *testing.T) {
+	t.Parallel()
+
+	limiter := concurrency.NewLimiter(&concurrency.AIMD{MinLimit: 10, MaxLimit: 10, InitialLimit: 10, BackoffRatio: 0.5})
+
+	var tokens []*concurrency.Token
+
+	for range 8 {
+		token, ok := limiter.Acquire(concurrency.PriorityNormal)
+		require.True(t, ok)
+
+		tokens = append(tokens, token)
+	}
+
+	// low priority requests are shed first
+	_, ok := limiter.Acquire(concurrency.PriorityLow)
+	assert.False(t, ok)
+
+	for range 2 {
+		_, ok = limiter.Acquire(concurrency.PriorityNormal)
+		assert.True(t, ok)
+	}
+
+	_, ok = limiter.Acquire(concurrency.PriorityNormal)
+	assert.False(t, ok)
+
+	// critical requests are never shed
+	_, ok = limiter.Acquire(concurrency.PriorityCritical)
+	assert.True(t, ok)
+	assert.Equal(t, 11, limiter.Inflight())
+
+	// tokens are released once
+	tokens[0].Release(false)
+	tokens[0].Release(false)
+	assert.Equal(t, 10, limiter.Inflight())
+}
+
+func TestAIMD(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	limiter := concurrency.NewLimiter(&concurrency.AIMD{
+		MinLimit:     2,
+		MaxLimit:     20,
+		InitialLimit: 4,
+		BackoffRatio: 0.5,
+		Timeout:      time.Second,
+	}, concurrency.LimiterWithClock(func() time.Time { return now }))
+
+	acquire := func(n int) []*concurrency.Token {
+		tokens := make([]*concurrency.Token, 0, n)
+		for range n {
+			token, ok := limiter.Acquire(concurrency.PriorityNormal)
+			require.True(t, ok)
+
+			tokens = append(tokens, token)
+		}
+
+		return tokens
+	}
+
+	// grows additively while utilized
+	for _, token := range acquire(4) {
+		token.Release(false)
+	}
+
+	assert.Equal(t, 7, limiter.Limit())
+
+	// does not grow while underutilized
+	acquire(1)[0].Release(false)
+	assert.Equal(t, 7, limiter.Limit())
+
+	// backs off on drops
+	acquire(1)[0].Release(true)
+	assert.Equal(t, 3, limiter.Limit())
+
+	// backs off on slow requests
+	token := acquire(1)[0]
+	now = now.Add(2 * time.Second)
+	token.Release(false)
+	assert.Equal(t, 2, limiter.Limit())
+}
+
+func TestGradient(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	limiter := concurrency.NewLimiter(&concurrency.Gradient{
+		MinLimit:     10,
+		MaxLimit:     1000,
+		InitialLimit: 100,
+		Smoothing:    1,
+		Tolerance:    1,
+		LongWindow:   600,
+	}, concurrency.LimiterWithClock(func() time.Time { return now }))
+
+	sample := func(n int, rtt time.Duration) {
+		tokens := make([]*concurrency.Token, 0, n)
+		for range n {
+			token, ok := limiter.Acquire(concurrency.PriorityCritical)
+			require.True(t, ok)
+
+			tokens = append(tokens, token)
+		}
+
+		now = now.Add(rtt)
+
+		for _, token := range tokens {
+			token.Release(false)
+		}
+	}
+
+	// grows by the queue size at a steady latency
+	sample(100, 10*time.Millisecond)
+	assert.Greater(t, limiter.Limit(), 100)
+
+	// shrinks once the latency increases
+	before := limiter.Limit()
+	sample(before, 100*time.Millisecond)
+	assert.Less(t, limiter.Limit(), before)
+}
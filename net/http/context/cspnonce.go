@@ -0,0 +1,19 @@
+package context
+
+import (
+	"context"
+)
+
+const ContextKeyCSPNonce contextKey = "cspNonce"
+
+func GetCSPNonce(ctx context.Context) (string, bool) {
+	if value, ok := ctx.Value(ContextKeyCSPNonce).(string); ok {
+		return value, true
+	} else {
+		return "", false
+	}
+}
+
+func SetCSPNonce(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, ContextKeyCSPNonce, nonce)
+}
@@ -0,0 +1,19 @@
+package context
+
+import (
+	"context"
+)
+
+const ContextKeyCSRFToken contextKey = "csrfToken"
+
+func GetCSRFToken(ctx context.Context) (string, bool) {
+	if value, ok := ctx.Value(ContextKeyCSRFToken).(string); ok {
+		return value, true
+	} else {
+		return "", false
+	}
+}
+
+func SetCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, ContextKeyCSRFToken, token)
+}
@@ -0,0 +1,188 @@
+package cookie
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidValue = errors.New("invalid cookie value")
+	ErrExpiredValue = errors.New("expired cookie value")
+)
+
+const (
+	// timestampSize of the issue time prefixed to the payload
+	timestampSize = 8
+	gcmNonceSize  = 12
+	gcmTagSize    = 16
+)
+
+type (
+	// Codec encodes the cookie values before they are set and decodes them once read
+	Codec interface {
+		Encode(name, value string) (string, error)
+		Decode(name, value string) (string, error)
+	}
+	CodecOptions struct {
+		// MaxAge of the values enforced independently of the cookie's expiry, 0 disables the check
+		MaxAge time.Duration
+		// Clock to retrieve the issue and validation time
+		Clock func() time.Time
+	}
+	CodecOption func(*CodecOptions)
+	// SignedCodec signs the values with HMAC-SHA256, the values remain readable
+	SignedCodec struct {
+		keyring *Keyring
+		opts    CodecOptions
+	}
+	// EncryptedCodec encrypts and authenticates the values with AES-GCM
+	EncryptedCodec struct {
+		keyring *Keyring
+		opts    CodecOptions
+	}
+)
+
+// GetDefaultCodecOptions returns the default options
+func GetDefaultCodecOptions() CodecOptions {
+	return CodecOptions{
+		Clock: time.Now,
+	}
+}
+
+// CodecWithMaxAge option
+func CodecWithMaxAge(v time.Duration) CodecOption {
+	return func(o *CodecOptions) {
+		o.MaxAge = v
+	}
+}
+
+// CodecWithClock option
+func CodecWithClock(v func() time.Time) CodecOption {
+	return func(o *CodecOptions) {
+		o.Clock = v
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewSignedCodec returns a new codec signing the values
+func NewSignedCodec(keyring *Keyring, opts ...CodecOption) *SignedCodec {
+	return &SignedCodec{
+		keyring: keyring,
+		opts:    newCodecOptions(opts),
+	}
+}
+
+// NewEncryptedCodec returns a new codec encrypting the values
+func NewEncryptedCodec(keyring *Keyring, opts ...CodecOption) *EncryptedCodec {
+	return &EncryptedCodec{
+		keyring: keyring,
+		opts:    newCodecOptions(opts),
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// Encode returns base64(timestamp | value | mac)
+func (c *SignedCodec) Encode(name, value string) (string, error) {
+	payload := c.opts.payload(value)
+	return base64.RawURLEncoding.EncodeToString(append(payload, sign(c.keyring.current().sign, name, payload)...)), nil
+}
+
+func (c *SignedCodec) Decode(name, value string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) < timestampSize+sha256.Size {
+		return "", ErrInvalidValue
+	}
+
+	payload, mac := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	for _, key := range c.keyring.all() {
+		if hmac.Equal(mac, sign(key.sign, name, payload)) {
+			return c.opts.value(payload)
+		}
+	}
+
+	return "", ErrInvalidValue
+}
+
+// Encode returns base64(nonce | sealed(timestamp | value))
+func (c *EncryptedCodec) Encode(name, value string) (string, error) {
+	aead := c.keyring.current().aead
+
+	nonce := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, "failed to generate nonce")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, c.opts.payload(value), []byte(name))), nil
+}
+
+func (c *EncryptedCodec) Decode(name, value string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) < gcmNonceSize+gcmTagSize+timestampSize {
+		return "", ErrInvalidValue
+	}
+
+	for _, key := range c.keyring.all() {
+		if payload, err := key.aead.Open(nil, data[:gcmNonceSize], data[gcmNonceSize:], []byte(name)); err == nil {
+			return c.opts.value(payload)
+		}
+	}
+
+	return "", ErrInvalidValue
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func newCodecOptions(opts []CodecOption) CodecOptions {
+	options := GetDefaultCodecOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return options
+}
+
+// payload prefixes the value with the issue time
+func (o CodecOptions) payload(value string) []byte {
+	payload := make([]byte, timestampSize, timestampSize+len(value))
+	binary.BigEndian.PutUint64(payload, uint64(o.Clock().Unix())) //nolint:gosec // G115: unix time is positive
+	return append(payload, value...)
+}
+
+// value returns the value of the payload unless it exceeded the max age
+func (o CodecOptions) value(payload []byte) (string, error) {
+	if o.MaxAge > 0 {
+		issued := time.Unix(int64(binary.BigEndian.Uint64(payload[:timestampSize])), 0) //nolint:gosec // G115: written by payload
+		if o.Clock().Sub(issued) > o.MaxAge {
+			return "", ErrExpiredValue
+		}
+	}
+
+	return string(payload[timestampSize:]), nil
+}
+
+// sign binds the payload to the cookie name so values can not be swapped between cookies
+func sign(key []byte, name string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(name))
+	_, _ = mac.Write([]byte{0})
+	_, _ = mac.Write(payload)
+
+	return mac.Sum(nil)
+}
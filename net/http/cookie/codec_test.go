@@ -0,0 +1,118 @@
+package cookie_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foomo/keel/net/http/cookie"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	keyring, err := cookie.NewKeyring(bytes.Repeat([]byte("a"), 32))
+	require.NoError(t, err)
+
+	codecs := map[string]cookie.Codec{
+		"signed":    cookie.NewSignedCodec(keyring, cookie.CodecWithMaxAge(time.Hour), cookie.CodecWithClock(clock)),
+		"encrypted": cookie.NewEncryptedCodec(keyring, cookie.CodecWithMaxAge(time.Hour), cookie.CodecWithClock(clock)),
+	}
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			value, err := codec.Encode("name", "value")
+			require.NoError(t, err)
+
+			decoded, err := codec.Decode("name", value)
+			require.NoError(t, err)
+			assert.Equal(t, "value", decoded)
+
+			// bound to the cookie name
+			_, err = codec.Decode("other", value)
+			require.ErrorIs(t, err, cookie.ErrInvalidValue)
+
+			// tampered
+			tampered := []byte(value)
+			tampered[len(tampered)/2] ^= 1
+			_, err = codec.Decode("name", string(tampered))
+			require.ErrorIs(t, err, cookie.ErrInvalidValue)
+			_, err = codec.Decode("name", "value")
+			require.ErrorIs(t, err, cookie.ErrInvalidValue)
+		})
+	}
+
+	// rotation keeps verifying old values
+	value, err := codecs["encrypted"].Encode("name", "value")
+	require.NoError(t, err)
+	require.NoError(t, keyring.Rotate(bytes.Repeat([]byte("b"), 32), 2))
+	rotated, err := codecs["encrypted"].Encode("name", "value")
+	require.NoError(t, err)
+	decoded, err := codecs["encrypted"].Decode("name", value)
+	require.NoError(t, err)
+	assert.Equal(t, "value", decoded)
+
+	// dropped keys no longer verify
+	require.NoError(t, keyring.Rotate(bytes.Repeat([]byte("c"), 32), 2))
+	assert.Equal(t, 2, keyring.Len())
+	_, err = codecs["encrypted"].Decode("name", value)
+	require.ErrorIs(t, err, cookie.ErrInvalidValue)
+	_, err = codecs["encrypted"].Decode("name", rotated)
+	require.NoError(t, err)
+
+	// max age
+	now = now.Add(2 * time.Hour)
+	_, err = codecs["encrypted"].Decode("name", rotated)
+	require.ErrorIs(t, err, cookie.ErrExpiredValue)
+
+	_, err = cookie.NewKeyring([]byte("short"))
+	require.ErrorIs(t, err, cookie.ErrKeyTooShort)
+}
+
+func TestCookie_codec(t *testing.T) {
+	t.Parallel()
+
+	keyring, err := cookie.NewKeyring(bytes.Repeat([]byte("a"), 32))
+	require.NoError(t, err)
+
+	type preferences struct {
+		Language string `json:"language"`
+		Dark     bool   `json:"dark"`
+	}
+
+	c := cookie.New("prefs", cookie.WithCodec(cookie.NewEncryptedCodec(keyring)))
+
+	w := httptest.NewRecorder()
+	_, err = cookie.SetValue(c, w, httptest.NewRequest(http.MethodGet, "/", nil), preferences{Language: "de", Dark: true})
+	require.NoError(t, err)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.NotContains(t, cookies[0].Value, "de")
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookies[0])
+	value, err := cookie.GetValue[preferences](c, r)
+	require.NoError(t, err)
+	assert.Equal(t, preferences{Language: "de", Dark: true}, value)
+
+	// forged
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "prefs", Value: strings.Repeat("A", 64)})
+	_, err = cookie.GetValue[preferences](c, r)
+	require.ErrorIs(t, err, cookie.ErrInvalidValue)
+
+	// replaced request cookie
+	cookie.SetRequestCookie(r, cookies[0])
+	require.Len(t, r.Cookies(), 1)
+	value, err = cookie.GetValue[preferences](c, r)
+	require.NoError(t, err)
+	assert.True(t, value.Dark)
+}
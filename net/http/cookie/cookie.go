@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+
+	keelhttp "github.com/foomo/keel/net/http"
 )
 
 type (
@@ -26,6 +28,8 @@ type (
 		TimeProvider TimeProvider
 		// DomainProvider function to retrieve the domain flag of the created cookie
 		DomainProvider DomainProvider
+		// Codec (optional) to sign or encrypt the cookie value
+		Codec Codec
 	}
 	Option func(options *Cookie)
 )
@@ -86,6 +90,13 @@ func WithDomainProvider(v DomainProvider) Option {
 	}
 }
 
+// WithCodec middleware option
+func WithCodec(v Codec) Option {
+	return func(o *Cookie) {
+		o.Codec = v
+	}
+}
+
 // New return a new provider
 func New(name string, opts ...Option) Cookie {
 	inst := Cookie{
@@ -125,10 +136,25 @@ func (c Cookie) Delete(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// Get returns the request cookie with the decoded value
 func (c Cookie) Get(r *http.Request) (*http.Cookie, error) {
-	return r.Cookie(c.Name)
+	cookie, err := r.Cookie(c.Name)
+	if err != nil || c.Codec == nil {
+		return cookie, err
+	}
+
+	value, err := c.Codec.Decode(c.Name, cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := *cookie
+	ret.Value = value
+
+	return &ret, nil
 }
 
+// Set sets the cookie with the encoded value
 func (c Cookie) Set(w http.ResponseWriter, r *http.Request, value string, opts ...Option) (*http.Cookie, error) {
 	domain, err := c.DomainProvider(r)
 	if err != nil {
@@ -140,6 +166,13 @@ func (c Cookie) Set(w http.ResponseWriter, r *http.Request, value string, opts .
 		opt(&options)
 	}
 
+	// don't encode deleted cookies
+	if options.Codec != nil && options.MaxAge >= 0 {
+		if value, err = options.Codec.Encode(c.Name, value); err != nil {
+			return nil, errors.Wrap(err, "failed to encode cookie value")
+		}
+	}
+
 	cookie := &http.Cookie{ //nolint:gosec // G124: provided by options
 		Name:     c.Name,
 		Value:    value,
@@ -158,3 +191,17 @@ func (c Cookie) Set(w http.ResponseWriter, r *http.Request, value string, opts .
 
 	return cookie, nil
 }
+
+// SetRequestCookie adds the cookie to the request replacing existing cookies with the same name
+func SetRequestCookie(r *http.Request, cookie *http.Cookie) {
+	cookies := r.Cookies()
+	r.Header.Del(keelhttp.HeaderCookie)
+
+	for _, value := range cookies {
+		if value.Name != cookie.Name {
+			r.AddCookie(value)
+		}
+	}
+
+	r.AddCookie(cookie)
+}
@@ -0,0 +1,136 @@
+package cookie
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// MinKeyLength of the keyring secrets
+const MinKeyLength = 32
+
+var (
+	ErrKeyringEmpty = errors.New("keyring without keys")
+	ErrKeyTooShort  = errors.New("keyring key too short")
+)
+
+type (
+	// Keyring holds the secrets of the signed and encrypted cookies. The newest
+	// key is used to sign and encrypt, all keys are used to verify and decrypt.
+	Keyring struct {
+		keys []keyringKey
+		lock sync.RWMutex
+	}
+	keyringKey struct {
+		sign []byte
+		aead cipher.AEAD
+	}
+)
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewKeyring returns a new keyring with the given secrets, newest first
+func NewKeyring(secrets ...[]byte) (*Keyring, error) {
+	if len(secrets) == 0 {
+		return nil, ErrKeyringEmpty
+	}
+
+	inst := &Keyring{}
+
+	for _, secret := range secrets {
+		key, err := newKeyringKey(secret)
+		if err != nil {
+			return nil, err
+		}
+
+		inst.keys = append(inst.keys, key)
+	}
+
+	return inst, nil
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// Rotate adds the secret as the newest key and keeps at most keep keys, 0 keeps all
+func (k *Keyring) Rotate(secret []byte, keep int) error {
+	key, err := newKeyringKey(secret)
+	if err != nil {
+		return err
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.keys = append([]keyringKey{key}, k.keys...)
+	if keep > 0 && len(k.keys) > keep {
+		k.keys = k.keys[:keep]
+	}
+
+	return nil
+}
+
+// Len returns the number of keys
+func (k *Keyring) Len() int {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	return len(k.keys)
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func (k *Keyring) current() keyringKey {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	return k.keys[0]
+}
+
+func (k *Keyring) all() []keyringKey {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	return k.keys
+}
+
+// newKeyringKey derives separate signing and encryption keys from the secret
+func newKeyringKey(secret []byte) (keyringKey, error) {
+	if len(secret) < MinKeyLength {
+		return keyringKey{}, ErrKeyTooShort
+	}
+
+	sign, err := hkdf.Key(sha256.New, secret, nil, "keel cookie signing", 32)
+	if err != nil {
+		return keyringKey{}, errors.Wrap(err, "failed to derive signing key")
+	}
+
+	encrypt, err := hkdf.Key(sha256.New, secret, nil, "keel cookie encryption", 32)
+	if err != nil {
+		return keyringKey{}, errors.Wrap(err, "failed to derive encryption key")
+	}
+
+	block, err := aes.NewCipher(encrypt)
+	if err != nil {
+		return keyringKey{}, errors.Wrap(err, "failed to create cipher")
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return keyringKey{}, errors.Wrap(err, "failed to create gcm")
+	}
+
+	return keyringKey{
+		sign: sign,
+		aead: aead,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package cookie
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// SetValue sets the cookie with the json encoded value
+func SetValue[T any](c Cookie, w http.ResponseWriter, r *http.Request, value T, opts ...Option) (*http.Cookie, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal cookie value")
+	}
+
+	return c.Set(w, r, base64.RawURLEncoding.EncodeToString(data), opts...)
+}
+
+// GetValue returns the json decoded value of the cookie
+func GetValue[T any](c Cookie, r *http.Request) (T, error) {
+	var ret T
+
+	cookie, err := c.Get(r)
+	if err != nil {
+		return ret, err
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return ret, ErrInvalidValue
+	}
+
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return ret, errors.Wrap(ErrInvalidValue, err.Error())
+	}
+
+	return ret, nil
+}
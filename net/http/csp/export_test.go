@@ -0,0 +1,7 @@
+package csp
+
+// ReportMetricValue exposes the unexported reportMetricValue for testing.
+var ReportMetricValue = reportMetricValue
+
+// KnownDirectives exposes the unexported knownDirectives for testing.
+var KnownDirectives = knownDirectives
@@ -0,0 +1,15 @@
+package csp
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
+
+// NewNonce returns a random base64 encoded nonce with 128 bits of entropy
+func NewNonce() string {
+	b := make([]byte, 16)
+	// never returns an error
+	_, _ = rand.Read(b)
+
+	return base64.StdEncoding.EncodeToString(b)
+}
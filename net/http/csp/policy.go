@@ -0,0 +1,270 @@
+package csp
+
+import (
+	"slices"
+	"strings"
+)
+
+// Source keywords and schemes
+const (
+	Self          = "'self'"
+	None          = "'none'"
+	UnsafeInline  = "'unsafe-inline'"
+	UnsafeEval    = "'unsafe-eval'"
+	StrictDynamic = "'strict-dynamic'"
+	ReportSample  = "'report-sample'"
+	Data          = "data:"
+	Blob          = "blob:"
+	HTTPS         = "https:"
+	// Nonce is a placeholder replaced by the per-request nonce
+	Nonce = "'nonce'"
+)
+
+// Directive names
+const (
+	DirectiveDefaultSrc              = "default-src"
+	DirectiveScriptSrc               = "script-src"
+	DirectiveStyleSrc                = "style-src"
+	DirectiveImgSrc                  = "img-src"
+	DirectiveFontSrc                 = "font-src"
+	DirectiveConnectSrc              = "connect-src"
+	DirectiveMediaSrc                = "media-src"
+	DirectiveObjectSrc               = "object-src"
+	DirectiveFrameSrc                = "frame-src"
+	DirectiveWorkerSrc               = "worker-src"
+	DirectiveManifestSrc             = "manifest-src"
+	DirectiveBaseURI                 = "base-uri"
+	DirectiveFormAction              = "form-action"
+	DirectiveFrameAncestors          = "frame-ancestors"
+	DirectiveUpgradeInsecureRequests = "upgrade-insecure-requests"
+	DirectiveReportURI               = "report-uri"
+	DirectiveReportTo                = "report-to"
+)
+
+type (
+	// Policy builds a Content-Security-Policy header value. Directives are
+	// rendered in the order they were first set.
+	Policy struct {
+		directives []directive
+	}
+	directive struct {
+		name    string
+		sources []string
+	}
+)
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// New returns an empty policy
+func New() *Policy {
+	return &Policy{}
+}
+
+// Default returns a strict, nonce based policy
+func Default() *Policy {
+	return New().
+		DefaultSrc(Self).
+		ScriptSrc(Self, Nonce).
+		StyleSrc(Self, Nonce).
+		ImgSrc(Self, Data).
+		ObjectSrc(None).
+		BaseURI(Self).
+		FormAction(Self).
+		FrameAncestors(None)
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// Set replaces the sources of the directive
+func (p *Policy) Set(name string, sources ...string) *Policy {
+	if i := p.index(name); i >= 0 {
+		p.directives[i].sources = slices.Clone(sources)
+	} else {
+		p.directives = append(p.directives, directive{name: name, sources: slices.Clone(sources)})
+	}
+
+	return p
+}
+
+// Add appends the sources to the directive unless already present
+func (p *Policy) Add(name string, sources ...string) *Policy {
+	i := p.index(name)
+	if i < 0 {
+		return p.Set(name, sources...)
+	}
+
+	for _, source := range sources {
+		if !slices.Contains(p.directives[i].sources, source) {
+			p.directives[i].sources = append(p.directives[i].sources, source)
+		}
+	}
+
+	return p
+}
+
+// Del removes the directive
+func (p *Policy) Del(name string) *Policy {
+	if i := p.index(name); i >= 0 {
+		p.directives = slices.Delete(p.directives, i, i+1)
+	}
+
+	return p
+}
+
+// Get returns the sources of the directive
+func (p *Policy) Get(name string) ([]string, bool) {
+	if i := p.index(name); i >= 0 {
+		return slices.Clone(p.directives[i].sources), true
+	}
+
+	return nil, false
+}
+
+// Clone returns a deep copy of the policy
+func (p *Policy) Clone() *Policy {
+	ret := New()
+	for _, d := range p.directives {
+		ret.Set(d.name, d.sources...)
+	}
+
+	return ret
+}
+
+// HasNonce returns true if any directive uses the Nonce placeholder
+func (p *Policy) HasNonce() bool {
+	for _, d := range p.directives {
+		if slices.Contains(d.sources, Nonce) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DefaultSrc sets the default-src directive
+func (p *Policy) DefaultSrc(sources ...string) *Policy {
+	return p.Set(DirectiveDefaultSrc, sources...)
+}
+
+// ScriptSrc sets the script-src directive
+func (p *Policy) ScriptSrc(sources ...string) *Policy {
+	return p.Set(DirectiveScriptSrc, sources...)
+}
+
+// StyleSrc sets the style-src directive
+func (p *Policy) StyleSrc(sources ...string) *Policy {
+	return p.Set(DirectiveStyleSrc, sources...)
+}
+
+// ImgSrc sets the img-src directive
+func (p *Policy) ImgSrc(sources ...string) *Policy {
+	return p.Set(DirectiveImgSrc, sources...)
+}
+
+// FontSrc sets the font-src directive
+func (p *Policy) FontSrc(sources ...string) *Policy {
+	return p.Set(DirectiveFontSrc, sources...)
+}
+
+// ConnectSrc sets the connect-src directive
+func (p *Policy) ConnectSrc(sources ...string) *Policy {
+	return p.Set(DirectiveConnectSrc, sources...)
+}
+
+// MediaSrc sets the media-src directive
+func (p *Policy) MediaSrc(sources ...string) *Policy {
+	return p.Set(DirectiveMediaSrc, sources...)
+}
+
+// ObjectSrc sets the object-src directive
+func (p *Policy) ObjectSrc(sources ...string) *Policy {
+	return p.Set(DirectiveObjectSrc, sources...)
+}
+
+// FrameSrc sets the frame-src directive
+func (p *Policy) FrameSrc(sources ...string) *Policy {
+	return p.Set(DirectiveFrameSrc, sources...)
+}
+
+// WorkerSrc sets the worker-src directive
+func (p *Policy) WorkerSrc(sources ...string) *Policy {
+	return p.Set(DirectiveWorkerSrc, sources...)
+}
+
+// ManifestSrc sets the manifest-src directive
+func (p *Policy) ManifestSrc(sources ...string) *Policy {
+	return p.Set(DirectiveManifestSrc, sources...)
+}
+
+// BaseURI sets the base-uri directive
+func (p *Policy) BaseURI(sources ...string) *Policy {
+	return p.Set(DirectiveBaseURI, sources...)
+}
+
+// FormAction sets the form-action directive
+func (p *Policy) FormAction(sources ...string) *Policy {
+	return p.Set(DirectiveFormAction, sources...)
+}
+
+// FrameAncestors sets the frame-ancestors directive
+func (p *Policy) FrameAncestors(sources ...string) *Policy {
+	return p.Set(DirectiveFrameAncestors, sources...)
+}
+
+// UpgradeInsecureRequests sets the upgrade-insecure-requests directive
+func (p *Policy) UpgradeInsecureRequests() *Policy {
+	return p.Set(DirectiveUpgradeInsecureRequests)
+}
+
+// ReportURI sets the deprecated report-uri directive
+func (p *Policy) ReportURI(uri string) *Policy {
+	return p.Set(DirectiveReportURI, uri)
+}
+
+// ReportTo sets the report-to directive to the endpoint group of the Reporting-Endpoints header
+func (p *Policy) ReportTo(group string) *Policy {
+	return p.Set(DirectiveReportTo, group)
+}
+
+// String returns the header value with the Nonce placeholder replaced by the
+// given nonce or removed if it is empty
+func (p *Policy) String(nonce string) string {
+	var b strings.Builder
+
+	for i, d := range p.directives {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+
+		b.WriteString(d.name)
+
+		for _, source := range d.sources {
+			if source == Nonce {
+				if nonce == "" {
+					continue
+				}
+
+				source = "'nonce-" + nonce + "'"
+			}
+
+			b.WriteByte(' ')
+			b.WriteString(source)
+		}
+	}
+
+	return b.String()
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func (p *Policy) index(name string) int {
+	return slices.IndexFunc(p.directives, func(d directive) bool {
+		return d.name == name
+	})
+}
@@ -0,0 +1,39 @@
+package csp_test
+
+import (
+	"testing"
+
+	"github.com/foomo/keel/net/http/csp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	p := csp.New().
+		DefaultSrc(csp.Self).
+		ScriptSrc(csp.Self, csp.Nonce).
+		UpgradeInsecureRequests()
+	p.Add(csp.DirectiveScriptSrc, "https://cdn.example.com", csp.Self)
+
+	assert.True(t, p.HasNonce())
+	assert.Equal(t, "default-src 'self'; script-src 'self' 'nonce-abc' https://cdn.example.com; upgrade-insecure-requests", p.String("abc"))
+	assert.Equal(t, "default-src 'self'; script-src 'self' https://cdn.example.com; upgrade-insecure-requests", p.String(""))
+
+	clone := p.Clone().Del(csp.DirectiveScriptSrc)
+	_, ok := clone.Get(csp.DirectiveScriptSrc)
+	assert.False(t, ok)
+
+	sources, ok := p.Get(csp.DirectiveScriptSrc)
+	require.True(t, ok)
+	assert.Len(t, sources, 3)
+}
+
+func TestNewNonce(t *testing.T) {
+	t.Parallel()
+
+	a, b := csp.NewNonce(), csp.NewNonce()
+	assert.Len(t, a, 24)
+	assert.NotEqual(t, a, b)
+}
@@ -0,0 +1,299 @@
+package csp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/foomo/keel/telemetry"
+	httputils "github.com/foomo/keel/utils/net/http"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+
+	"github.com/foomo/keel/log"
+)
+
+const (
+	// ContentTypeCSPReport of the deprecated report-uri reports
+	ContentTypeCSPReport = "application/csp-report"
+	// ContentTypeReports of the Reporting API
+	ContentTypeReports = "application/reports+json"
+	// ReportTypeCSPViolation of the Reporting API
+	ReportTypeCSPViolation = "csp-violation"
+)
+
+const (
+	reportTypeKey  = attribute.Key("keel.report.type")
+	directiveKey   = attribute.Key("keel.csp.directive")
+	dispositionKey = attribute.Key("keel.csp.disposition")
+)
+
+// reportMetricValueOther replaces unknown values in the metric attributes
+const reportMetricValueOther = "other"
+
+// known metric attribute values, the endpoint is public so the values of the
+// reports must not create arbitrary metric series
+var (
+	knownReportTypes = map[string]bool{
+		ReportTypeCSPViolation:         true,
+		"coep":                         true,
+		"coop":                         true,
+		"crash":                        true,
+		"deprecation":                  true,
+		"document-policy-violation":    true,
+		"intervention":                 true,
+		"network-error":                true,
+		"permissions-policy-violation": true,
+		"integrity-violation":          true,
+	}
+	knownDirectives = map[string]bool{
+		DirectiveDefaultSrc:              true,
+		DirectiveScriptSrc:               true,
+		DirectiveStyleSrc:                true,
+		DirectiveImgSrc:                  true,
+		DirectiveFontSrc:                 true,
+		DirectiveConnectSrc:              true,
+		DirectiveMediaSrc:                true,
+		DirectiveObjectSrc:               true,
+		DirectiveFrameSrc:                true,
+		DirectiveWorkerSrc:               true,
+		DirectiveManifestSrc:             true,
+		DirectiveBaseURI:                 true,
+		DirectiveFormAction:              true,
+		DirectiveFrameAncestors:          true,
+		DirectiveUpgradeInsecureRequests: true,
+		"child-src":                      true,
+		"script-src-elem":                true,
+		"script-src-attr":                true,
+		"style-src-elem":                 true,
+		"style-src-attr":                 true,
+		"require-trusted-types-for":      true,
+		"trusted-types":                  true,
+		"sandbox":                        true,
+	}
+	knownDispositions = map[string]bool{
+		"enforce": true,
+		"report":  true,
+	}
+)
+
+var ErrInvalidReport = errors.New("invalid report")
+
+type (
+	// Report of the Reporting API
+	Report struct {
+		Type      string          `json:"type"`
+		Age       int64           `json:"age,omitempty"`
+		URL       string          `json:"url"`
+		UserAgent string          `json:"user_agent,omitempty"`
+		Body      json.RawMessage `json:"body,omitempty"`
+	}
+	// Violation is the body of a csp-violation report
+	Violation struct {
+		DocumentURL        string `json:"documentURL"`
+		Referrer           string `json:"referrer,omitempty"`
+		BlockedURL         string `json:"blockedURL,omitempty"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		OriginalPolicy     string `json:"originalPolicy,omitempty"`
+		SourceFile         string `json:"sourceFile,omitempty"`
+		Sample             string `json:"sample,omitempty"`
+		Disposition        string `json:"disposition,omitempty"`
+		StatusCode         int    `json:"statusCode,omitempty"`
+		LineNumber         int    `json:"lineNumber,omitempty"`
+		ColumnNumber       int    `json:"columnNumber,omitempty"`
+	}
+	// legacyReport is sent for the report-uri directive
+	legacyReport struct {
+		CSPReport struct {
+			DocumentURI        string `json:"document-uri"`
+			Referrer           string `json:"referrer"`
+			BlockedURI         string `json:"blocked-uri"`
+			ViolatedDirective  string `json:"violated-directive"`
+			EffectiveDirective string `json:"effective-directive"`
+			OriginalPolicy     string `json:"original-policy"`
+			SourceFile         string `json:"source-file"`
+			ScriptSample       string `json:"script-sample"`
+			Disposition        string `json:"disposition"`
+			StatusCode         int    `json:"status-code"`
+			LineNumber         int    `json:"line-number"`
+			ColumnNumber       int    `json:"column-number"`
+		} `json:"csp-report"`
+	}
+	ReportHandlerOptions struct {
+		// MaxBodySize of accepted reports
+		MaxBodySize int64
+	}
+	ReportHandlerOption func(*ReportHandlerOptions)
+)
+
+// ReportHandlerWithMaxBodySize option
+func ReportHandlerWithMaxBodySize(v int64) ReportHandlerOption {
+	return func(o *ReportHandlerOptions) {
+		o.MaxBodySize = v
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewReportHandler returns a handler receiving both report-uri and Reporting
+// API reports. CSP violations are logged and counted by directive and
+// disposition, other report types only by type.
+func NewReportHandler(l *zap.Logger, opts ...ReportHandlerOption) http.Handler {
+	options := ReportHandlerOptions{
+		MaxBodySize: 64 << 10,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	reports := telemetry.NewIntCounter("keel.http.server.reports",
+		metric.WithDescription("Number of received browser reports"),
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			httputils.MethodNotAllowedServerError(l, w, r, errors.Errorf("method %s not allowed", r.Method))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, options.MaxBodySize))
+		if err != nil {
+			httputils.BadRequestServerError(l, w, r, errors.Wrap(err, "failed to read report"))
+			return
+		}
+
+		violations, others, err := ParseReports(body)
+		if err != nil {
+			httputils.BadRequestServerError(l, w, r, err)
+			return
+		}
+
+		for _, v := range violations {
+			reports.Add(r.Context(), 1, metric.WithAttributes(
+				reportTypeKey.String(ReportTypeCSPViolation),
+				directiveKey.String(reportMetricValue(knownDirectives, v.EffectiveDirective)),
+				dispositionKey.String(reportMetricValue(knownDispositions, v.Disposition)),
+			))
+
+			log.WithHTTPRequest(l, r).Warn("csp violation",
+				zap.String("csp_document_url", v.DocumentURL),
+				zap.String("csp_blocked_url", v.BlockedURL),
+				zap.String("csp_directive", v.EffectiveDirective),
+				zap.String("csp_disposition", v.Disposition),
+				zap.String("csp_source_file", v.SourceFile),
+				zap.Int("csp_line_number", v.LineNumber),
+				zap.Int("csp_column_number", v.ColumnNumber),
+				zap.String("csp_sample", v.Sample),
+			)
+		}
+
+		for _, report := range others {
+			reports.Add(r.Context(), 1, metric.WithAttributes(reportTypeKey.String(reportMetricValue(knownReportTypes, report.Type))))
+
+			log.WithHTTPRequest(l, r).Info("browser report",
+				zap.String("report_type", report.Type),
+				zap.String("report_url", report.URL),
+				zap.ByteString("report_body", report.Body),
+			)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// ParseReports parses either a report-uri report object or a Reporting API
+// report list and returns the CSP violations and the reports of other types
+func ParseReports(body []byte) ([]Violation, []Report, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, nil, ErrInvalidReport
+	}
+
+	if body[0] == '{' {
+		var legacy legacyReport
+		if err := json.Unmarshal(body, &legacy); err != nil {
+			return nil, nil, errors.Wrap(ErrInvalidReport, err.Error())
+		}
+
+		v := legacy.CSPReport
+
+		directive := v.EffectiveDirective
+		if directive == "" {
+			directive = v.ViolatedDirective
+		}
+
+		disposition := v.Disposition
+		if disposition == "" {
+			disposition = "enforce"
+		}
+
+		return []Violation{{
+			DocumentURL:        v.DocumentURI,
+			Referrer:           v.Referrer,
+			BlockedURL:         v.BlockedURI,
+			EffectiveDirective: directive,
+			OriginalPolicy:     v.OriginalPolicy,
+			SourceFile:         v.SourceFile,
+			Sample:             v.ScriptSample,
+			Disposition:        disposition,
+			StatusCode:         v.StatusCode,
+			LineNumber:         v.LineNumber,
+			ColumnNumber:       v.ColumnNumber,
+		}}, nil, nil
+	}
+
+	var list []Report
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, nil, errors.Wrap(ErrInvalidReport, err.Error())
+	}
+
+	var (
+		violations []Violation
+		others     []Report
+	)
+
+	for _, report := range list {
+		if report.Type != ReportTypeCSPViolation {
+			others = append(others, report)
+			continue
+		}
+
+		var v Violation
+		if err := json.Unmarshal(report.Body, &v); err != nil {
+			return nil, nil, errors.Wrap(ErrInvalidReport, err.Error())
+		}
+
+		if v.DocumentURL == "" {
+			v.DocumentURL = report.URL
+		}
+
+		violations = append(violations, v)
+	}
+
+	return violations, others, nil
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// reportMetricValue returns the value if known or other
+func reportMetricValue(known map[string]bool, value string) string {
+	if known[value] {
+		return value
+	}
+
+	return reportMetricValueOther
+}
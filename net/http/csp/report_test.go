@@ -0,0 +1,82 @@
+package csp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/foomo/keel/net/http/csp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseReports(t *testing.T) {
+	t.Parallel()
+
+	t.Run("report-uri", func(t *testing.T) {
+		t.Parallel()
+
+		violations, others, err := csp.ParseReports([]byte(`{"csp-report":{"document-uri":"https://example.com/","violated-directive":"script-src-elem","blocked-uri":"inline"}}`))
+		require.NoError(t, err)
+		assert.Empty(t, others)
+		require.Len(t, violations, 1)
+		assert.Equal(t, "script-src-elem", violations[0].EffectiveDirective)
+		assert.Equal(t, "enforce", violations[0].Disposition)
+		assert.Equal(t, "inline", violations[0].BlockedURL)
+	})
+
+	t.Run("reporting api", func(t *testing.T) {
+		t.Parallel()
+
+		violations, others, err := csp.ParseReports([]byte(`[
+			{"type":"csp-violation","url":"https://example.com/","body":{"effectiveDirective":"img-src","blockedURL":"https://evil.com/a.png","disposition":"report"}},
+			{"type":"deprecation","url":"https://example.com/","body":{"id":"foo"}}
+		]`))
+		require.NoError(t, err)
+		require.Len(t, violations, 1)
+		assert.Equal(t, "img-src", violations[0].EffectiveDirective)
+		assert.Equal(t, "https://example.com/", violations[0].DocumentURL)
+		require.Len(t, others, 1)
+		assert.Equal(t, "deprecation", others[0].Type)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := csp.ParseReports([]byte(`nope`))
+		require.ErrorIs(t, err, csp.ErrInvalidReport)
+	})
+}
+
+func TestNewReportHandler(t *testing.T) {
+	t.Parallel()
+
+	handler := csp.NewReportHandler(zap.NewNop(), csp.ReportHandlerWithMaxBodySize(128))
+
+	post := func(body string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", csp.ContentTypeReports)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, post(`[{"type":"csp-violation","body":{"effectiveDirective":"img-src"}}]`))
+	assert.Equal(t, http.StatusBadRequest, post(`[`))
+	assert.Equal(t, http.StatusBadRequest, post(`[`+strings.Repeat(" ", 256)+`]`))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestReportMetricValue(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, csp.DirectiveScriptSrc, csp.ReportMetricValue(csp.KnownDirectives, csp.DirectiveScriptSrc))
+	assert.Equal(t, "other", csp.ReportMetricValue(csp.KnownDirectives, "made-up-directive"))
+	assert.Equal(t, "other", csp.ReportMetricValue(csp.KnownDirectives, ""))
+}
@@ -67,6 +67,30 @@ const (
 	HeaderContentSecurityPolicyReportOnly = "Content-Security-Policy-Report-Only"
 	HeaderXCSRFToken                      = "X-CSRF-Token"
 	HeaderReferrerPolicy                  = "Referrer-Policy"
+	HeaderPermissionsPolicy               = "Permissions-Policy"
+	HeaderCrossOriginOpenerPolicy         = "Cross-Origin-Opener-Policy"
+	HeaderCrossOriginEmbedderPolicy       = "Cross-Origin-Embedder-Policy"
+	HeaderCrossOriginResourcePolicy       = "Cross-Origin-Resource-Policy"
+	HeaderReportingEndpoints              = "Reporting-Endpoints"
+
+	// Rate limiting
+
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+	HeaderRetryAfter         = "Retry-After"
+
+	// Caching
+
+	HeaderAge              = "Age"
+	HeaderCacheControl     = "Cache-Control"
+	HeaderETag             = "ETag"
+	HeaderExpires          = "Expires"
+	HeaderIfNoneMatch      = "If-None-Match"
+	HeaderSurrogateKey     = "Surrogate-Key"
+	HeaderSurrogateControl = "Surrogate-Control"
+	HeaderXCache           = "X-Cache"
 
 	// Telementry
 
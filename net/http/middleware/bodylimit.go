@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/foomo/keel/config"
+	keelhttp "github.com/foomo/keel/net/http"
+	httputils "github.com/foomo/keel/utils/net/http"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var ErrBodyTooLarge = errors.New("request body too large")
+
+type (
+	BodyLimitOptions struct {
+		// MaxBytes applied to routes without a limit of their own, 0 disables the limit
+		MaxBytes int64
+		// Routes limits by route template of the keelhttp.Router or path
+		Routes map[string]int64
+		// Skippers exclude requests from limiting
+		Skippers []Skipper
+		// LimitedHandler responds to requests exceeding the limit
+		LimitedHandler BodyLimitHandler
+	}
+	BodyLimitOption  func(*BodyLimitOptions)
+	BodyLimitHandler func(l *zap.Logger, w http.ResponseWriter, r *http.Request)
+	// BodyLimitRouteConfig is the file-shape configuration of a route limit
+	BodyLimitRouteConfig struct {
+		Route    string `json:"route" yaml:"route" mapstructure:"route"`
+		MaxBytes int64  `json:"maxBytes" yaml:"maxBytes" mapstructure:"maxBytes"`
+	}
+)
+
+// DefaultBodyLimitHandler function
+func DefaultBodyLimitHandler(l *zap.Logger, w http.ResponseWriter, r *http.Request) {
+	httputils.RequestEntityTooLargeServerError(l, w, r, ErrBodyTooLarge)
+}
+
+// GetDefaultBodyLimitOptions returns the default options
+func GetDefaultBodyLimitOptions() BodyLimitOptions {
+	return BodyLimitOptions{
+		MaxBytes:       10 << 20,
+		LimitedHandler: DefaultBodyLimitHandler,
+	}
+}
+
+// BodyLimitWithMaxBytes middleware option
+func BodyLimitWithMaxBytes(v int64) BodyLimitOption {
+	return func(o *BodyLimitOptions) {
+		o.MaxBytes = v
+	}
+}
+
+// BodyLimitWithRoute middleware option limits the route template or path
+func BodyLimitWithRoute(route string, v int64) BodyLimitOption {
+	return func(o *BodyLimitOptions) {
+		if o.Routes == nil {
+			o.Routes = map[string]int64{}
+		}
+
+		o.Routes[route] = v
+	}
+}
+
+// BodyLimitWithSkippers middleware option
+func BodyLimitWithSkippers(v ...Skipper) BodyLimitOption {
+	return func(o *BodyLimitOptions) {
+		o.Skippers = append(o.Skippers, v...)
+	}
+}
+
+// BodyLimitWithLimitedHandler middleware option
+func BodyLimitWithLimitedHandler(v BodyLimitHandler) BodyLimitOption {
+	return func(o *BodyLimitOptions) {
+		o.LimitedHandler = v
+	}
+}
+
+// BodyLimitWithConfig middleware option reads the limits from the config key
+//
+// Expected shape for key "http.bodyLimit":
+//
+//	http:
+//	  bodyLimit:
+//	    maxBytes: 1048576
+//	    routes:
+//	      - route: /upload/{id}
+//	        maxBytes: 104857600
+func BodyLimitWithConfig(c *viper.Viper, key string) BodyLimitOption {
+	if c == nil {
+		c = config.Config()
+	}
+
+	return func(o *BodyLimitOptions) {
+		o.MaxBytes = config.GetInt64(c, key+".maxBytes", o.MaxBytes)()
+
+		var routes []BodyLimitRouteConfig
+		if err := c.UnmarshalKey(key+".routes", &routes); err != nil {
+			panic(fmt.Sprintf("invalid config key %s.routes: %s", key, err))
+		}
+
+		for _, route := range routes {
+			BodyLimitWithRoute(route.Route, route.MaxBytes)(o)
+		}
+	}
+}
+
+// BodyLimit middleware
+func BodyLimit(opts ...BodyLimitOption) keelhttp.Middleware {
+	options := GetDefaultBodyLimitOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return BodyLimitWithOptions(options)
+}
+
+// BodyLimitWithOptions middleware enforces the maximum request body size.
+// Requests announcing a larger body are rejected upfront while chunked
+// bodies are cut off once they exceed the limit. If a handler fails to read
+// such a body, its response is replaced by the LimitedHandler.
+func BodyLimitWithOptions(opts BodyLimitOptions) keelhttp.Middleware {
+	return func(l *zap.Logger, name string, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if span.IsRecording() {
+				span.AddEvent("BodyLimit")
+			}
+
+			for _, skipper := range opts.Skippers {
+				if skipper(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			route, maxBytes := routeValue(r, opts.Routes, opts.MaxBytes)
+			if maxBytes <= 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > maxBytes {
+				if span.IsRecording() {
+					span.AddEvent("BodyLimited", trace.WithAttributes(semconv.HTTPRoute(route)))
+				}
+
+				opts.LimitedHandler(l, w, r)
+
+				return
+			}
+
+			body := &bodyLimitReader{ReadCloser: http.MaxBytesReader(w, r.Body, maxBytes)}
+			bw := &bodyLimitResponseWriter{
+				ResponseWriter: w,
+				body:           body,
+				limited: func() {
+					if span.IsRecording() {
+						span.AddEvent("BodyLimited", trace.WithAttributes(semconv.HTTPRoute(route)))
+					}
+
+					opts.LimitedHandler(l, w, r)
+				},
+			}
+
+			r.Body = body
+			next.ServeHTTP(bw, r)
+
+			// the handler gave up on the body without responding
+			if body.exceeded && !bw.wroteHeader {
+				bw.limit()
+			}
+		})
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// bodyLimitReader records whether the limit was exceeded
+type bodyLimitReader struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (r *bodyLimitReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if maxBytesErr := new(http.MaxBytesError); errors.As(err, &maxBytesErr) {
+		r.exceeded = true
+	}
+
+	return n, err
+}
+
+// bodyLimitResponseWriter replaces the handler's response once the limit was exceeded
+type bodyLimitResponseWriter struct {
+	http.ResponseWriter
+	body        *bodyLimitReader
+	limited     func()
+	wroteHeader bool
+	discard     bool
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (w *bodyLimitResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *bodyLimitResponseWriter) Flush() {
+	if w.discard {
+		return
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *bodyLimitResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+
+	if w.body.exceeded {
+		w.limit()
+		return
+	}
+
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *bodyLimitResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.discard {
+		return len(b), nil
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyLimitResponseWriter) limit() {
+	w.wroteHeader = true
+	w.discard = true
+	w.limited()
+}
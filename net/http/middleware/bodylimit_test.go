@@ -0,0 +1,82 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestBodyLimit(t *testing.T) {
+	t.Parallel()
+
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		_, _ = w.Write(b)
+	}
+
+	router := keelhttp.NewRouter(zap.NewNop(), "test")
+	router.Use(middleware.BodyLimit(
+		middleware.BodyLimitWithMaxBytes(4),
+		middleware.BodyLimitWithRoute("/upload/{id}", 8),
+	))
+	router.HandleFunc("upload", "/upload/{id}", echo)
+	router.HandleFunc("root", "/", echo)
+
+	post := func(path, body string, chunked bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if chunked {
+			r.ContentLength = -1
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := post("/", "1234", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1234", w.Body.String())
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/", "12345", false).Code)
+
+	// chunked bodies are cut off and the handler's response replaced
+	w = post("/", "12345", true)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, http.StatusText(http.StatusRequestEntityTooLarge)+"\n", w.Body.String())
+
+	// routes have their own limits
+	assert.Equal(t, http.StatusOK, post("/upload/1", "12345678", true).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/upload/1", "123456789", true).Code)
+}
+
+func TestBodyLimitWithConfig(t *testing.T) {
+	t.Parallel()
+
+	c := viper.New()
+	c.Set("bodyLimit", map[string]any{
+		"maxBytes": 2,
+		"routes": []any{
+			map[string]any{"route": "/upload", "maxBytes": 4},
+		},
+	})
+
+	options := middleware.GetDefaultBodyLimitOptions()
+	middleware.BodyLimitWithConfig(c, "bodyLimit")(&options)
+
+	assert.Equal(t, int64(2), options.MaxBytes)
+	assert.Equal(t, map[string]int64{"/upload": 4}, options.Routes)
+}
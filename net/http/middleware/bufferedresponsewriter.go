@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// bufferedResponseWriter buffers the response up to a maximum size so that it
+// can be processed once the handler returned. Larger or flushed responses are
+// streamed through.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	maxSize     int
+	statusCode  int
+	wroteHeader bool
+	streaming   bool
+	buf         bytes.Buffer
+}
+
+func newBufferedResponseWriter(w http.ResponseWriter, maxSize int) *bufferedResponseWriter {
+	return &bufferedResponseWriter{
+		ResponseWriter: w,
+		maxSize:        maxSize,
+		statusCode:     http.StatusOK,
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (w *bufferedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush switches to streaming the response
+func (w *bufferedResponseWriter) Flush() {
+	w.stream()
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *bufferedResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+	w.statusCode = statusCode
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.streaming && w.maxSize > 0 && w.buf.Len()+len(b) > w.maxSize {
+		w.stream()
+	}
+
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+
+	return w.buf.Write(b)
+}
+
+// Body returns the buffered body
+func (w *bufferedResponseWriter) Body() []byte {
+	return w.buf.Bytes()
+}
+
+// Streaming returns true if the response was already sent
+func (w *bufferedResponseWriter) Streaming() bool {
+	return w.streaming
+}
+
+// stream sends the buffered response and writes through from then on
+func (w *bufferedResponseWriter) stream() {
+	if w.streaming {
+		return
+	}
+
+	w.streaming = true
+	w.ResponseWriter.WriteHeader(w.statusCode)
+
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
@@ -0,0 +1,340 @@
+package middleware
+
+import (
+	"bytes"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/cache"
+	"github.com/foomo/keel/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/foomo/keel/log"
+)
+
+const (
+	cacheResultKey    = attribute.Key("keel.cache.result")
+	cacheResultHit    = "hit"
+	cacheResultMiss   = "miss"
+	cacheResultBypass = "bypass"
+)
+
+type (
+	CacheOptions struct {
+		// Store keeping the responses
+		Store cache.Store
+		// KeyFunc returns the key to cache the response by
+		KeyFunc CacheKeyFunc
+		// DefaultTTL of responses without explicit freshness, 0 disables caching them
+		DefaultTTL time.Duration
+		// MaxBodySize of responses to cache, larger ones are streamed
+		MaxBodySize int
+		// StripSurrogateHeaders removes the Surrogate-Key and Surrogate-Control headers from responses
+		StripSurrogateHeaders bool
+		// SetCacheHeader adds the X-Cache header with HIT or MISS to responses
+		SetCacheHeader bool
+		// Skippers exclude requests from caching
+		Skippers []Skipper
+	}
+	CacheOption  func(*CacheOptions)
+	CacheKeyFunc func(r *http.Request) string
+)
+
+// CacheRequestKey caches by host, path and query which is also the key to purge by
+func CacheRequestKey() CacheKeyFunc {
+	return func(r *http.Request) string {
+		return r.Host + r.URL.RequestURI()
+	}
+}
+
+// GetDefaultCacheOptions returns the default options
+func GetDefaultCacheOptions() CacheOptions {
+	return CacheOptions{
+		KeyFunc:               CacheRequestKey(),
+		MaxBodySize:           1 << 20,
+		StripSurrogateHeaders: true,
+		SetCacheHeader:        true,
+	}
+}
+
+// CacheWithStore middleware option
+func CacheWithStore(v cache.Store) CacheOption {
+	return func(o *CacheOptions) {
+		o.Store = v
+	}
+}
+
+// CacheWithKeyFunc middleware option
+func CacheWithKeyFunc(v CacheKeyFunc) CacheOption {
+	return func(o *CacheOptions) {
+		o.KeyFunc = v
+	}
+}
+
+// CacheWithDefaultTTL middleware option
+func CacheWithDefaultTTL(v time.Duration) CacheOption {
+	return func(o *CacheOptions) {
+		o.DefaultTTL = v
+	}
+}
+
+// CacheWithMaxBodySize middleware option
+func CacheWithMaxBodySize(v int) CacheOption {
+	return func(o *CacheOptions) {
+		o.MaxBodySize = v
+	}
+}
+
+// CacheWithStripSurrogateHeaders middleware option
+func CacheWithStripSurrogateHeaders(v bool) CacheOption {
+	return func(o *CacheOptions) {
+		o.StripSurrogateHeaders = v
+	}
+}
+
+// CacheWithSetCacheHeader middleware option
+func CacheWithSetCacheHeader(v bool) CacheOption {
+	return func(o *CacheOptions) {
+		o.SetCacheHeader = v
+	}
+}
+
+// CacheWithSkippers middleware option
+func CacheWithSkippers(v ...Skipper) CacheOption {
+	return func(o *CacheOptions) {
+		o.Skippers = append(o.Skippers, v...)
+	}
+}
+
+// Cache middleware
+func Cache(opts ...CacheOption) keelhttp.Middleware {
+	options := GetDefaultCacheOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return CacheWithOptions(options)
+}
+
+// CacheWithOptions middleware caches GET responses on the server side as
+// long as they are fresh according to their Cache-Control header and
+// serves them for GET and HEAD requests, varying by the Vary header.
+// Responses are tagged with the space separated Surrogate-Key header to
+// be purged through the store. Successful unsafe requests purge their key.
+func CacheWithOptions(opts CacheOptions) keelhttp.Middleware {
+	if opts.Store == nil {
+		opts.Store = cache.NewMemoryStore()
+	}
+
+	requests := telemetry.NewIntCounter("keel.http.server.cache.requests",
+		metric.WithDescription("Number of requests looked up in the response cache"),
+	)
+
+	return func(l *zap.Logger, name string, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if span.IsRecording() {
+				span.AddEvent("Cache")
+			}
+
+			for _, skipper := range opts.Skippers {
+				if skipper(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			key := opts.KeyFunc(r)
+			attrs := metric.WithAttributes(attribute.String("http.server_name", name))
+
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				wr := WrapResponseWriter(w)
+				next.ServeHTTP(wr, r)
+
+				if wr.StatusCode() < http.StatusBadRequest {
+					if _, err := opts.Store.Purge(r.Context(), key); err != nil {
+						log.WithError(l, err).Warn("failed to purge cache", log.FValue(key))
+					}
+				}
+
+				return
+			}
+
+			directives := cache.ParseCacheControl(r.Header.Values(keelhttp.HeaderCacheControl)...)
+			if directives.Has("no-store") {
+				requests.Add(r.Context(), 1, attrs, metric.WithAttributes(cacheResultKey.String(cacheResultBypass)))
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			now := time.Now()
+
+			entry, ok, err := opts.Store.Get(r.Context(), key)
+			if err != nil {
+				log.WithError(l, err).Warn("failed to get cache entry", log.FValue(key))
+			}
+
+			if ok && !directives.Has("no-cache") {
+				if res, ok := entry.Variants[entry.Variant(r)]; ok && res.Fresh(now) {
+					requests.Add(r.Context(), 1, attrs, metric.WithAttributes(cacheResultKey.String(cacheResultHit)))
+
+					if span.IsRecording() {
+						span.AddEvent("CacheHit")
+					}
+
+					h := w.Header()
+					maps.Copy(h, res.Header.Clone())
+					h.Set(keelhttp.HeaderAge, strconv.Itoa(int(res.Age(now).Seconds())))
+
+					if opts.SetCacheHeader {
+						h.Set(keelhttp.HeaderXCache, "HIT")
+					}
+
+					writeConditionalResponse(w, r, res.StatusCode, res.Body)
+
+					return
+				}
+			}
+
+			requests.Add(r.Context(), 1, attrs, metric.WithAttributes(cacheResultKey.String(cacheResultMiss)))
+
+			bw := newBufferedResponseWriter(w, opts.MaxBodySize)
+			if opts.SetCacheHeader {
+				w.Header().Set(keelhttp.HeaderXCache, "MISS")
+			}
+
+			next.ServeHTTP(bw, r)
+
+			h := w.Header()
+			tags := strings.Fields(h.Get(keelhttp.HeaderSurrogateKey))
+			ttl, storable := cacheTTL(r, bw.statusCode, h, opts.DefaultTTL)
+
+			if opts.StripSurrogateHeaders {
+				h.Del(keelhttp.HeaderSurrogateKey)
+				h.Del(keelhttp.HeaderSurrogateControl)
+			}
+
+			if bw.Streaming() {
+				return
+			}
+
+			if storable && r.Method == http.MethodGet {
+				header := h.Clone()
+				header.Del(keelhttp.HeaderXCache)
+
+				res := &cache.Response{
+					StatusCode: bw.statusCode,
+					Header:     header,
+					Body:       bytes.Clone(bw.Body()),
+					Stored:     now,
+					Expires:    now.Add(ttl),
+				}
+
+				if err := opts.Store.Set(r.Context(), key, cacheEntry(entry, r, res, tags)); err != nil {
+					log.WithError(l, err).Warn("failed to set cache entry", log.FValue(key))
+				}
+			}
+
+			writeConditionalResponse(w, r, bw.statusCode, bw.Body())
+		})
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// cacheTTL returns the freshness lifetime of the response and whether it may be stored
+func cacheTTL(r *http.Request, statusCode int, header http.Header, defaultTTL time.Duration) (time.Duration, bool) {
+	switch statusCode {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent, http.StatusMovedPermanently,
+		http.StatusNotFound, http.StatusGone:
+	default:
+		return 0, false
+	}
+
+	if header.Get(keelhttp.HeaderSetCookie) != "" || header.Get(keelhttp.HeaderVary) == "*" {
+		return 0, false
+	}
+
+	directives := cache.ParseCacheControl(header.Values(keelhttp.HeaderCacheControl)...)
+	if directives.Has("no-store") || directives.Has("no-cache") || directives.Has("private") {
+		return 0, false
+	}
+
+	// shared caches must not store authorized responses unless explicitly allowed
+	if r.Header.Get(keelhttp.HeaderAuthorization) != "" && !directives.Has("public") && !directives.Has("s-maxage") {
+		return 0, false
+	}
+
+	if v, ok := cache.ParseCacheControl(header.Values(keelhttp.HeaderSurrogateControl)...).Seconds("max-age"); ok {
+		return v, v > 0
+	}
+
+	if v, ok := directives.Seconds("s-maxage"); ok {
+		return v, v > 0
+	}
+
+	if v, ok := directives.Seconds("max-age"); ok {
+		return v, v > 0
+	}
+
+	if v := header.Get(keelhttp.HeaderExpires); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0, false
+		}
+
+		ttl := time.Until(expires)
+
+		return ttl, ttl > 0
+	}
+
+	return defaultTTL, defaultTTL > 0
+}
+
+// cacheEntry returns a new entry with the response added to the variants of
+// the existing entry as long as they vary by the same headers
+func cacheEntry(existing *cache.Entry, r *http.Request, res *cache.Response, tags []string) *cache.Entry {
+	var vary []string
+
+	for _, value := range res.Header.Values(keelhttp.HeaderVary) {
+		for name := range strings.SplitSeq(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				vary = append(vary, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	ret := &cache.Entry{
+		Vary:     vary,
+		Variants: map[string]*cache.Response{},
+	}
+
+	if existing != nil && slices.Equal(existing.Vary, vary) {
+		maps.Copy(ret.Variants, existing.Variants)
+		ret.Tags = slices.Clone(existing.Tags)
+	}
+
+	ret.Variants[ret.Variant(r)] = res
+
+	for _, tag := range tags {
+		if !slices.Contains(ret.Tags, tag) {
+			ret.Tags = append(ret.Tags, tag)
+		}
+	}
+
+	return ret
+}
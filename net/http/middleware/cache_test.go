@@ -0,0 +1,99 @@
+package middleware_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/cache"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+
+	store := cache.NewMemoryStore()
+
+	handler := keelhttp.Compose(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set(keelhttp.HeaderCacheControl, "private, max-age=60")
+		case "/vary":
+			w.Header().Set(keelhttp.HeaderCacheControl, "max-age=60")
+			w.Header().Set(keelhttp.HeaderVary, "Accept-Language")
+		default:
+			w.Header().Set(keelhttp.HeaderCacheControl, "public, max-age=60")
+			w.Header().Set(keelhttp.HeaderSurrogateKey, "products product-1")
+		}
+
+		_, _ = fmt.Fprintf(w, "%s %d", r.Header.Get("Accept-Language"), n)
+	}), middleware.ETag(), middleware.Cache(middleware.CacheWithStore(store)))
+
+	do := func(method, path string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := do(http.MethodGet, "/products/1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, " 1", w.Body.String())
+	assert.Equal(t, "MISS", w.Header().Get(keelhttp.HeaderXCache))
+	assert.Empty(t, w.Header().Get(keelhttp.HeaderSurrogateKey))
+
+	etag := w.Header().Get(keelhttp.HeaderETag)
+	require.NotEmpty(t, etag)
+
+	w = do(http.MethodGet, "/products/1", nil)
+	assert.Equal(t, " 1", w.Body.String())
+	assert.Equal(t, "HIT", w.Header().Get(keelhttp.HeaderXCache))
+	assert.Equal(t, "0", w.Header().Get(keelhttp.HeaderAge))
+
+	// conditional requests are answered from the cache
+	w = do(http.MethodGet, "/products/1", http.Header{keelhttp.HeaderIfNoneMatch: {etag}})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, "HIT", w.Header().Get(keelhttp.HeaderXCache))
+
+	// the request can bypass the cache
+	assert.Equal(t, " 2", do(http.MethodGet, "/products/1", http.Header{keelhttp.HeaderCacheControl: {"no-cache"}}).Body.String())
+	assert.Equal(t, " 2", do(http.MethodGet, "/products/1", nil).Body.String())
+
+	// private responses are not stored
+	assert.Equal(t, " 3", do(http.MethodGet, "/private", nil).Body.String())
+	assert.Equal(t, " 4", do(http.MethodGet, "/private", nil).Body.String())
+
+	// variants by the Vary header
+	assert.Equal(t, "de 5", do(http.MethodGet, "/vary", http.Header{"Accept-Language": {"de"}}).Body.String())
+	assert.Equal(t, "en 6", do(http.MethodGet, "/vary", http.Header{"Accept-Language": {"en"}}).Body.String())
+	assert.Equal(t, "de 5", do(http.MethodGet, "/vary", http.Header{"Accept-Language": {"de"}}).Body.String())
+
+	// purge by surrogate key
+	n, err := store.PurgeTags(t.Context(), "product-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, " 7", do(http.MethodGet, "/products/1", nil).Body.String())
+
+	// unsafe requests purge their key
+	do(http.MethodPut, "/products/1", nil)
+	assert.Equal(t, " 9", do(http.MethodGet, "/products/1", nil).Body.String())
+
+	// hosts do not share entries
+	assert.Equal(t, " 10", do(http.MethodGet, "http://other.example.com/products/1", nil).Body.String())
+	assert.Equal(t, " 9", do(http.MethodGet, "/products/1", nil).Body.String())
+}
@@ -0,0 +1,375 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	stdhttp "github.com/foomo/gostandards/http"
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/compress"
+	httputils "github.com/foomo/keel/utils/net/http"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/foomo/keel/log"
+)
+
+type (
+	CompressOptions struct {
+		// Encodings in order of preference
+		Encodings []stdhttp.Encoding
+		// Levels by encoding, falling back to the compress.DefaultLevels
+		Levels map[stdhttp.Encoding]int
+		// MinSize of response bodies to compress
+		MinSize int
+		// ExcludedContentTypes by media type prefix that are already compressed
+		ExcludedContentTypes []string
+		// DecompressRequests decodes request bodies of the supported encodings
+		DecompressRequests bool
+		// DecompressMaxBytes limits the decoded request bodies, 0 disables the limit
+		DecompressMaxBytes int64
+		// Skippers exclude requests from compression
+		Skippers []Skipper
+	}
+	CompressOption func(*CompressOptions)
+)
+
+// DefaultCompressExcludedContentTypes are already compressed
+var DefaultCompressExcludedContentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"image/avif",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/x-brotli",
+	"application/pdf",
+	"text/event-stream",
+}
+
+// GetDefaultCompressOptions returns the default options
+func GetDefaultCompressOptions() CompressOptions {
+	return CompressOptions{
+		Encodings:            compress.DefaultEncodings,
+		MinSize:              1024,
+		ExcludedContentTypes: DefaultCompressExcludedContentTypes,
+		DecompressMaxBytes:   10 << 20,
+	}
+}
+
+// CompressWithEncodings middleware option
+func CompressWithEncodings(v ...stdhttp.Encoding) CompressOption {
+	return func(o *CompressOptions) {
+		o.Encodings = v
+	}
+}
+
+// CompressWithLevel middleware option sets the level of the underlying library for the encoding
+func CompressWithLevel(encoding stdhttp.Encoding, v int) CompressOption {
+	return func(o *CompressOptions) {
+		if o.Levels == nil {
+			o.Levels = map[stdhttp.Encoding]int{}
+		}
+
+		o.Levels[encoding] = v
+	}
+}
+
+// CompressWithMinSize middleware option
+func CompressWithMinSize(v int) CompressOption {
+	return func(o *CompressOptions) {
+		o.MinSize = v
+	}
+}
+
+// CompressWithExcludedContentTypes middleware option
+func CompressWithExcludedContentTypes(v ...string) CompressOption {
+	return func(o *CompressOptions) {
+		o.ExcludedContentTypes = append(slices.Clone(o.ExcludedContentTypes), v...)
+	}
+}
+
+// CompressWithDecompressRequests middleware option
+func CompressWithDecompressRequests(v bool) CompressOption {
+	return func(o *CompressOptions) {
+		o.DecompressRequests = v
+	}
+}
+
+// CompressWithDecompressMaxBytes middleware option
+func CompressWithDecompressMaxBytes(v int64) CompressOption {
+	return func(o *CompressOptions) {
+		o.DecompressMaxBytes = v
+	}
+}
+
+// CompressWithSkippers middleware option
+func CompressWithSkippers(v ...Skipper) CompressOption {
+	return func(o *CompressOptions) {
+		o.Skippers = append(o.Skippers, v...)
+	}
+}
+
+// CompressWithGZipOptions middleware option applies the options of the GZip middleware
+func CompressWithGZipOptions(v GZipOptions) CompressOption {
+	return func(o *CompressOptions) {
+		CompressWithLevel(stdhttp.EncodingGzip, v.CompressionLevel)(o)
+		o.MinSize = v.MinSize
+	}
+}
+
+// Compress middleware
+func Compress(opts ...CompressOption) keelhttp.Middleware {
+	options := GetDefaultCompressOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return CompressWithOptions(options)
+}
+
+// CompressWithOptions middleware compresses responses with the encoding
+// negotiated through the Accept-Encoding header. Responses that are small,
+// already encoded or of an excluded content type are sent as they are.
+func CompressWithOptions(opts CompressOptions) keelhttp.Middleware {
+	encoders := compress.NewEncoders(opts.Levels)
+
+	return func(l *zap.Logger, name string, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if span.IsRecording() {
+				span.AddEvent("Compress")
+			}
+
+			for _, skipper := range opts.Skippers {
+				if skipper(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			if encoding := stdhttp.Encoding(strings.ToLower(r.Header.Get(keelhttp.HeaderContentEncoding))); opts.DecompressRequests && r.Body != nil && slices.Contains(opts.Encodings, encoding) {
+				body, err := compress.NewReader(encoding, r.Body)
+				if err != nil {
+					httputils.BadRequestServerError(l, w, r, err)
+					return
+				}
+				defer body.Close()
+
+				if opts.DecompressMaxBytes > 0 {
+					body = http.MaxBytesReader(w, body, opts.DecompressMaxBytes)
+				}
+
+				r.Header.Del(keelhttp.HeaderContentEncoding)
+				r.Header.Del(keelhttp.HeaderContentLength)
+				r.ContentLength = -1
+				r.Body = body
+			}
+
+			w.Header().Add(keelhttp.HeaderVary, keelhttp.HeaderAcceptEncoding)
+
+			encoding := compress.Negotiate(r.Header.Get(keelhttp.HeaderAcceptEncoding), opts.Encodings)
+			if encoding == stdhttp.EncodingIdentity {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressResponseWriter{
+				ResponseWriter: w,
+				encoders:       encoders,
+				encoding:       encoding,
+				opts:           &opts,
+				statusCode:     http.StatusOK,
+			}
+
+			next.ServeHTTP(cw, r)
+
+			if err := cw.Close(); err != nil {
+				log.WithError(l, err).Debug("failed to close compress writer")
+			}
+		})
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// compressResponseWriter buffers the body up to the minimum size before it
+// decides whether to compress the response
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoders    *compress.Encoders
+	encoding    stdhttp.Encoding
+	opts        *CompressOptions
+	statusCode  int
+	wroteHeader bool
+	decided     bool
+	writer      compress.Writer
+	buf         []byte
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (w *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+
+	// pass through informational responses
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	w.wroteHeader = true
+	w.statusCode = statusCode
+
+	if !w.compressible() {
+		w.decide(false)
+	}
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) >= w.opts.MinSize {
+			w.decide(true)
+		}
+
+		return len(b), nil
+	}
+
+	if w.writer != nil {
+		return w.writer.Write(b)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush compresses the response regardless of its size
+func (w *compressResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.decided {
+		w.decide(true)
+	}
+
+	if w.writer != nil {
+		_ = w.writer.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close sends small responses uncompressed and finishes the encoding
+func (w *compressResponseWriter) Close() error {
+	if !w.wroteHeader {
+		return nil
+	}
+
+	if !w.decided {
+		w.decide(false)
+	}
+
+	if w.writer == nil {
+		return nil
+	}
+
+	err := w.writer.Close()
+	w.encoders.Put(w.encoding, w.writer)
+	w.writer = nil
+
+	return err
+}
+
+// compressible returns false if the headers rule out compression
+func (w *compressResponseWriter) compressible() bool {
+	switch {
+	case w.statusCode < http.StatusOK, w.statusCode == http.StatusNoContent, w.statusCode == http.StatusNotModified,
+		w.statusCode == http.StatusPartialContent:
+		return false
+	}
+
+	h := w.Header()
+	if h.Get(keelhttp.HeaderContentEncoding) != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+
+	if v, err := strconv.Atoi(h.Get(keelhttp.HeaderContentLength)); err == nil && v < w.opts.MinSize {
+		return false
+	}
+
+	if contentType := h.Get(keelhttp.HeaderContentType); contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		for _, excluded := range w.opts.ExcludedContentTypes {
+			if strings.HasPrefix(mediaType, excluded) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// decide writes the header and buffered body either compressed or as they are
+func (w *compressResponseWriter) decide(compressed bool) {
+	w.decided = true
+	h := w.Header()
+
+	if compressed && h.Get(keelhttp.HeaderContentType) == "" && len(w.buf) > 0 {
+		h.Set(keelhttp.HeaderContentType, http.DetectContentType(w.buf))
+	}
+
+	if compressed && w.compressible() {
+		if writer, err := w.encoders.Get(w.encoding, w.ResponseWriter); err == nil {
+			w.writer = writer
+
+			h.Set(keelhttp.HeaderContentEncoding, w.encoding.String())
+			h.Del(keelhttp.HeaderContentLength)
+			h.Del("Accept-Ranges")
+
+			// the encoded representation is not byte equal
+			if etag := h.Get(keelhttp.HeaderETag); etag != "" && !strings.HasPrefix(etag, "W/") {
+				h.Set(keelhttp.HeaderETag, "W/"+etag)
+			}
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.statusCode)
+
+	if len(w.buf) == 0 {
+		return
+	}
+
+	if w.writer != nil {
+		_, _ = w.writer.Write(w.buf)
+	} else {
+		_, _ = w.ResponseWriter.Write(w.buf)
+	}
+
+	w.buf = nil
+}
@@ -0,0 +1,143 @@
+package middleware_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	stdhttp "github.com/foomo/gostandards/http"
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/compress"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+
+	payload := strings.Repeat("keel ", 1000)
+
+	handler := middleware.Compress(
+		middleware.CompressWithDecompressRequests(true),
+	)(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		switch r.URL.Path {
+		case "/small":
+			body = []byte("small")
+		case "/image":
+			w.Header().Set(keelhttp.HeaderContentType, "image/png")
+		case "/encoded":
+			w.Header().Set(keelhttp.HeaderContentEncoding, "br")
+		}
+
+		w.Header().Set(keelhttp.HeaderETag, `"v1"`)
+		_, _ = w.Write(body)
+	}))
+
+	do := func(path, acceptEncoding string, body io.Reader) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, body)
+		r.Header.Set(keelhttp.HeaderAcceptEncoding, acceptEncoding)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	for _, encoding := range compress.DefaultEncodings {
+		w := do("/", encoding.String()+", identity;q=0.5", strings.NewReader(payload))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, encoding.String(), w.Header().Get(keelhttp.HeaderContentEncoding))
+		assert.Equal(t, keelhttp.HeaderAcceptEncoding, w.Header().Get(keelhttp.HeaderVary))
+		assert.Equal(t, `W/"v1"`, w.Header().Get(keelhttp.HeaderETag))
+		assert.Less(t, w.Body.Len(), len(payload))
+
+		r, err := compress.NewReader(encoding, w.Body)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(b))
+	}
+
+	// small, excluded and encoded responses are sent as they are
+	for _, path := range []string{"/small", "/image", "/encoded"} {
+		w := do(path, "gzip, br, zstd", strings.NewReader(payload))
+		assert.NotEqual(t, "gzip", w.Header().Get(keelhttp.HeaderContentEncoding), path)
+		assert.Equal(t, `"v1"`, w.Header().Get(keelhttp.HeaderETag), path)
+	}
+
+	// not acceptable
+	w := do("/", "deflate", strings.NewReader(payload))
+	assert.Empty(t, w.Header().Get(keelhttp.HeaderContentEncoding))
+	assert.Equal(t, payload, w.Body.String())
+
+	// compressed request bodies are decoded
+	var buf bytes.Buffer
+
+	zw, err := compress.NewWriter(compress.EncodingZstd, &buf, 3)
+	require.NoError(t, err)
+	_, _ = zw.Write([]byte(payload))
+	require.NoError(t, zw.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/", &buf)
+	r.Header.Set(keelhttp.HeaderContentEncoding, compress.EncodingZstd.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, payload, w.Body.String())
+}
+
+func TestCompress_decompressMaxBytes(t *testing.T) {
+	t.Parallel()
+
+	handler := middleware.Compress(
+		middleware.CompressWithDecompressRequests(true),
+		middleware.CompressWithDecompressMaxBytes(4096),
+	)(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	var buf bytes.Buffer
+
+	zw, err := compress.NewWriter(stdhttp.EncodingGzip, &buf, 9)
+	require.NoError(t, err)
+	_, _ = zw.Write(bytes.Repeat([]byte{0}, 1<<20))
+	require.NoError(t, zw.Close())
+	assert.Less(t, buf.Len(), 4096)
+
+	r := httptest.NewRequest(http.MethodPost, "/", &buf)
+	r.Header.Set(keelhttp.HeaderContentEncoding, stdhttp.EncodingGzip.String())
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestCompress_gzipOptions(t *testing.T) {
+	t.Parallel()
+
+	handler := middleware.Compress(
+		middleware.CompressWithGZipOptions(middleware.GZipOptions{CompressionLevel: 9, MinSize: 4}),
+	)(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("keel"))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(keelhttp.HeaderAcceptEncoding, stdhttp.EncodingGzip.String())
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, stdhttp.EncodingGzip.String(), w.Header().Get(keelhttp.HeaderContentEncoding))
+}
@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/concurrency"
+	"github.com/foomo/keel/telemetry"
+	httputils "github.com/foomo/keel/utils/net/http"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const concurrencyPriorityKey = attribute.Key("keel.concurrency.priority")
+
+var ErrConcurrencyLimited = errors.New("concurrency limit exceeded")
+
+type (
+	ConcurrencyLimitOptions struct {
+		// Limiter admitting the requests, defaults to an AIMD limiter
+		Limiter *concurrency.Limiter
+		// PriorityFunc returns the priority of requests that are not critical
+		PriorityFunc ConcurrencyPriorityFunc
+		// CriticalRoutes by route template of the keelhttp.Router or path are never shed
+		CriticalRoutes []string
+		// CriticalPathPrefixes are never shed e.g. health and internal endpoints
+		CriticalPathPrefixes []string
+		// RetryAfter is sent with shed requests
+		RetryAfter time.Duration
+		// Skippers exclude requests from limiting
+		Skippers []Skipper
+		// ShedHandler responds to shed requests
+		ShedHandler ConcurrencyShedHandler
+	}
+	ConcurrencyLimitOption  func(*ConcurrencyLimitOptions)
+	ConcurrencyPriorityFunc func(r *http.Request) concurrency.Priority
+	ConcurrencyShedHandler  func(l *zap.Logger, w http.ResponseWriter, r *http.Request)
+)
+
+// DefaultConcurrencyShedHandler function
+func DefaultConcurrencyShedHandler(l *zap.Logger, w http.ResponseWriter, r *http.Request) {
+	httputils.InternalServiceUnavailable(l, w, r, ErrConcurrencyLimited)
+}
+
+// GetDefaultConcurrencyLimitOptions returns the default options
+func GetDefaultConcurrencyLimitOptions() ConcurrencyLimitOptions {
+	return ConcurrencyLimitOptions{
+		CriticalPathPrefixes: []string{"/healthz", "/internal/"},
+		RetryAfter:           time.Second,
+		ShedHandler:          DefaultConcurrencyShedHandler,
+	}
+}
+
+// ConcurrencyLimitWithLimiter middleware option
+func ConcurrencyLimitWithLimiter(v *concurrency.Limiter) ConcurrencyLimitOption {
+	return func(o *ConcurrencyLimitOptions) {
+		o.Limiter = v
+	}
+}
+
+// ConcurrencyLimitWithPriorityFunc middleware option
+func ConcurrencyLimitWithPriorityFunc(v ConcurrencyPriorityFunc) ConcurrencyLimitOption {
+	return func(o *ConcurrencyLimitOptions) {
+		o.PriorityFunc = v
+	}
+}
+
+// ConcurrencyLimitWithCriticalRoutes middleware option
+func ConcurrencyLimitWithCriticalRoutes(v ...string) ConcurrencyLimitOption {
+	return func(o *ConcurrencyLimitOptions) {
+		o.CriticalRoutes = append(o.CriticalRoutes, v...)
+	}
+}
+
+// ConcurrencyLimitWithCriticalPathPrefixes middleware option
+func ConcurrencyLimitWithCriticalPathPrefixes(v ...string) ConcurrencyLimitOption {
+	return func(o *ConcurrencyLimitOptions) {
+		o.CriticalPathPrefixes = v
+	}
+}
+
+// ConcurrencyLimitWithRetryAfter middleware option
+func ConcurrencyLimitWithRetryAfter(v time.Duration) ConcurrencyLimitOption {
+	return func(o *ConcurrencyLimitOptions) {
+		o.RetryAfter = v
+	}
+}
+
+// ConcurrencyLimitWithSkippers middleware option
+func ConcurrencyLimitWithSkippers(v ...Skipper) ConcurrencyLimitOption {
+	return func(o *ConcurrencyLimitOptions) {
+		o.Skippers = append(o.Skippers, v...)
+	}
+}
+
+// ConcurrencyLimitWithShedHandler middleware option
+func ConcurrencyLimitWithShedHandler(v ConcurrencyShedHandler) ConcurrencyLimitOption {
+	return func(o *ConcurrencyLimitOptions) {
+		o.ShedHandler = v
+	}
+}
+
+// ConcurrencyLimit middleware
+func ConcurrencyLimit(opts ...ConcurrencyLimitOption) keelhttp.Middleware {
+	options := GetDefaultConcurrencyLimitOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return ConcurrencyLimitWithOptions(options)
+}
+
+// ConcurrencyLimitWithOptions middleware
+func ConcurrencyLimitWithOptions(opts ConcurrencyLimitOptions) keelhttp.Middleware {
+	if opts.Limiter == nil {
+		opts.Limiter = concurrency.NewLimiter(concurrency.NewAIMD())
+	}
+
+	limitGauge := telemetry.NewIntGauge("keel.http.server.concurrency.limit",
+		metric.WithDescription("Current adaptive concurrency limit"),
+	)
+	inflightCounter := telemetry.NewIntUpDownCounter("keel.http.server.concurrency.inflight",
+		metric.WithDescription("Number of requests admitted by the concurrency limit"),
+	)
+	shedCounter := telemetry.NewIntCounter("keel.http.server.concurrency.shed",
+		metric.WithDescription("Number of requests shed by the concurrency limit"),
+	)
+
+	return func(l *zap.Logger, name string, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if span.IsRecording() {
+				span.AddEvent("ConcurrencyLimit")
+			}
+
+			for _, skipper := range opts.Skippers {
+				if skipper(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			priority := concurrencyPriority(r, opts)
+			attrs := metric.WithAttributes(
+				attribute.String("http.server_name", name),
+				concurrencyPriorityKey.String(concurrencyPriorityName(priority)),
+			)
+
+			token, ok := opts.Limiter.Acquire(priority)
+			if !ok {
+				shedCounter.Add(r.Context(), 1, attrs)
+
+				if span.IsRecording() {
+					span.AddEvent("ConcurrencyLimited")
+				}
+
+				w.Header().Set(keelhttp.HeaderRetryAfter, strconv.Itoa(max(1, ceilSeconds(opts.RetryAfter))))
+				opts.ShedHandler(l, w, r)
+
+				return
+			}
+
+			inflightCounter.Add(r.Context(), 1, attrs)
+
+			wr := WrapResponseWriter(w)
+
+			defer func() {
+				// treat server side failures and cancellations as drops
+				dropped := wr.StatusCode() >= http.StatusInternalServerError || r.Context().Err() != nil
+				token.Release(dropped)
+
+				inflightCounter.Add(r.Context(), -1, attrs)
+				limitGauge.Record(r.Context(), int64(opts.Limiter.Limit()), metric.WithAttributes(attribute.String("http.server_name", name)))
+			}()
+
+			next.ServeHTTP(wr, r)
+		})
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// concurrencyPriority returns the priority of the request
+func concurrencyPriority(r *http.Request, opts ConcurrencyLimitOptions) concurrency.Priority {
+	for _, prefix := range opts.CriticalPathPrefixes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return concurrency.PriorityCritical
+		}
+	}
+
+	route, ok := keelhttp.RouteFromRequest(r)
+	for _, critical := range opts.CriticalRoutes {
+		if critical == r.URL.Path || (ok && route.Template != "" && critical == route.Template) {
+			return concurrency.PriorityCritical
+		}
+	}
+
+	if opts.PriorityFunc != nil {
+		return opts.PriorityFunc(r)
+	}
+
+	return concurrency.PriorityNormal
+}
+
+func concurrencyPriorityName(v concurrency.Priority) string {
+	switch {
+	case v >= concurrency.PriorityCritical:
+		return "critical"
+	case v <= concurrency.PriorityLow:
+		return "low"
+	default:
+		return "normal"
+	}
+}
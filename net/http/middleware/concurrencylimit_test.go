@@ -0,0 +1,66 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/concurrency"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+
+	limiter := concurrency.NewLimiter(&concurrency.AIMD{MinLimit: 1, MaxLimit: 1, InitialLimit: 1, BackoffRatio: 0.5})
+
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+
+	router := keelhttp.NewRouter(zap.NewNop(), "test")
+	router.Use(middleware.ConcurrencyLimit(
+		middleware.ConcurrencyLimitWithLimiter(limiter),
+		middleware.ConcurrencyLimitWithCriticalRoutes("/orders/{id}"),
+	))
+	router.HandleFunc("slow", "/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(blocked)
+		<-release
+	})
+	router.HandleFunc("orders", "/orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	router.HandleFunc("root", "/", func(w http.ResponseWriter, r *http.Request) {})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return w
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		get("/slow")
+	}()
+
+	<-blocked
+
+	w := get("/")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get(keelhttp.HeaderRetryAfter))
+
+	// critical requests are never shed
+	assert.Equal(t, http.StatusOK, get("/orders/1").Code)
+	assert.Equal(t, http.StatusOK, get("/healthz/readiness").Code)
+
+	close(release)
+	<-done
+
+	require.Equal(t, 0, limiter.Inflight())
+	assert.Equal(t, http.StatusOK, get("/").Code)
+}
@@ -0,0 +1,410 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	keelhttpcontext "github.com/foomo/keel/net/http/context"
+	"github.com/foomo/keel/net/http/cookie"
+	"github.com/foomo/keel/telemetry"
+	httputils "github.com/foomo/keel/utils/net/http"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// CSRFStrategy of the token validation
+type CSRFStrategy string
+
+const (
+	// CSRFStrategyDoubleSubmit compares the submitted token with the token cookie
+	CSRFStrategyDoubleSubmit CSRFStrategy = "doubleSubmit"
+	// CSRFStrategySynchronizer verifies the submitted token against the session id
+	CSRFStrategySynchronizer CSRFStrategy = "synchronizer"
+)
+
+const (
+	DefaultCSRFCookieName = "keel-csrf"
+	DefaultCSRFFormField  = "csrf_token"
+)
+
+const csrfReasonKey = attribute.Key("keel.csrf.reason")
+
+var (
+	ErrCSRFTokenMissing   = errors.New("csrf token missing")
+	ErrCSRFTokenInvalid   = errors.New("csrf token invalid")
+	ErrCSRFOriginInvalid  = errors.New("csrf origin invalid")
+	ErrCSRFSessionMissing = errors.New("csrf session missing")
+)
+
+type (
+	CSRFOptions struct {
+		// Strategy of the token validation
+		Strategy CSRFStrategy
+		// Secret (required for the synchronizer strategy) signing the tokens, shared by all instances.
+		// With the double submit strategy the cookie tokens are signed for the session id if set.
+		Secret []byte
+		// Cookie holding the double submit token, readable by scripts
+		Cookie cookie.Cookie
+		// Header to look up the submitted token
+		Header string
+		// FormField to look up the submitted token of form posts
+		FormField string
+		// CheckOrigin rejects unsafe requests whose Origin or Referer is neither the request host nor trusted
+		CheckOrigin bool
+		// TrustedOrigins e.g. https://www.example.com that may send unsafe requests
+		TrustedOrigins []string
+		// SafeMethods are exempted from validation
+		SafeMethods []string
+		// Skippers exclude requests from validation
+		Skippers []Skipper
+		// ErrorHandler responds to rejected requests
+		ErrorHandler CSRFErrorHandler
+	}
+	CSRFOption       func(*CSRFOptions)
+	CSRFErrorHandler func(l *zap.Logger, w http.ResponseWriter, r *http.Request, err error)
+)
+
+// DefaultCSRFErrorHandler function
+func DefaultCSRFErrorHandler(l *zap.Logger, w http.ResponseWriter, r *http.Request, err error) {
+	httputils.ForbiddenServerError(l, w, r, err)
+}
+
+// GetDefaultCSRFOptions returns the default options
+func GetDefaultCSRFOptions() CSRFOptions {
+	return CSRFOptions{
+		Strategy:     CSRFStrategyDoubleSubmit,
+		Cookie:       cookie.New(DefaultCSRFCookieName, cookie.WithHTTPOnly(false), cookie.WithSameSite(http.SameSiteLaxMode)),
+		Header:       keelhttp.HeaderXCSRFToken,
+		FormField:    DefaultCSRFFormField,
+		CheckOrigin:  true,
+		SafeMethods:  []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace},
+		ErrorHandler: DefaultCSRFErrorHandler,
+	}
+}
+
+// CSRFWithStrategy middleware option
+func CSRFWithStrategy(v CSRFStrategy) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.Strategy = v
+	}
+}
+
+// CSRFWithSecret middleware option
+func CSRFWithSecret(v []byte) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.Secret = v
+	}
+}
+
+// CSRFWithCookie middleware option
+func CSRFWithCookie(v cookie.Cookie) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.Cookie = v
+	}
+}
+
+// CSRFWithHeader middleware option
+func CSRFWithHeader(v string) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.Header = v
+	}
+}
+
+// CSRFWithFormField middleware option
+func CSRFWithFormField(v string) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.FormField = v
+	}
+}
+
+// CSRFWithCheckOrigin middleware option
+func CSRFWithCheckOrigin(v bool) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.CheckOrigin = v
+	}
+}
+
+// CSRFWithTrustedOrigins middleware option
+func CSRFWithTrustedOrigins(v ...string) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.TrustedOrigins = append(o.TrustedOrigins, v...)
+	}
+}
+
+// CSRFWithSafeMethods middleware option
+func CSRFWithSafeMethods(v ...string) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.SafeMethods = v
+	}
+}
+
+// CSRFWithSkippers middleware option
+func CSRFWithSkippers(v ...Skipper) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.Skippers = append(o.Skippers, v...)
+	}
+}
+
+// CSRFWithErrorHandler middleware option
+func CSRFWithErrorHandler(v CSRFErrorHandler) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.ErrorHandler = v
+	}
+}
+
+// CSRF middleware
+func CSRF(opts ...CSRFOption) keelhttp.Middleware {
+	options := GetDefaultCSRFOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return CSRFWithOptions(options)
+}
+
+// CSRFWithOptions middleware protects cookie authenticated endpoints against
+// cross-site request forgery. Unsafe requests must submit the token through
+// the header or form field and, if enabled, originate from the request host
+// or a trusted origin.
+//
+// With the double submit strategy the token is kept in a cookie which is set
+// on the first request. Given a secret, the cookie token is signed for the
+// session id so cookies tossed from a subdomain are rejected. With the
+// synchronizer strategy the token is signed for the session id, so the
+// SessionID middleware has to run before.
+//
+// The current token is stored in the context for templates and clients.
+func CSRFWithOptions(opts CSRFOptions) keelhttp.Middleware {
+	// a random secret would not be shared across instances and restarts
+	if opts.Strategy == CSRFStrategySynchronizer && len(opts.Secret) == 0 {
+		panic("missing csrf secret for the synchronizer strategy")
+	}
+
+	trustedOrigins := make([]string, len(opts.TrustedOrigins))
+	for i, origin := range opts.TrustedOrigins {
+		trustedOrigins[i] = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	}
+
+	failures := telemetry.NewIntCounter("keel.http.server.csrf.failures",
+		metric.WithDescription("Number of requests rejected by the csrf protection"),
+	)
+
+	return func(l *zap.Logger, name string, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if span.IsRecording() {
+				span.AddEvent("CSRF")
+			}
+
+			for _, skipper := range opts.Skippers {
+				if skipper(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			fail := func(err error) {
+				failures.Add(r.Context(), 1, metric.WithAttributes(
+					attribute.String("http.server_name", name),
+					csrfReasonKey.String(err.Error()),
+				))
+
+				if span.IsRecording() {
+					span.AddEvent("CSRFRejected", trace.WithAttributes(csrfReasonKey.String(err.Error())))
+				}
+
+				opts.ErrorHandler(l, w, r, err)
+			}
+
+			var token string
+
+			switch opts.Strategy {
+			case CSRFStrategySynchronizer:
+				if sessionID := SessionIDFromContext(r.Context()); sessionID != "" {
+					token = csrfSignToken(opts.Secret, sessionID)
+				}
+			default:
+				sessionID := SessionIDFromContext(r.Context())
+				if c, err := opts.Cookie.Get(r); err == nil && csrfValidDoubleSubmitToken(opts.Secret, sessionID, c.Value) {
+					token = c.Value
+				} else {
+					token = csrfNewDoubleSubmitToken(opts.Secret, sessionID)
+					if _, err := opts.Cookie.Set(w, r, token); err != nil {
+						httputils.InternalServerError(l, w, r, errors.Wrap(err, "failed to set csrf cookie"))
+						return
+					}
+				}
+			}
+
+			if token != "" {
+				r = r.WithContext(keelhttpcontext.SetCSRFToken(r.Context(), token))
+			}
+
+			if slices.Contains(opts.SafeMethods, r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if opts.CheckOrigin && !csrfValidOrigin(r, trustedOrigins) {
+				fail(ErrCSRFOriginInvalid)
+				return
+			}
+
+			submitted := csrfSubmittedToken(r, opts)
+			if submitted == "" {
+				fail(ErrCSRFTokenMissing)
+				return
+			}
+
+			switch opts.Strategy {
+			case CSRFStrategySynchronizer:
+				sessionID := SessionIDFromContext(r.Context())
+				if sessionID == "" {
+					fail(ErrCSRFSessionMissing)
+					return
+				}
+
+				if !csrfVerifyToken(opts.Secret, sessionID, submitted) {
+					fail(ErrCSRFTokenInvalid)
+					return
+				}
+			default:
+				// a freshly issued cookie can not have been submitted
+				if c, err := opts.Cookie.Get(r); err != nil || c.Value != token ||
+					subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+					fail(ErrCSRFTokenInvalid)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CSRFTokenFromContext helper
+func CSRFTokenFromContext(ctx context.Context) string {
+	if value, ok := keelhttpcontext.GetCSRFToken(ctx); ok {
+		return value
+	}
+
+	return ""
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func csrfNewToken() string {
+	b := make([]byte, 32)
+	// never returns an error
+	_, _ = rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func csrfValidToken(v string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	return err == nil && len(b) == 32
+}
+
+// csrfNewDoubleSubmitToken returns a random token, signed for the session id if a secret is set
+func csrfNewDoubleSubmitToken(secret []byte, sessionID string) string {
+	if len(secret) == 0 {
+		return csrfNewToken()
+	}
+
+	return csrfSignToken(secret, sessionID)
+}
+
+func csrfValidDoubleSubmitToken(secret []byte, sessionID, v string) bool {
+	if len(secret) == 0 {
+		return csrfValidToken(v)
+	}
+
+	return csrfVerifyToken(secret, sessionID, v)
+}
+
+// csrfSignToken returns a random nonce with its signature for the session id
+// so every rendered token differs while staying bound to the session
+func csrfSignToken(secret []byte, sessionID string) string {
+	nonce := csrfNewToken()
+	return nonce + "." + csrfSignature(secret, sessionID, nonce)
+}
+
+func csrfVerifyToken(secret []byte, sessionID, token string) bool {
+	nonce, signature, ok := strings.Cut(token, ".")
+	if !ok || !csrfValidToken(nonce) {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(csrfSignature(secret, sessionID, nonce)))
+}
+
+func csrfSignature(secret []byte, sessionID, nonce string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(sessionID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(nonce))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// csrfSubmittedToken returns the token of the header or the form field
+func csrfSubmittedToken(r *http.Request, opts CSRFOptions) string {
+	if value := r.Header.Get(opts.Header); value != "" {
+		return value
+	}
+
+	if opts.FormField == "" {
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get(keelhttp.HeaderContentType))
+	if mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data" {
+		return r.PostFormValue(opts.FormField)
+	}
+
+	return ""
+}
+
+// csrfValidOrigin checks the Origin or else the Referer header. Requests
+// without either are not sent by browsers and therefore allowed.
+func csrfValidOrigin(r *http.Request, trustedOrigins []string) bool {
+	origin := r.Header.Get(keelhttp.HeaderOrigin)
+	if origin == "" {
+		referer := r.Referer()
+		if referer == "" {
+			return true
+		}
+
+		origin = referer
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, httputils.GetRequestHost(r)) {
+		return true
+	}
+
+	return slices.Contains(trustedOrigins, strings.ToLower(u.Scheme+"://"+u.Host))
+}
@@ -0,0 +1,168 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCSRF(t *testing.T) {
+	t.Parallel()
+
+	handler := keelhttp.Compose(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(middleware.CSRFTokenFromContext(r.Context())))
+	}), middleware.CSRF(
+		middleware.CSRFWithTrustedOrigins("https://trusted.example.com"),
+	))
+
+	// safe requests receive the cookie and token
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	token := w.Body.String()
+	assert.Equal(t, cookies[0].Value, token)
+
+	post := func(header, form, origin string, withCookie bool) int {
+		var r *http.Request
+		if form != "" {
+			r = httptest.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader(url.Values{"csrf_token": {form}}.Encode()))
+			r.Header.Set(keelhttp.HeaderContentType, "application/x-www-form-urlencoded")
+		} else {
+			r = httptest.NewRequest(http.MethodPost, "http://example.com/", nil)
+		}
+
+		if header != "" {
+			r.Header.Set(keelhttp.HeaderXCSRFToken, header)
+		}
+
+		if origin != "" {
+			r.Header.Set(keelhttp.HeaderOrigin, origin)
+		}
+
+		if withCookie {
+			r.AddCookie(cookies[0])
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, post(token, "", "", true))
+	assert.Equal(t, http.StatusOK, post("", token, "http://example.com", true))
+	assert.Equal(t, http.StatusOK, post(token, "", "https://trusted.example.com", true))
+	assert.Equal(t, http.StatusForbidden, post(token, "", "https://evil.com", true))
+	assert.Equal(t, http.StatusForbidden, post("", "", "", true))
+	assert.Equal(t, http.StatusForbidden, post(token, "", "", false))
+	assert.Equal(t, http.StatusForbidden, post("invalid", "", "", true))
+}
+
+func TestCSRF_secret(t *testing.T) {
+	t.Parallel()
+
+	handler := keelhttp.Compose(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(middleware.CSRFTokenFromContext(r.Context())))
+	}),
+		middleware.CSRF(middleware.CSRFWithSecret([]byte("secret"))),
+		middleware.SessionID(),
+	)
+
+	request := func(method, sessionID string, c *http.Cookie, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/", nil)
+		r.Header.Set(keelhttp.HeaderXSessionID, sessionID)
+
+		if c != nil {
+			r.AddCookie(c)
+		}
+
+		if token != "" {
+			r.Header.Set(keelhttp.HeaderXCSRFToken, token)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := request(http.MethodGet, "session-a", nil, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	token := w.Body.String()
+	assert.Equal(t, cookies[0].Value, token)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "session-a", cookies[0], token).Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "session-b", cookies[0], token).Code)
+
+	// unsigned cookies tossed from a subdomain are rejected
+	tossed := strings.Repeat("A", 43)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "session-a", &http.Cookie{Name: middleware.DefaultCSRFCookieName, Value: tossed}, tossed).Code)
+}
+
+func TestCSRFSynchronizer(t *testing.T) {
+	t.Parallel()
+
+	var errs []error
+
+	handler := keelhttp.Compose(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(middleware.CSRFTokenFromContext(r.Context())))
+	}),
+		middleware.CSRF(
+			middleware.CSRFWithStrategy(middleware.CSRFStrategySynchronizer),
+			middleware.CSRFWithSecret([]byte("secret")),
+			middleware.CSRFWithErrorHandler(func(l *zap.Logger, w http.ResponseWriter, r *http.Request, err error) {
+				errs = append(errs, err)
+				w.WriteHeader(http.StatusForbidden)
+			}),
+		),
+		middleware.SessionID(),
+	)
+
+	request := func(method, sessionID, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/", nil)
+		if sessionID != "" {
+			r.Header.Set(keelhttp.HeaderXSessionID, sessionID)
+		}
+
+		if token != "" {
+			r.Header.Set(keelhttp.HeaderXCSRFToken, token)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := request(http.MethodGet, "session-a", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	token := w.Body.String()
+	require.NotEmpty(t, token)
+	assert.Empty(t, w.Result().Cookies())
+	assert.NotEqual(t, token, request(http.MethodGet, "session-a", "").Body.String())
+
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "session-a", token).Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "session-b", token).Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "", token).Code)
+	assert.Equal(t, []error{middleware.ErrCSRFTokenInvalid, middleware.ErrCSRFSessionMissing}, errs)
+}
+
+func TestCSRFSynchronizer_missingSecret(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() {
+		middleware.CSRF(middleware.CSRFWithStrategy(middleware.CSRFStrategySynchronizer))
+	})
+}
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/foomo/keel/config"
+	"github.com/foomo/keel/log"
+	keelhttp "github.com/foomo/keel/net/http"
+	httplog "github.com/foomo/keel/net/http/log"
+	httputils "github.com/foomo/keel/utils/net/http"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	deadlineTimeoutKey  = attribute.Key("keel.http.deadline.timeout")
+	deadlineExceededKey = attribute.Key("keel.http.deadline.exceeded")
+)
+
+var ErrDeadlineExceeded = errors.New("request deadline exceeded")
+
+type (
+	DeadlineOptions struct {
+		// Timeout applied to routes without a timeout of their own, 0 disables the deadline
+		Timeout time.Duration
+		// Routes timeouts by route template of the keelhttp.Router or path
+		Routes map[string]time.Duration
+		// StatusCode responded once the deadline is hit, either 503 or 504
+		StatusCode int
+		// Skippers exclude requests from the deadline
+		Skippers []Skipper
+	}
+	DeadlineOption func(*DeadlineOptions)
+	// DeadlineRouteConfig is the file-shape configuration of a route timeout
+	DeadlineRouteConfig struct {
+		Route   string        `json:"route" yaml:"route" mapstructure:"route"`
+		Timeout time.Duration `json:"timeout" yaml:"timeout" mapstructure:"timeout"`
+	}
+)
+
+// GetDefaultDeadlineOptions returns the default options
+func GetDefaultDeadlineOptions() DeadlineOptions {
+	return DeadlineOptions{
+		Timeout:    30 * time.Second,
+		StatusCode: http.StatusGatewayTimeout,
+	}
+}
+
+// DeadlineWithTimeout middleware option
+func DeadlineWithTimeout(v time.Duration) DeadlineOption {
+	return func(o *DeadlineOptions) {
+		o.Timeout = v
+	}
+}
+
+// DeadlineWithRoute middleware option sets the timeout of the route template or path
+func DeadlineWithRoute(route string, v time.Duration) DeadlineOption {
+	return func(o *DeadlineOptions) {
+		if o.Routes == nil {
+			o.Routes = map[string]time.Duration{}
+		}
+
+		o.Routes[route] = v
+	}
+}
+
+// DeadlineWithStatusCode middleware option
+func DeadlineWithStatusCode(v int) DeadlineOption {
+	return func(o *DeadlineOptions) {
+		o.StatusCode = v
+	}
+}
+
+// DeadlineWithSkippers middleware option
+func DeadlineWithSkippers(v ...Skipper) DeadlineOption {
+	return func(o *DeadlineOptions) {
+		o.Skippers = append(o.Skippers, v...)
+	}
+}
+
+// DeadlineWithConfig middleware option reads the timeouts from the config key
+//
+// Expected shape for key "http.deadline":
+//
+//	http:
+//	  deadline:
+//	    timeout: 10s
+//	    statusCode: 503
+//	    routes:
+//	      - route: /reports/{id}
+//	        timeout: 2m
+func DeadlineWithConfig(c *viper.Viper, key string) DeadlineOption {
+	if c == nil {
+		c = config.Config()
+	}
+
+	return func(o *DeadlineOptions) {
+		o.Timeout = config.GetDuration(c, key+".timeout", o.Timeout)()
+		o.StatusCode = config.GetInt(c, key+".statusCode", o.StatusCode)()
+
+		var routes []DeadlineRouteConfig
+		if err := c.UnmarshalKey(key+".routes", &routes); err != nil {
+			panic(fmt.Sprintf("invalid config key %s.routes: %s", key, err))
+		}
+
+		for _, route := range routes {
+			DeadlineWithRoute(route.Route, route.Timeout)(o)
+		}
+	}
+}
+
+// Deadline middleware
+func Deadline(opts ...DeadlineOption) keelhttp.Middleware {
+	options := GetDefaultDeadlineOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return DeadlineWithOptions(options)
+}
+
+// DeadlineWithOptions middleware sets a deadline on the request context which
+// propagates to all downstream calls using it. Handlers are expected to
+// return once the context is done; if they did not respond by then, the
+// configured status code is sent.
+func DeadlineWithOptions(opts DeadlineOptions) keelhttp.Middleware {
+	return func(l *zap.Logger, name string, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if span.IsRecording() {
+				span.AddEvent("Deadline")
+			}
+
+			for _, skipper := range opts.Skippers {
+				if skipper(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			route, timeout := routeValue(r, opts.Routes, opts.Timeout)
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			wr := WrapResponseWriter(w)
+			next.ServeHTTP(wr, r.WithContext(ctx))
+
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return
+			}
+
+			attrs := []attribute.KeyValue{
+				semconv.HTTPRoute(route),
+				deadlineTimeoutKey.String(timeout.String()),
+				deadlineExceededKey.Bool(true),
+			}
+
+			span.SetStatus(codes.Error, ErrDeadlineExceeded.Error())
+			span.SetAttributes(attrs...)
+
+			if labeler, ok := httplog.LabelerFromRequest(r); ok {
+				labeler.Add(log.Attributes(attrs...)...)
+			} else {
+				l = l.With(log.Attributes(attrs...)...)
+			}
+
+			if !wr.wroteHeader {
+				httputils.ServerError(l, wr, r, opts.StatusCode, ErrDeadlineExceeded)
+			} else if _, ok := httplog.LabelerFromRequest(r); !ok {
+				log.WithHTTPRequest(l, r).Warn(ErrDeadlineExceeded.Error())
+			}
+		})
+	}
+}
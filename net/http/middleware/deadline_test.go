@@ -0,0 +1,80 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestDeadline(t *testing.T) {
+	t.Parallel()
+
+	wait := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(100 * time.Millisecond):
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+
+	router := keelhttp.NewRouter(zap.NewNop(), "test")
+	router.Use(middleware.Deadline(
+		middleware.DeadlineWithTimeout(10*time.Millisecond),
+		middleware.DeadlineWithRoute("/reports/{id}", time.Second),
+	))
+	router.HandleFunc("reports", "/reports/{id}", wait)
+	router.HandleFunc("root", "/", wait)
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusGatewayTimeout, get("/"))
+	assert.Equal(t, http.StatusNoContent, get("/reports/1"))
+}
+
+func TestDeadlineWithConfig(t *testing.T) {
+	t.Parallel()
+
+	c := viper.New()
+	c.Set("deadline", map[string]any{
+		"timeout":    "1ms",
+		"statusCode": http.StatusServiceUnavailable,
+		"routes": []any{
+			map[string]any{"route": "/reports", "timeout": "2m"},
+		},
+	})
+
+	handler := middleware.Deadline(
+		middleware.DeadlineWithConfig(c, "deadline"),
+	)(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		assert.True(t, ok)
+
+		if time.Until(deadline) > time.Minute {
+			return
+		}
+
+		<-r.Context().Done()
+	}))
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, get("/"))
+	assert.Equal(t, http.StatusOK, get("/reports"))
+}
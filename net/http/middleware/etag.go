@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type (
+	ETagOptions struct {
+		// Weak generates weak instead of strong validators
+		Weak bool
+		// MaxBodySize of responses to generate validators for, larger ones are streamed
+		MaxBodySize int
+		// Skippers exclude requests from conditional handling
+		Skippers []Skipper
+	}
+	ETagOption func(*ETagOptions)
+)
+
+// GetDefaultETagOptions returns the default options
+func GetDefaultETagOptions() ETagOptions {
+	return ETagOptions{
+		MaxBodySize: 1 << 20,
+	}
+}
+
+// ETagWithWeak middleware option
+func ETagWithWeak(v bool) ETagOption {
+	return func(o *ETagOptions) {
+		o.Weak = v
+	}
+}
+
+// ETagWithMaxBodySize middleware option
+func ETagWithMaxBodySize(v int) ETagOption {
+	return func(o *ETagOptions) {
+		o.MaxBodySize = v
+	}
+}
+
+// ETagWithSkippers middleware option
+func ETagWithSkippers(v ...Skipper) ETagOption {
+	return func(o *ETagOptions) {
+		o.Skippers = append(o.Skippers, v...)
+	}
+}
+
+// ETag middleware
+func ETag(opts ...ETagOption) keelhttp.Middleware {
+	options := GetDefaultETagOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return ETagWithOptions(options)
+}
+
+// ETagWithOptions middleware adds an ETag to successful GET responses
+// that do not have one yet and answers conditional GET and HEAD requests
+// with 304. The validator is generated over the body and its Content-Encoding
+// so differently encoded representations never share a tag.
+func ETagWithOptions(opts ETagOptions) keelhttp.Middleware {
+	return func(l *zap.Logger, name string, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if span.IsRecording() {
+				span.AddEvent("ETag")
+			}
+
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for _, skipper := range opts.Skippers {
+				if skipper(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			bw := newBufferedResponseWriter(w, opts.MaxBodySize)
+			next.ServeHTTP(bw, r)
+
+			if bw.Streaming() {
+				return
+			}
+
+			// HEAD responses lack the body to generate the validator from
+			if bw.statusCode == http.StatusOK && r.Method == http.MethodGet && w.Header().Get(keelhttp.HeaderETag) == "" {
+				w.Header().Set(keelhttp.HeaderETag, generateETag(bw.Body(), w.Header().Get(keelhttp.HeaderContentEncoding), opts.Weak))
+			}
+
+			writeConditionalResponse(w, r, bw.statusCode, bw.Body())
+		})
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func generateETag(body []byte, encoding string, weak bool) string {
+	h := sha256.New()
+	if encoding != "" {
+		_, _ = h.Write([]byte(encoding))
+		_, _ = h.Write([]byte{0})
+	}
+
+	_, _ = h.Write(body)
+
+	ret := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	if weak {
+		ret = "W/" + ret
+	}
+
+	return ret
+}
+
+// writeConditionalResponse writes the response or 304 if the request's
+// preconditions match the validators already set on the response header
+func writeConditionalResponse(w http.ResponseWriter, r *http.Request, statusCode int, body []byte) {
+	if statusCode == http.StatusOK && (r.Method == http.MethodGet || r.Method == http.MethodHead) && notModified(r, w.Header()) {
+		h := w.Header()
+		h.Del(keelhttp.HeaderContentType)
+		h.Del(keelhttp.HeaderContentLength)
+		h.Del(keelhttp.HeaderContentEncoding)
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	w.WriteHeader(statusCode)
+
+	if len(body) > 0 {
+		_, _ = w.Write(body)
+	}
+}
+
+// notModified evaluates If-None-Match and If-Modified-Since as described in RFC 9110
+func notModified(r *http.Request, header http.Header) bool {
+	if inm := r.Header.Get(keelhttp.HeaderIfNoneMatch); inm != "" {
+		etag := header.Get(keelhttp.HeaderETag)
+		if etag == "" {
+			return false
+		}
+
+		for candidate := range strings.SplitSeq(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || weakETag(candidate) == weakETag(etag) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	ims, err := http.ParseTime(r.Header.Get(keelhttp.HeaderIfModifiedSince))
+	if err != nil {
+		return false
+	}
+
+	lastModified, err := http.ParseTime(header.Get(keelhttp.HeaderLastModified))
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(ims)
+}
+
+// weakETag returns the opaque tag for the weak comparison
+func weakETag(v string) string {
+	return strings.TrimPrefix(v, "W/")
+}
@@ -0,0 +1,104 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestETag(t *testing.T) {
+	t.Parallel()
+
+	lastModified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	handler := middleware.ETag()(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(keelhttp.HeaderContentType, "application/json")
+		w.Header().Set(keelhttp.HeaderLastModified, lastModified.Format(http.TimeFormat))
+		_, _ = w.Write([]byte(`{"foo":"bar"}`))
+	}))
+
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := get(nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"foo":"bar"}`, w.Body.String())
+
+	etag := w.Header().Get(keelhttp.HeaderETag)
+	assert.True(t, strings.HasPrefix(etag, `"`))
+
+	w = get(http.Header{keelhttp.HeaderIfNoneMatch: {`"other", ` + etag}})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get(keelhttp.HeaderETag))
+	assert.Empty(t, w.Header().Get(keelhttp.HeaderContentType))
+
+	// weak comparison
+	assert.Equal(t, http.StatusNotModified, get(http.Header{keelhttp.HeaderIfNoneMatch: {"W/" + etag}}).Code)
+	assert.Equal(t, http.StatusOK, get(http.Header{keelhttp.HeaderIfNoneMatch: {`"other"`}}).Code)
+
+	// If-Modified-Since is only evaluated without If-None-Match
+	assert.Equal(t, http.StatusNotModified, get(http.Header{keelhttp.HeaderIfModifiedSince: {lastModified.Format(http.TimeFormat)}}).Code)
+	assert.Equal(t, http.StatusOK, get(http.Header{keelhttp.HeaderIfModifiedSince: {lastModified.Add(-time.Hour).Format(http.TimeFormat)}}).Code)
+}
+
+func TestETag_contentEncoding(t *testing.T) {
+	t.Parallel()
+
+	handler := middleware.ETag()(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if encoding := r.URL.Query().Get("encoding"); encoding != "" {
+			w.Header().Set(keelhttp.HeaderContentEncoding, encoding)
+		}
+
+		_, _ = w.Write([]byte("keel"))
+	}))
+
+	etag := func(encoding string) string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?encoding="+encoding, nil))
+
+		return w.Header().Get(keelhttp.HeaderETag)
+	}
+
+	assert.NotEqual(t, etag(""), etag("br"))
+	assert.NotEqual(t, etag("br"), etag("gzip"))
+	assert.Equal(t, etag("gzip"), etag("gzip"))
+}
+
+func TestETag_streaming(t *testing.T) {
+	t.Parallel()
+
+	handler := middleware.ETag(
+		middleware.ETagWithMaxBodySize(4),
+		middleware.ETagWithWeak(true),
+	)(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Query().Get("body")))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?body=1234", nil))
+	assert.True(t, strings.HasPrefix(w.Header().Get(keelhttp.HeaderETag), `W/"`))
+
+	// larger responses are streamed without validator
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?body=12345", nil))
+	assert.Equal(t, "12345", w.Body.String())
+	assert.Empty(t, w.Header().Get(keelhttp.HeaderETag))
+}
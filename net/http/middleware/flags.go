@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/foomo/keel/flags"
+	keelhttp "github.com/foomo/keel/net/http"
+	keelhttpcontext "github.com/foomo/keel/net/http/context"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type (
+	FlagsOptions struct {
+		// Headers exposed as "header.<Name>" attributes
+		Headers []string
+		// ClaimsProvider returns the claims exposed as "claim.<name>" attributes
+		ClaimsProvider FlagsClaimsProvider
+		// TargetingKeyProvider returns the key used for sticky bucketing
+		TargetingKeyProvider FlagsTargetingKeyProvider
+	}
+	FlagsOption               func(*FlagsOptions)
+	FlagsClaimsProvider       func(r *http.Request) map[string]any
+	FlagsTargetingKeyProvider func(r *http.Request, ec flags.EvaluationContext) string
+)
+
+// DefaultFlagsTargetingKeyProvider function uses the tracking id, session id or the jwt subject
+func DefaultFlagsTargetingKeyProvider(r *http.Request, ec flags.EvaluationContext) string {
+	for _, attribute := range []string{flags.AttributeTrackingID, flags.AttributeSessionID, flags.AttributeClaimPrefix + "sub"} {
+		if value, ok := ec.Value(attribute); ok && value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// GetDefaultFlagsOptions returns the default options
+func GetDefaultFlagsOptions() FlagsOptions {
+	return FlagsOptions{
+		TargetingKeyProvider: DefaultFlagsTargetingKeyProvider,
+	}
+}
+
+// FlagsWithHeaders middleware option
+func FlagsWithHeaders(v ...string) FlagsOption {
+	return func(o *FlagsOptions) {
+		o.Headers = append(o.Headers, v...)
+	}
+}
+
+// FlagsWithClaimsProvider middleware option
+func FlagsWithClaimsProvider(v FlagsClaimsProvider) FlagsOption {
+	return func(o *FlagsOptions) {
+		o.ClaimsProvider = v
+	}
+}
+
+// FlagsWithJWTContextKey middleware option reads the claims stored by the JWT middleware
+func FlagsWithJWTContextKey(contextKey any) FlagsOption {
+	return func(o *FlagsOptions) {
+		o.ClaimsProvider = func(r *http.Request) map[string]any {
+			value := r.Context().Value(contextKey)
+			if value == nil {
+				return nil
+			}
+
+			// round trip through json to support any claims implementation
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil
+			}
+
+			var ret map[string]any
+			if err := json.Unmarshal(b, &ret); err != nil {
+				return nil
+			}
+
+			return ret
+		}
+	}
+}
+
+// FlagsWithTargetingKeyProvider middleware option
+func FlagsWithTargetingKeyProvider(v FlagsTargetingKeyProvider) FlagsOption {
+	return func(o *FlagsOptions) {
+		o.TargetingKeyProvider = v
+	}
+}
+
+// Flags middleware stores a flags.EvaluationContext built from the
+// request so handlers can evaluate flags with r.Context(). Add it after
+// the TrackingID, SessionID and JWT middlewares it reads from.
+func Flags(opts ...FlagsOption) keelhttp.Middleware {
+	options := GetDefaultFlagsOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return FlagsWithOptions(options)
+}
+
+// FlagsWithOptions middleware
+func FlagsWithOptions(opts FlagsOptions) keelhttp.Middleware {
+	return func(l *zap.Logger, name string, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if span.IsRecording() {
+				span.AddEvent("Flags")
+			}
+
+			ec := flags.NewEvaluationContext("")
+
+			if value, ok := keelhttpcontext.GetTrackingID(r.Context()); ok {
+				ec.Attributes[flags.AttributeTrackingID] = []string{value}
+			}
+
+			if value, ok := keelhttpcontext.GetSessionID(r.Context()); ok {
+				ec.Attributes[flags.AttributeSessionID] = []string{value}
+			}
+
+			for _, header := range opts.Headers {
+				if values := r.Header.Values(header); len(values) > 0 {
+					ec.Attributes[flags.AttributeHeaderPrefix+http.CanonicalHeaderKey(header)] = values
+				}
+			}
+
+			if opts.ClaimsProvider != nil {
+				for key, value := range opts.ClaimsProvider(r) {
+					if values := flagsClaimValues(value); len(values) > 0 {
+						ec.Attributes[flags.AttributeClaimPrefix+key] = values
+					}
+				}
+			}
+
+			if opts.TargetingKeyProvider != nil {
+				ec.TargetingKey = opts.TargetingKeyProvider(r, ec)
+			}
+
+			next.ServeHTTP(w, r.WithContext(flags.WithEvaluationContext(r.Context(), ec)))
+		})
+	}
+}
+
+// flagsClaimValues flattens scalar and list claims; nested objects are skipped.
+func flagsClaimValues(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case bool, float64, json.Number:
+		return []string{fmt.Sprint(t)}
+	case []any:
+		ret := make([]string, 0, len(t))
+		for _, e := range t {
+			ret = append(ret, flagsClaimValues(e)...)
+		}
+
+		return ret
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/foomo/keel/flags"
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFlags(t *testing.T) {
+	t.Parallel()
+
+	f, err := flags.New(zap.NewNop(), flags.Config{
+		Flags: []flags.Flag{
+			{
+				Key: "beta",
+				Rules: []flags.Rule{
+					{
+						Conditions: []flags.Condition{{Attribute: flags.AttributeTrackingID, Operator: flags.OperatorIn, Values: []string{"tracking-a"}}},
+						Variant:    "on",
+					},
+					{
+						Conditions: []flags.Condition{{Attribute: "header.X-Country", Operator: flags.OperatorIn, Values: []string{"DE"}}},
+						Variant:    "on",
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	var ec flags.EvaluationContext
+
+	handler := keelhttp.Compose(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ec, _ = flags.EvaluationContextFromContext(r.Context())
+		_, _ = w.Write([]byte(f.Variant(r.Context(), "beta", "")))
+	}),
+		middleware.Flags(middleware.FlagsWithHeaders("X-Country")),
+		middleware.SessionID(),
+		middleware.TrackingID(),
+	)
+
+	serve := func(header map[string]string) string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w.Body.String()
+	}
+
+	// the tracking id is preferred as targeting key
+	assert.Equal(t, "on", serve(map[string]string{
+		keelhttp.HeaderXTrackingID: "tracking-a",
+		keelhttp.HeaderXSessionID:  "session-a",
+	}))
+	assert.Equal(t, "tracking-a", ec.TargetingKey)
+
+	value, _ := ec.Value(flags.AttributeSessionID)
+	assert.Equal(t, "session-a", value)
+
+	// the session id is used without a tracking id
+	assert.Equal(t, "off", serve(map[string]string{keelhttp.HeaderXSessionID: "session-b"}))
+	assert.Equal(t, "session-b", ec.TargetingKey)
+
+	// configured headers are exposed as attributes
+	assert.Equal(t, "on", serve(map[string]string{"X-Country": "DE"}))
+	assert.Empty(t, ec.TargetingKey)
+}
@@ -4,9 +4,14 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/foomo/keel/log"
 	keelhttp "github.com/foomo/keel/net/http"
+	httplog "github.com/foomo/keel/net/http/log"
+	"github.com/foomo/keel/telemetry"
 	gojwt "github.com/golang-jwt/jwt/v5"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
@@ -14,6 +19,14 @@ import (
 	httputils "github.com/foomo/keel/utils/net/http"
 )
 
+const jwtRejectionKey = attribute.Key("keel.jwt.rejection")
+
+const (
+	JWTRejectionInvalid  = "invalid"
+	JWTRejectionRevoked  = "revoked"
+	JWTRejectionReplayed = "replayed"
+)
+
 type (
 	JWTOptions struct {
 		SetContext          bool
@@ -22,7 +35,12 @@ type (
 		ClaimsHandler       JWTClaimsHandler
 		MissingTokenHandler JWTMissingTokenHandler
 		InvalidTokenHandler JWTInvalidTokenHandler
+		RevokedTokenHandler JWTRevokedTokenHandler
 		ErrorHandler        JWTErrorHandler
+		// RevocationStore (optional) rejects revoked tokens
+		RevocationStore jwt.RevocationStore
+		// OneTime rejects tokens which have been used before, requires the RevocationStore
+		OneTime bool
 	}
 	JWTOption              func(*JWTOptions)
 	JWTClaimsProvider      func() gojwt.Claims
@@ -30,6 +48,7 @@ type (
 	JWTErrorHandler        func(*zap.Logger, http.ResponseWriter, *http.Request, error) bool
 	JWTMissingTokenHandler func(*zap.Logger, http.ResponseWriter, *http.Request) (gojwt.Claims, bool)
 	JWTInvalidTokenHandler func(*zap.Logger, http.ResponseWriter, *http.Request, *gojwt.Token) bool
+	JWTRevokedTokenHandler func(*zap.Logger, http.ResponseWriter, *http.Request, *gojwt.Token, error) bool
 )
 
 // DefaultJWTErrorHandler function
@@ -55,6 +74,12 @@ func DefaultJWTInvalidTokenHandler(l *zap.Logger, w http.ResponseWriter, r *http
 	return false
 }
 
+// DefaultJWTRevokedTokenHandler function
+func DefaultJWTRevokedTokenHandler(l *zap.Logger, w http.ResponseWriter, r *http.Request, token *gojwt.Token, err error) bool {
+	httputils.UnauthorizedServerError(l, w, r, err)
+	return false
+}
+
 // DefaultJWTClaimsProvider function
 func DefaultJWTClaimsProvider() gojwt.Claims {
 	return &gojwt.RegisteredClaims{}
@@ -74,6 +99,7 @@ func GetDefaultJWTOptions() JWTOptions {
 		ClaimsHandler:       DefaultJWTClaimsHandler,
 		ErrorHandler:        DefaultJWTErrorHandler,
 		InvalidTokenHandler: DefaultJWTInvalidTokenHandler,
+		RevokedTokenHandler: DefaultJWTRevokedTokenHandler,
 		MissingTokenHandler: DefaultJWTMissingTokenHandler,
 	}
 }
@@ -106,6 +132,27 @@ func JWTWithInvalidTokenHandler(v JWTInvalidTokenHandler) JWTOption {
 	}
 }
 
+// JWTWithRevokedTokenHandler middleware option
+func JWTWithRevokedTokenHandler(v JWTRevokedTokenHandler) JWTOption {
+	return func(o *JWTOptions) {
+		o.RevokedTokenHandler = v
+	}
+}
+
+// JWTWithRevocationStore middleware option
+func JWTWithRevocationStore(v jwt.RevocationStore) JWTOption {
+	return func(o *JWTOptions) {
+		o.RevocationStore = v
+	}
+}
+
+// JWTWithOneTime middleware option
+func JWTWithOneTime(v bool) JWTOption {
+	return func(o *JWTOptions) {
+		o.OneTime = v
+	}
+}
+
 // JWTWithMissingTokenHandler middleware option
 func JWTWithMissingTokenHandler(v JWTMissingTokenHandler) JWTOption {
 	return func(o *JWTOptions) {
@@ -141,6 +188,10 @@ func JWT(v *jwt.JWT, contextKey any, opts ...JWTOption) keelhttp.Middleware {
 
 // JWTWithOptions middleware
 func JWTWithOptions(v *jwt.JWT, contextKey any, opts JWTOptions) keelhttp.Middleware {
+	rejections := telemetry.NewIntCounter("keel.http.server.jwt.rejections",
+		metric.WithDescription("Number of requests with an invalid, revoked or replayed jwt token"),
+	)
+
 	return func(l *zap.Logger, name string, next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			span := trace.SpanFromContext(r.Context())
@@ -148,6 +199,24 @@ func JWTWithOptions(v *jwt.JWT, contextKey any, opts JWTOptions) keelhttp.Middle
 				span.AddEvent("JWT")
 			}
 
+			reject := func(reason string) *zap.Logger {
+				rejections.Add(r.Context(), 1, metric.WithAttributes(
+					attribute.String("http.server_name", name),
+					jwtRejectionKey.String(reason),
+				))
+
+				if span.IsRecording() {
+					span.AddEvent("JWTRejected", trace.WithAttributes(jwtRejectionKey.String(reason)))
+				}
+
+				if labeler, ok := httplog.LabelerFromRequest(r); ok {
+					labeler.Add(log.Attributes(jwtRejectionKey.String(reason))...)
+					return l
+				}
+
+				return l.With(log.Attributes(jwtRejectionKey.String(reason))...)
+			}
+
 			claims := opts.ClaimsProvider()
 
 			// check existing claims from context
@@ -185,20 +254,51 @@ func JWTWithOptions(v *jwt.JWT, contextKey any, opts JWTOptions) keelhttp.Middle
 			// handle existing token
 			jwtToken, err := v.ParseWithClaims(token, claims)
 			if err != nil {
-				if resume := opts.ErrorHandler(l, w, r, err); resume {
+				errL := l
+				if jwtInvalidTokenError(err) {
+					errL = reject(JWTRejectionInvalid)
+				}
+
+				if resume := opts.ErrorHandler(errL, w, r, err); resume {
 					next.ServeHTTP(w, r)
 					return
 				} else {
 					return
 				}
 			} else if !jwtToken.Valid {
-				if resume := opts.InvalidTokenHandler(l, w, r, jwtToken); resume {
+				if resume := opts.InvalidTokenHandler(reject(JWTRejectionInvalid), w, r, jwtToken); resume {
 					next.ServeHTTP(w, r)
 					return
 				} else {
 					return
 				}
-			} else if resume := opts.ClaimsHandler(l, w, r, claims); !resume {
+			}
+
+			// check revocation and replays
+			if opts.RevocationStore != nil {
+				if err := jwt.CheckRevocation(r.Context(), opts.RevocationStore, jwtToken, opts.OneTime); err != nil {
+					var resume bool
+
+					switch {
+					case errors.Is(err, jwt.ErrTokenRevoked):
+						resume = opts.RevokedTokenHandler(reject(JWTRejectionRevoked), w, r, jwtToken, err)
+					case errors.Is(err, jwt.ErrTokenReplayed):
+						resume = opts.RevokedTokenHandler(reject(JWTRejectionReplayed), w, r, jwtToken, err)
+					case errors.Is(err, jwt.ErrTokenNoID):
+						resume = opts.InvalidTokenHandler(reject(JWTRejectionInvalid), w, r, jwtToken)
+					default:
+						resume = opts.ErrorHandler(l, w, r, err)
+					}
+
+					if resume {
+						next.ServeHTTP(w, r)
+					}
+
+					return
+				}
+			}
+
+			if resume := opts.ClaimsHandler(l, w, r, claims); !resume {
 				return
 			} else {
 				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey, claims)))
@@ -207,3 +307,12 @@ func JWTWithOptions(v *jwt.JWT, contextKey any, opts JWTOptions) keelhttp.Middle
 		})
 	}
 }
+
+// jwtInvalidTokenError returns true if the token was rejected by the parser e.g.
+// for an invalid signature or expired claims
+func jwtInvalidTokenError(err error) bool {
+	return errors.Is(err, gojwt.ErrTokenMalformed) ||
+		errors.Is(err, gojwt.ErrTokenUnverifiable) ||
+		errors.Is(err, gojwt.ErrTokenSignatureInvalid) ||
+		errors.Is(err, gojwt.ErrTokenInvalidClaims)
+}
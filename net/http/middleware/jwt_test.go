@@ -0,0 +1,154 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/foomo/keel/jwt"
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/middleware"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestJWT_revocation(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwt.NewHMACKey("test", "HS256", []byte("secret"))
+	require.NoError(t, err)
+
+	j := jwt.New(key)
+	store := jwt.NewMemoryRevocationStore()
+
+	type contextKey string
+
+	var rejected error
+
+	handler := keelhttp.Compose(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Context().Value(contextKey("claims")).(*gojwt.RegisteredClaims).Subject))
+	}), middleware.JWT(j, contextKey("claims"),
+		middleware.JWTWithRevocationStore(store),
+		middleware.JWTWithRevokedTokenHandler(func(l *zap.Logger, w http.ResponseWriter, r *http.Request, token *gojwt.Token, err error) bool {
+			rejected = err
+			return middleware.DefaultJWTRevokedTokenHandler(l, w, r, token, err)
+		}),
+	))
+
+	sign := func(id string) string {
+		token, err := j.GetSignedToken(&gojwt.RegisteredClaims{
+			ID:        id,
+			Subject:   "user",
+			IssuedAt:  gojwt.NewNumericDate(time.Now().Add(-time.Minute)),
+			ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Hour)),
+		})
+		require.NoError(t, err)
+
+		return token
+	}
+
+	serve := func(token string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(keelhttp.HeaderAuthorization, keelhttp.HeaderValueAuthorizationPrefix+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	token := sign("a")
+	assert.Equal(t, http.StatusOK, serve(token))
+
+	require.NoError(t, store.RevokeID(context.Background(), "a", time.Now().Add(time.Hour)))
+	assert.Equal(t, http.StatusUnauthorized, serve(token))
+	require.ErrorIs(t, rejected, jwt.ErrTokenRevoked)
+	assert.Equal(t, http.StatusOK, serve(sign("b")))
+}
+
+func TestJWT_oneTime(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwt.NewHMACKey("test", "HS256", []byte("secret"))
+	require.NoError(t, err)
+
+	j := jwt.New(key)
+
+	type contextKey string
+
+	handler := keelhttp.Compose(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), middleware.JWT(j, contextKey("claims"),
+		middleware.JWTWithRevocationStore(jwt.NewMemoryRevocationStore()),
+		middleware.JWTWithOneTime(true),
+	))
+
+	serve := func(token string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(keelhttp.HeaderAuthorization, keelhttp.HeaderValueAuthorizationPrefix+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	token, err := j.GetSignedToken(&gojwt.RegisteredClaims{ID: "a", ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Hour))})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, serve(token))
+	assert.Equal(t, http.StatusUnauthorized, serve(token))
+
+	// one-time tokens require a jti
+	token, err = j.GetSignedToken(&gojwt.RegisteredClaims{ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Hour))})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, serve(token))
+}
+
+func TestJWT_invalid(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwt.NewHMACKey("test", "HS256", []byte("secret"))
+	require.NoError(t, err)
+
+	other, err := jwt.NewHMACKey("test", "HS256", []byte("other"))
+	require.NoError(t, err)
+
+	type contextKey string
+
+	tests := map[string]func() (string, error){
+		"expired": func() (string, error) {
+			return jwt.New(key).GetSignedToken(&gojwt.RegisteredClaims{ExpiresAt: gojwt.NewNumericDate(time.Now().Add(-time.Hour))})
+		},
+		"signature": func() (string, error) {
+			return jwt.New(other).GetSignedToken(&gojwt.RegisteredClaims{})
+		},
+		"malformed": func() (string, error) {
+			return "malformed", nil
+		},
+	}
+
+	for name, sign := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			core, logs := observer.New(zap.InfoLevel)
+			handler := keelhttp.Compose(zap.New(core), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}), middleware.JWT(jwt.New(key), contextKey("claims")))
+
+			token, err := sign()
+			require.NoError(t, err)
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(keelhttp.HeaderAuthorization, keelhttp.HeaderValueAuthorizationPrefix+token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.NotEqual(t, http.StatusNoContent, w.Code)
+
+			require.Equal(t, 1, logs.FilterField(zap.String("keel_jwt_rejection", middleware.JWTRejectionInvalid)).Len())
+		})
+	}
+}
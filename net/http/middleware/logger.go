@@ -96,6 +96,8 @@ func LoggerWithOptions(opts LoggerOptions) keelhttp.Middleware {
 				r, labeler = httplog.InjectLabelerIntoRequest(r)
 			}
 
+			r, route := keelhttp.InjectRouteIntoRequest(r)
+
 			next.ServeHTTP(wr, r)
 
 			l = l.With(
@@ -106,6 +108,9 @@ func LoggerWithOptions(opts LoggerOptions) keelhttp.Middleware {
 
 			if labeler != nil {
 				l = l.With(labeler.Get()...)
+			} else if route.Template != "" {
+				// the router adds the route to the labeler otherwise
+				l = l.With(log.Attribute(semconv.HTTPRoute(route.Template)))
 			}
 
 			if err := r.Context().Err(); err != nil {
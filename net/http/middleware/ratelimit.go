@@ -0,0 +1,290 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	keelhttpcontext "github.com/foomo/keel/net/http/context"
+	"github.com/foomo/keel/net/http/ratelimit"
+	"github.com/foomo/keel/telemetry"
+	httputils "github.com/foomo/keel/utils/net/http"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/foomo/keel/log"
+)
+
+const (
+	rateLimitOutcomeKey     = attribute.Key("keel.ratelimit.outcome")
+	rateLimitOutcomeAllowed = "allowed"
+	rateLimitOutcomeLimited = "limited"
+	rateLimitOutcomeError   = "error"
+)
+
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+type (
+	RateLimitOptions struct {
+		// Limit applied to routes without a limit of their own
+		Limit ratelimit.Limit
+		// Routes limits by route template of the keelhttp.Router or path
+		Routes map[string]ratelimit.Limit
+		// Store keeping the state of the keys
+		Store ratelimit.Store
+		// KeyFunc returns the key to limit by, requests without a key are not limited
+		KeyFunc RateLimitKeyFunc
+		// Skippers exclude requests from limiting
+		Skippers []Skipper
+		// SetHeaders adds the RateLimit-* headers to all responses
+		SetHeaders bool
+		// LimitedHandler responds to limited requests
+		LimitedHandler RateLimitHandler
+	}
+	RateLimitOption  func(*RateLimitOptions)
+	RateLimitKeyFunc func(r *http.Request) string
+	RateLimitHandler func(l *zap.Logger, w http.ResponseWriter, r *http.Request, result ratelimit.Result)
+)
+
+// DefaultRateLimitHandler function
+func DefaultRateLimitHandler(l *zap.Logger, w http.ResponseWriter, r *http.Request, result ratelimit.Result) {
+	httputils.TooManyRequestsServerError(l, w, r, ErrRateLimited)
+}
+
+// RateLimitRemoteAddrKey limits by client ip
+func RateLimitRemoteAddrKey() RateLimitKeyFunc {
+	return httputils.GetRemoteAddr
+}
+
+// RateLimitHeaderKey limits by the value of the request header
+func RateLimitHeaderKey(name string) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// RateLimitSessionIDKey limits by the session id set by the SessionID middleware
+func RateLimitSessionIDKey() RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return SessionIDFromContext(r.Context())
+	}
+}
+
+// RateLimitTrackingIDKey limits by the tracking id set by the TrackingID middleware
+func RateLimitTrackingIDKey() RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		value, _ := keelhttpcontext.GetTrackingID(r.Context())
+		return value
+	}
+}
+
+// RateLimitJWTClaimKey limits by a claim of the claims set by the JWT
+// middleware under the given context key, e.g. "sub"
+func RateLimitJWTClaimKey(contextKey any, claim string) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		claims, ok := r.Context().Value(contextKey).(gojwt.Claims)
+		if !ok {
+			return ""
+		}
+
+		return jwtClaim(claims, claim)
+	}
+}
+
+// GetDefaultRateLimitOptions returns the default options
+func GetDefaultRateLimitOptions() RateLimitOptions {
+	return RateLimitOptions{
+		Limit:          ratelimit.TokenBucket(100, time.Minute),
+		KeyFunc:        RateLimitRemoteAddrKey(),
+		SetHeaders:     true,
+		LimitedHandler: DefaultRateLimitHandler,
+	}
+}
+
+// RateLimitWithLimit middleware option
+func RateLimitWithLimit(v ratelimit.Limit) RateLimitOption {
+	return func(o *RateLimitOptions) {
+		o.Limit = v
+	}
+}
+
+// RateLimitWithRoute middleware option limits the route template or path
+func RateLimitWithRoute(route string, v ratelimit.Limit) RateLimitOption {
+	return func(o *RateLimitOptions) {
+		if o.Routes == nil {
+			o.Routes = map[string]ratelimit.Limit{}
+		}
+
+		o.Routes[route] = v
+	}
+}
+
+// RateLimitWithStore middleware option
+func RateLimitWithStore(v ratelimit.Store) RateLimitOption {
+	return func(o *RateLimitOptions) {
+		o.Store = v
+	}
+}
+
+// RateLimitWithKeyFunc middleware option
+func RateLimitWithKeyFunc(v RateLimitKeyFunc) RateLimitOption {
+	return func(o *RateLimitOptions) {
+		o.KeyFunc = v
+	}
+}
+
+// RateLimitWithSkippers middleware option
+func RateLimitWithSkippers(v ...Skipper) RateLimitOption {
+	return func(o *RateLimitOptions) {
+		o.Skippers = append(o.Skippers, v...)
+	}
+}
+
+// RateLimitWithSetHeaders middleware option
+func RateLimitWithSetHeaders(v bool) RateLimitOption {
+	return func(o *RateLimitOptions) {
+		o.SetHeaders = v
+	}
+}
+
+// RateLimitWithLimitedHandler middleware option
+func RateLimitWithLimitedHandler(v RateLimitHandler) RateLimitOption {
+	return func(o *RateLimitOptions) {
+		o.LimitedHandler = v
+	}
+}
+
+// RateLimit middleware
+func RateLimit(opts ...RateLimitOption) keelhttp.Middleware {
+	options := GetDefaultRateLimitOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return RateLimitWithOptions(options)
+}
+
+// RateLimitWithOptions middleware
+func RateLimitWithOptions(opts RateLimitOptions) keelhttp.Middleware {
+	if opts.Store == nil {
+		opts.Store = ratelimit.NewMemoryStore()
+	}
+
+	requests := telemetry.NewIntCounter("keel.http.server.ratelimit.requests",
+		metric.WithDescription("Number of requests checked by the rate limit"),
+	)
+
+	return func(l *zap.Logger, name string, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if span.IsRecording() {
+				span.AddEvent("RateLimit")
+			}
+
+			for _, skipper := range opts.Skippers {
+				if skipper(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			key := opts.KeyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			route, limit := routeValue(r, opts.Routes, opts.Limit)
+			attrs := metric.WithAttributes(
+				attribute.String("http.server_name", name),
+				semconv.HTTPRoute(route),
+			)
+
+			result, err := opts.Store.Take(r.Context(), route+"|"+key, limit)
+			if err != nil {
+				// fail open to not take the service down with the store
+				log.WithError(l, err).Warn("failed to take rate limit", log.Attribute(semconv.HTTPRoute(route)))
+				requests.Add(r.Context(), 1, attrs, metric.WithAttributes(rateLimitOutcomeKey.String(rateLimitOutcomeError)))
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if opts.SetHeaders {
+				setRateLimitHeaders(w, limit, result)
+			}
+
+			if !result.Allowed {
+				requests.Add(r.Context(), 1, attrs, metric.WithAttributes(rateLimitOutcomeKey.String(rateLimitOutcomeLimited)))
+
+				if span.IsRecording() {
+					span.AddEvent("RateLimited")
+				}
+
+				w.Header().Set(keelhttp.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				opts.LimitedHandler(l, w, r, result)
+
+				return
+			}
+
+			requests.Add(r.Context(), 1, attrs, metric.WithAttributes(rateLimitOutcomeKey.String(rateLimitOutcomeAllowed)))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func setRateLimitHeaders(w http.ResponseWriter, limit ratelimit.Limit, result ratelimit.Result) {
+	w.Header().Set(keelhttp.HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+	w.Header().Set(keelhttp.HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+	w.Header().Set(keelhttp.HeaderRateLimitReset, strconv.Itoa(ceilSeconds(result.Reset)))
+	w.Header().Set(keelhttp.HeaderRateLimitPolicy, limit.Policy())
+}
+
+func ceilSeconds(v time.Duration) int {
+	return int(math.Ceil(v.Seconds()))
+}
+
+// jwtClaim returns the string value of the claim
+func jwtClaim(claims gojwt.Claims, claim string) string {
+	switch claim {
+	case "sub":
+		v, _ := claims.GetSubject()
+		return v
+	case "iss":
+		v, _ := claims.GetIssuer()
+		return v
+	}
+
+	values, ok := claims.(gojwt.MapClaims)
+	if !ok {
+		b, err := json.Marshal(claims)
+		if err != nil || json.Unmarshal(b, &values) != nil {
+			return ""
+		}
+	}
+
+	switch v := values[claim].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/foomo/keel/net/http/ratelimit"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	router := keelhttp.NewRouter(zap.NewNop(), "test")
+	router.Use(middleware.RateLimit(
+		middleware.RateLimitWithLimit(ratelimit.TokenBucket(2, time.Minute)),
+		middleware.RateLimitWithRoute("/login/{provider}", ratelimit.SlidingWindow(1, time.Minute)),
+		middleware.RateLimitWithSkippers(middleware.RequestURIBlacklistSkipper("/health")),
+	))
+	router.HandleFunc("login", "/login/{provider}", func(w http.ResponseWriter, r *http.Request) {})
+	router.HandleFunc("health", "/health", func(w http.ResponseWriter, r *http.Request) {})
+	router.HandleFunc("root", "/", func(w http.ResponseWriter, r *http.Request) {})
+
+	get := func(path, ip string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = ip + ":1234"
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := get("/", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(keelhttp.HeaderRateLimitLimit))
+	assert.Equal(t, "1", w.Header().Get(keelhttp.HeaderRateLimitRemaining))
+	assert.Equal(t, "2;w=60", w.Header().Get(keelhttp.HeaderRateLimitPolicy))
+
+	assert.Equal(t, http.StatusOK, get("/other", "10.0.0.1").Code)
+
+	w = get("/", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get(keelhttp.HeaderRetryAfter))
+
+	// keys are limited independently
+	assert.Equal(t, http.StatusOK, get("/", "10.0.0.2").Code)
+
+	// routes have their own limits
+	assert.Equal(t, http.StatusOK, get("/login/github", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, get("/login/google", "10.0.0.1").Code)
+
+	// skipped requests are not limited
+	assert.Equal(t, http.StatusOK, get("/health", "10.0.0.1").Code)
+}
+
+func TestRateLimit_jwtClaimKey(t *testing.T) {
+	t.Parallel()
+
+	type contextKey string
+
+	handler := middleware.RateLimit(
+		middleware.RateLimitWithLimit(ratelimit.TokenBucket(1, time.Minute)),
+		middleware.RateLimitWithKeyFunc(middleware.RateLimitJWTClaimKey(contextKey("claims"), "sub")),
+	)(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	get := func(sub string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if sub != "" {
+			r = r.WithContext(context.WithValue(r.Context(), contextKey("claims"), &gojwt.RegisteredClaims{Subject: sub}))
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, get("alice"))
+	assert.Equal(t, http.StatusTooManyRequests, get("alice"))
+	assert.Equal(t, http.StatusOK, get("bob"))
+
+	// requests without a key are not limited
+	assert.Equal(t, http.StatusOK, get(""))
+	assert.Equal(t, http.StatusOK, get(""))
+}
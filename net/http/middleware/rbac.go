@@ -3,8 +3,10 @@ package middleware
 import (
 	"net/http"
 
+	"github.com/foomo/keel/log"
 	keelhttp "github.com/foomo/keel/net/http"
 	"github.com/foomo/keel/net/http/rbac"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
@@ -34,6 +36,10 @@ import (
 // AllowRoles set. Path matching picks the most specific rule (exact
 // wins over longest matching prefix).
 //
+// Requests routed by keelhttp.Router are matched against rules declared
+// for their route template first, e.g. "/users/{id}", if the middleware
+// is registered on the router.
+//
 // Denied requests log a structured zap warning ("rbac denied request")
 // at WarnLevel with path, outcome, authenticated flag, roles, and
 // matched rule path; allowed requests are not logged. The 401/403
@@ -47,7 +53,13 @@ func RBAC(m *rbac.Matcher, extract rbac.RolesExtractor) keelhttp.Middleware {
 				span.AddEvent("RBAC")
 			}
 
-			d := m.Evaluate(extract, r)
+			var d rbac.Decision
+			route, ok := keelhttp.RouteFromRequest(r)
+			if ok && route.Template != "" {
+				d = m.EvaluateRoute(extract, r, route.Template)
+			} else {
+				d = m.Evaluate(extract, r)
+			}
 
 			// Fast path: allowed requests skip logging entirely — they
 			// are the common case and would dwarf denies under traffic.
@@ -67,6 +79,10 @@ func RBAC(m *rbac.Matcher, extract rbac.RolesExtractor) keelhttp.Middleware {
 					fields = append(fields, zap.String("rule_path", d.Rule.Raw.Path))
 				}
 
+				if ok && route.Template != "" {
+					fields = append(fields, log.Attribute(semconv.HTTPRoute(route.Template)))
+				}
+
 				l.Warn("rbac denied request", fields...)
 			}
 
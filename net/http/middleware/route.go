@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	keelhttp "github.com/foomo/keel/net/http"
+)
+
+// routeValue returns the route and its value configured by route template of
+// the keelhttp.Router or path, falling back to "*" and the given value
+func routeValue[T any](r *http.Request, routes map[string]T, fallback T) (string, T) {
+	if route, ok := keelhttp.RouteFromRequest(r); ok && route.Template != "" {
+		if v, ok := routes[route.Template]; ok {
+			return route.Template, v
+		}
+	}
+
+	if v, ok := routes[r.URL.Path]; ok {
+		return r.URL.Path, v
+	}
+
+	return "*", fallback
+}
@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	keelhttpcontext "github.com/foomo/keel/net/http/context"
+	"github.com/foomo/keel/net/http/csp"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// cspReportGroup is the Reporting-Endpoints group of the report path
+const cspReportGroup = "csp-endpoint"
+
+type (
+	SecureHeadersOptions struct {
+		// StrictTransportSecurity header value, ignored by browsers on plain http
+		StrictTransportSecurity string
+		// ContentTypeOptions header value
+		ContentTypeOptions string
+		// FrameOptions header value
+		FrameOptions string
+		// ReferrerPolicy header value
+		ReferrerPolicy string
+		// PermissionsPolicy header value
+		PermissionsPolicy string
+		// CrossOriginOpenerPolicy header value
+		CrossOriginOpenerPolicy string
+		// CrossOriginEmbedderPolicy header value, disabled by default since
+		// every embedded cross-origin resource has to opt in
+		CrossOriginEmbedderPolicy string
+		// CrossOriginResourcePolicy header value
+		CrossOriginResourcePolicy string
+		// CSP sent with every response, with a fresh nonce for the csp.Nonce placeholder
+		CSP *csp.Policy
+		// CSPReportOnly sends the CSP as Content-Security-Policy-Report-Only
+		CSPReportOnly bool
+		// ReportPath serves the csp.NewReportHandler and adds it to the CSP
+		ReportPath string
+		// Skippers exclude requests from the headers
+		Skippers []Skipper
+	}
+	SecureHeadersOption func(*SecureHeadersOptions)
+)
+
+// GetDefaultSecureHeadersOptions returns the default options
+func GetDefaultSecureHeadersOptions() SecureHeadersOptions {
+	return SecureHeadersOptions{
+		StrictTransportSecurity:   "max-age=" + strconv.Itoa(int((365 * 24 * time.Hour).Seconds())) + "; includeSubDomains",
+		ContentTypeOptions:        "nosniff",
+		FrameOptions:              "DENY",
+		ReferrerPolicy:            "strict-origin-when-cross-origin",
+		PermissionsPolicy:         "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginResourcePolicy: "same-origin",
+	}
+}
+
+// SecureHeadersWithStrictTransportSecurity middleware option
+func SecureHeadersWithStrictTransportSecurity(maxAge time.Duration, includeSubDomains, preload bool) SecureHeadersOption {
+	return func(o *SecureHeadersOptions) {
+		if maxAge <= 0 {
+			o.StrictTransportSecurity = ""
+			return
+		}
+
+		o.StrictTransportSecurity = "max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+		if includeSubDomains {
+			o.StrictTransportSecurity += "; includeSubDomains"
+		}
+
+		if preload {
+			o.StrictTransportSecurity += "; preload"
+		}
+	}
+}
+
+// SecureHeadersWithContentTypeOptions middleware option
+func SecureHeadersWithContentTypeOptions(v string) SecureHeadersOption {
+	return func(o *SecureHeadersOptions) {
+		o.ContentTypeOptions = v
+	}
+}
+
+// SecureHeadersWithFrameOptions middleware option
+func SecureHeadersWithFrameOptions(v string) SecureHeadersOption {
+	return func(o *SecureHeadersOptions) {
+		o.FrameOptions = v
+	}
+}
+
+// SecureHeadersWithReferrerPolicy middleware option
+func SecureHeadersWithReferrerPolicy(v string) SecureHeadersOption {
+	return func(o *SecureHeadersOptions) {
+		o.ReferrerPolicy = v
+	}
+}
+
+// SecureHeadersWithPermissionsPolicy middleware option
+func SecureHeadersWithPermissionsPolicy(v string) SecureHeadersOption {
+	return func(o *SecureHeadersOptions) {
+		o.PermissionsPolicy = v
+	}
+}
+
+// SecureHeadersWithCrossOriginOpenerPolicy middleware option
+func SecureHeadersWithCrossOriginOpenerPolicy(v string) SecureHeadersOption {
+	return func(o *SecureHeadersOptions) {
+		o.CrossOriginOpenerPolicy = v
+	}
+}
+
+// SecureHeadersWithCrossOriginEmbedderPolicy middleware option
+func SecureHeadersWithCrossOriginEmbedderPolicy(v string) SecureHeadersOption {
+	return func(o *SecureHeadersOptions) {
+		o.CrossOriginEmbedderPolicy = v
+	}
+}
+
+// SecureHeadersWithCrossOriginResourcePolicy middleware option
+func SecureHeadersWithCrossOriginResourcePolicy(v string) SecureHeadersOption {
+	return func(o *SecureHeadersOptions) {
+		o.CrossOriginResourcePolicy = v
+	}
+}
+
+// SecureHeadersWithCSP middleware option
+func SecureHeadersWithCSP(v *csp.Policy) SecureHeadersOption {
+	return func(o *SecureHeadersOptions) {
+		o.CSP = v
+	}
+}
+
+// SecureHeadersWithCSPReportOnly middleware option
+func SecureHeadersWithCSPReportOnly(v bool) SecureHeadersOption {
+	return func(o *SecureHeadersOptions) {
+		o.CSPReportOnly = v
+	}
+}
+
+// SecureHeadersWithReportPath middleware option
+func SecureHeadersWithReportPath(v string) SecureHeadersOption {
+	return func(o *SecureHeadersOptions) {
+		o.ReportPath = v
+	}
+}
+
+// SecureHeadersWithSkippers middleware option
+func SecureHeadersWithSkippers(v ...Skipper) SecureHeadersOption {
+	return func(o *SecureHeadersOptions) {
+		o.Skippers = append(o.Skippers, v...)
+	}
+}
+
+// SecureHeaders middleware
+func SecureHeaders(opts ...SecureHeadersOption) keelhttp.Middleware {
+	options := GetDefaultSecureHeadersOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return SecureHeadersWithOptions(options)
+}
+
+// SecureHeadersWithOptions middleware sets the security headers on every
+// response. If a CSP is configured, a nonce is generated per request,
+// stored in the context for templates and substituted into the policy.
+// With a report path, violation reports sent to it are logged and counted.
+func SecureHeadersWithOptions(opts SecureHeadersOptions) keelhttp.Middleware {
+	var policy *csp.Policy
+	if opts.CSP != nil {
+		policy = opts.CSP.Clone()
+		if opts.ReportPath != "" {
+			policy.ReportURI(opts.ReportPath).ReportTo(cspReportGroup)
+		}
+	}
+
+	cspHeader := keelhttp.HeaderContentSecurityPolicy
+	if opts.CSPReportOnly {
+		cspHeader = keelhttp.HeaderContentSecurityPolicyReportOnly
+	}
+
+	headers := map[string]string{
+		keelhttp.HeaderStrictTransportSecurity:   opts.StrictTransportSecurity,
+		keelhttp.HeaderXContentTypeOptions:       opts.ContentTypeOptions,
+		keelhttp.HeaderXFrameOptions:             opts.FrameOptions,
+		keelhttp.HeaderReferrerPolicy:            opts.ReferrerPolicy,
+		keelhttp.HeaderPermissionsPolicy:         opts.PermissionsPolicy,
+		keelhttp.HeaderCrossOriginOpenerPolicy:   opts.CrossOriginOpenerPolicy,
+		keelhttp.HeaderCrossOriginEmbedderPolicy: opts.CrossOriginEmbedderPolicy,
+		keelhttp.HeaderCrossOriginResourcePolicy: opts.CrossOriginResourcePolicy,
+	}
+	if policy != nil && opts.ReportPath != "" {
+		headers[keelhttp.HeaderReportingEndpoints] = cspReportGroup + `="` + opts.ReportPath + `"`
+	}
+
+	return func(l *zap.Logger, name string, next http.Handler) http.Handler {
+		var reportHandler http.Handler
+		if opts.ReportPath != "" {
+			reportHandler = csp.NewReportHandler(l)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if span.IsRecording() {
+				span.AddEvent("SecureHeaders")
+			}
+
+			if reportHandler != nil && r.URL.Path == opts.ReportPath {
+				reportHandler.ServeHTTP(w, r)
+				return
+			}
+
+			for _, skipper := range opts.Skippers {
+				if skipper(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			h := w.Header()
+			for key, value := range headers {
+				if value != "" {
+					h.Set(key, value)
+				}
+			}
+
+			if policy != nil {
+				nonce := csp.NewNonce()
+				h.Set(cspHeader, policy.String(nonce))
+				r = r.WithContext(keelhttpcontext.SetCSPNonce(r.Context(), nonce))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CSPNonceFromContext helper returns the nonce to be set on inline scripts and styles
+func CSPNonceFromContext(ctx context.Context) string {
+	if value, ok := keelhttpcontext.GetCSPNonce(ctx); ok {
+		return value
+	}
+
+	return ""
+}
@@ -0,0 +1,72 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/csp"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	t.Parallel()
+
+	handler := middleware.SecureHeaders()(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, middleware.CSPNonceFromContext(r.Context()))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	h := w.Header()
+	assert.Equal(t, "max-age=31536000; includeSubDomains", h.Get(keelhttp.HeaderStrictTransportSecurity))
+	assert.Equal(t, "nosniff", h.Get(keelhttp.HeaderXContentTypeOptions))
+	assert.Equal(t, "DENY", h.Get(keelhttp.HeaderXFrameOptions))
+	assert.Equal(t, "strict-origin-when-cross-origin", h.Get(keelhttp.HeaderReferrerPolicy))
+	assert.Equal(t, "same-origin", h.Get(keelhttp.HeaderCrossOriginOpenerPolicy))
+	assert.NotEmpty(t, h.Get(keelhttp.HeaderPermissionsPolicy))
+	assert.Empty(t, h.Get(keelhttp.HeaderCrossOriginEmbedderPolicy))
+	assert.Empty(t, h.Get(keelhttp.HeaderContentSecurityPolicy))
+}
+
+func TestSecureHeadersCSP(t *testing.T) {
+	t.Parallel()
+
+	var nonce string
+
+	handler := middleware.SecureHeaders(
+		middleware.SecureHeadersWithStrictTransportSecurity(0, false, false),
+		middleware.SecureHeadersWithCSP(csp.Default()),
+		middleware.SecureHeadersWithCSPReportOnly(true),
+		middleware.SecureHeadersWithReportPath("/_csp"),
+	)(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = middleware.CSPNonceFromContext(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.NotEmpty(t, nonce)
+	h := w.Header()
+	assert.Empty(t, h.Get(keelhttp.HeaderStrictTransportSecurity))
+	assert.Empty(t, h.Get(keelhttp.HeaderContentSecurityPolicy))
+	policy := h.Get(keelhttp.HeaderContentSecurityPolicyReportOnly)
+	assert.Contains(t, policy, "script-src 'self' 'nonce-"+nonce+"'")
+	assert.Contains(t, policy, "report-uri /_csp; report-to csp-endpoint")
+	assert.Equal(t, `csp-endpoint="/_csp"`, h.Get(keelhttp.HeaderReportingEndpoints))
+
+	// the report endpoint is served by the middleware
+	nonce = ""
+	r := httptest.NewRequest(http.MethodPost, "/_csp", strings.NewReader(`{"csp-report":{"violated-directive":"script-src"}}`))
+	r.Header.Set(keelhttp.HeaderContentType, csp.ContentTypeCSPReport)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, nonce)
+}
@@ -18,7 +18,7 @@ import (
 
 type (
 	SessionIDOptions struct {
-		// Header to look up the session id
+		// Header to look up the session id, ignored if the cookie has a codec
 		Header string
 		// Cookie how to set the cookie
 		Cookie cookie.Cookie
@@ -90,6 +90,15 @@ func SessionIDWithCookie(v cookie.Cookie) SessionIDOption {
 	}
 }
 
+// SessionIDWithCookieCodec middleware option to sign or encrypt the session id cookie.
+// Incoming session id headers are ignored once a codec is set, so the id is no
+// longer propagated between services through the header.
+func SessionIDWithCookieCodec(v cookie.Codec) SessionIDOption {
+	return func(o *SessionIDOptions) {
+		o.Cookie.Codec = v
+	}
+}
+
 // SessionIDWithGenerator middleware option
 func SessionIDWithGenerator(v SessionIDGenerator) SessionIDOption {
 	return func(o *SessionIDOptions) {
@@ -120,17 +129,18 @@ func SessionIDWithOptions(opts SessionIDOptions) keelhttp.Middleware {
 			}
 
 			var sessionID string
-			if value := r.Header.Get(opts.Header); value != "" {
+			// the header would bypass the verification of the cookie codec
+			if value := r.Header.Get(opts.Header); value != "" && opts.Cookie.Codec == nil {
 				sessionID = value
-			} else if c, err := opts.Cookie.Get(r); errors.Is(err, http.ErrNoCookie) && !opts.SetCookie {
+			} else if c, err := opts.Cookie.Get(r); missingCookie(err) && !opts.SetCookie {
 				// do nothing
-			} else if errors.Is(err, http.ErrNoCookie) && opts.SetCookie {
+			} else if missingCookie(err) && opts.SetCookie {
 				sessionID = opts.Generator()
 				if c, err := opts.Cookie.Set(w, r, sessionID); err != nil {
 					httputils.InternalServerError(l, w, r, errors.Wrap(err, "failed to set session id cookie"))
 					return
 				} else {
-					r.AddCookie(c)
+					cookie.SetRequestCookie(r, c)
 				}
 			} else if err != nil {
 				httputils.InternalServerError(l, w, r, errors.Wrap(err, "failed to read session id cookie"))
@@ -164,3 +174,8 @@ func SessionIDFromContext(ctx context.Context) string {
 
 	return ""
 }
+
+// missingCookie returns true if the cookie is missing, forged or expired
+func missingCookie(err error) bool {
+	return errors.Is(err, http.ErrNoCookie) || errors.Is(err, cookie.ErrInvalidValue) || errors.Is(err, cookie.ErrExpiredValue)
+}
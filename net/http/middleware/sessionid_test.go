@@ -0,0 +1,69 @@
+package middleware_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/cookie"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSessionID_cookieCodec(t *testing.T) {
+	t.Parallel()
+
+	keyring, err := cookie.NewKeyring(bytes.Repeat([]byte("a"), 32))
+	require.NoError(t, err)
+
+	handler := keelhttp.Compose(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(middleware.SessionIDFromContext(r.Context())))
+	}), middleware.SessionID(
+		middleware.SessionIDWithSetCookie(true),
+		middleware.SessionIDWithCookieCodec(cookie.NewSignedCodec(keyring)),
+	))
+
+	serve := func(c *http.Cookie, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		if c != nil {
+			r.AddCookie(c)
+		}
+
+		for _, value := range header {
+			r.Header.Set(keelhttp.HeaderXSessionID, value)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	// new session
+	w := serve(nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	sessionID := w.Body.String()
+	assert.NotEmpty(t, sessionID)
+	assert.NotEqual(t, sessionID, cookies[0].Value)
+
+	// existing session
+	w = serve(cookies[0])
+	assert.Equal(t, sessionID, w.Body.String())
+	assert.Empty(t, w.Result().Cookies())
+
+	// forged session ids are replaced
+	w = serve(&http.Cookie{Name: middleware.DefaultSessionIDCookieName, Value: "forged"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, "forged", w.Body.String())
+	assert.Len(t, w.Result().Cookies(), 1)
+
+	// headers can not bypass the codec
+	w = serve(cookies[0], "forged")
+	assert.Equal(t, sessionID, w.Body.String())
+}
@@ -11,6 +11,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
@@ -20,6 +21,9 @@ type (
 		Name                    string
 		OtelOpts                []otelhttp.Option
 		InjectPropagationHeader bool
+		// SpanNameFormatter names the span and renames it once the request
+		// has been routed by keelhttp.Router
+		SpanNameFormatter func(operation string, r *http.Request) string
 	}
 	TelemetryOption func(*TelemetryOptions)
 )
@@ -27,10 +31,12 @@ type (
 // GetDefaultTelemetryOptions returns the default options
 func GetDefaultTelemetryOptions() TelemetryOptions {
 	return TelemetryOptions{
-		OtelOpts: []otelhttp.Option{
-			otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
-				return fmt.Sprintf("HTTP %s %s", r.Method, operation)
-			}),
+		SpanNameFormatter: func(operation string, r *http.Request) string {
+			if route, ok := keelhttp.RouteFromRequest(r); ok && route.Template != "" {
+				return fmt.Sprintf("HTTP %s %s", r.Method, route.Template)
+			}
+
+			return fmt.Sprintf("HTTP %s %s", r.Method, operation)
 		},
 		InjectPropagationHeader: true,
 	}
@@ -61,6 +67,13 @@ func TelemetryWithInjectPropagationHeader(v bool) TelemetryOption {
 	}
 }
 
+// TelemetryWithSpanNameFormatter middleware option
+func TelemetryWithSpanNameFormatter(v func(operation string, r *http.Request) string) TelemetryOption {
+	return func(o *TelemetryOptions) {
+		o.SpanNameFormatter = v
+	}
+}
+
 // TelemetryWithOtelOpts middleware options
 func TelemetryWithOtelOpts(v ...otelhttp.Option) TelemetryOption {
 	return func(o *TelemetryOptions) {
@@ -75,6 +88,11 @@ func TelemetryWithOptions(opts TelemetryOptions) keelhttp.Middleware {
 			name = opts.Name
 		}
 
+		otelOpts := opts.OtelOpts
+		if opts.SpanNameFormatter != nil {
+			otelOpts = append([]otelhttp.Option{otelhttp.WithSpanNameFormatter(opts.SpanNameFormatter)}, otelOpts...)
+		}
+
 		return otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			span := trace.SpanFromContext(r.Context())
 			if span.IsRecording() {
@@ -85,7 +103,8 @@ func TelemetryWithOptions(opts TelemetryOptions) keelhttp.Middleware {
 				otel.GetTextMapPropagator().Inject(r.Context(), propagation.HeaderCarrier(w.Header()))
 			}
 
-			if labeler, ok := otelhttp.LabelerFromContext(r.Context()); ok {
+			labeler, hasLabeler := otelhttp.LabelerFromContext(r.Context())
+			if hasLabeler {
 				labeler.Add(
 					// Deprecated: will be removed
 					attribute.String("http.server_name", name),
@@ -102,7 +121,22 @@ func TelemetryWithOptions(opts TelemetryOptions) keelhttp.Middleware {
 			// wrap response write to get access to status & size
 			wr := WrapResponseWriter(w)
 
+			r, route := keelhttp.InjectRouteIntoRequest(r)
+
 			next.ServeHTTP(wr, r)
-		}), name, opts.OtelOpts...)
+
+			// use the route template instead of the raw path
+			if route.Template != "" {
+				if hasLabeler {
+					labeler.Add(semconv.HTTPRoute(route.Template))
+				}
+
+				span.SetAttributes(semconv.HTTPRoute(route.Template))
+
+				if opts.SpanNameFormatter != nil {
+					span.SetName(opts.SpanNameFormatter(name, r))
+				}
+			}
+		}), name, otelOpts...)
 	}
 }
@@ -19,7 +19,7 @@ import (
 
 type (
 	TrackingIDOptions struct {
-		// Header to look up the tracking id
+		// Header to look up the tracking id, ignored if the cookie has a codec
 		Header string
 		// Cookie how to set the cookie
 		Cookie cookie.Cookie
@@ -91,6 +91,15 @@ func TrackingIDWithCookie(v cookie.Cookie) TrackingIDOption {
 	}
 }
 
+// TrackingIDWithCookieCodec middleware option to sign or encrypt the tracking id cookie.
+// Incoming tracking id headers are ignored once a codec is set, so the id is no
+// longer propagated between services through the header.
+func TrackingIDWithCookieCodec(v cookie.Codec) TrackingIDOption {
+	return func(o *TrackingIDOptions) {
+		o.Cookie.Codec = v
+	}
+}
+
 // TrackingIDWithGenerator middleware option
 func TrackingIDWithGenerator(v TrackingIDGenerator) TrackingIDOption {
 	return func(o *TrackingIDOptions) {
@@ -121,17 +130,18 @@ func TrackingIDWithOptions(opts TrackingIDOptions) keelhttp.Middleware {
 			}
 
 			var tackingID string
-			if value := r.Header.Get(opts.Header); value != "" {
+			// the header would bypass the verification of the cookie codec
+			if value := r.Header.Get(opts.Header); value != "" && opts.Cookie.Codec == nil {
 				tackingID = value
-			} else if c, err := opts.Cookie.Get(r); errors.Is(err, http.ErrNoCookie) && !opts.SetCookie {
+			} else if c, err := opts.Cookie.Get(r); missingCookie(err) && !opts.SetCookie {
 				// do nothing
-			} else if errors.Is(err, http.ErrNoCookie) && opts.SetCookie {
+			} else if missingCookie(err) && opts.SetCookie {
 				tackingID = opts.Generator()
 				if c, err := opts.Cookie.Set(w, r, tackingID); err != nil {
 					httputils.InternalServerError(l, w, r, errors.Wrap(err, "failed to set tracking id cookie"))
 					return
 				} else {
-					r.AddCookie(c)
+					cookie.SetRequestCookie(r, c)
 				}
 			} else if err != nil {
 				httputils.InternalServerError(l, w, r, errors.Wrap(err, "failed to read tracking id cookie"))
@@ -0,0 +1,181 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Algorithm of a Limit
+type Algorithm string
+
+const (
+	// AlgorithmTokenBucket allows bursts of up to Requests which refill
+	// evenly over the Window
+	AlgorithmTokenBucket Algorithm = "tokenBucket"
+	// AlgorithmSlidingWindow allows Requests within any Window, weighting
+	// the previous fixed window by its overlap
+	AlgorithmSlidingWindow Algorithm = "slidingWindow"
+)
+
+type (
+	// Limit allows Requests per Window
+	Limit struct {
+		Algorithm Algorithm
+		Requests  int
+		Window    time.Duration
+	}
+	// State of a key, persisted by the Store between requests
+	State struct {
+		// Tokens left in the bucket
+		Tokens float64 `json:"tokens,omitempty"`
+		// Updated is the time of the last refill
+		Updated time.Time `json:"updated,omitzero"`
+		// WindowStart of the current fixed window
+		WindowStart time.Time `json:"windowStart,omitzero"`
+		// Count of requests in the current window
+		Count int `json:"count,omitempty"`
+		// PrevCount of requests in the previous window
+		PrevCount int `json:"prevCount,omitempty"`
+	}
+	// Result of taking a request
+	Result struct {
+		Allowed bool
+		// Limit of the policy
+		Limit int
+		// Remaining requests
+		Remaining int
+		// Reset is the time until the quota is fully restored
+		Reset time.Duration
+		// RetryAfter is the time until the next request is allowed
+		RetryAfter time.Duration
+	}
+)
+
+// TokenBucket returns a token bucket limit
+func TokenBucket(requests int, window time.Duration) Limit {
+	return Limit{Algorithm: AlgorithmTokenBucket, Requests: requests, Window: window}
+}
+
+// SlidingWindow returns a sliding window limit
+func SlidingWindow(requests int, window time.Duration) Limit {
+	return Limit{Algorithm: AlgorithmSlidingWindow, Requests: requests, Window: window}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// Policy returns the `RateLimit-Policy` header value
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(math.Ceil(l.Window.Seconds())))
+}
+
+// Take takes a request at the given time and updates the state
+func (l Limit) Take(state *State, now time.Time) Result {
+	if l.Requests <= 0 || l.Window <= 0 {
+		return Result{Allowed: false, Limit: l.Requests, RetryAfter: l.Window}
+	}
+
+	if l.Algorithm == AlgorithmSlidingWindow {
+		return l.takeSlidingWindow(state, now)
+	}
+
+	return l.takeTokenBucket(state, now)
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func (l Limit) takeTokenBucket(state *State, now time.Time) Result {
+	capacity := float64(l.Requests)
+	rate := capacity / l.Window.Seconds()
+
+	if state.Updated.IsZero() {
+		state.Tokens = capacity
+	} else if elapsed := now.Sub(state.Updated).Seconds(); elapsed > 0 {
+		state.Tokens = math.Min(capacity, state.Tokens+elapsed*rate)
+	}
+
+	state.Updated = now
+
+	ret := Result{Limit: l.Requests}
+
+	if state.Tokens >= 1 {
+		state.Tokens--
+		ret.Allowed = true
+	} else {
+		ret.RetryAfter = seconds((1 - state.Tokens) / rate)
+	}
+
+	ret.Remaining = int(state.Tokens)
+	ret.Reset = seconds((capacity - state.Tokens) / rate)
+
+	return ret
+}
+
+func (l Limit) takeSlidingWindow(state *State, now time.Time) Result {
+	windowStart := now.Truncate(l.Window)
+
+	if !state.WindowStart.Equal(windowStart) {
+		if state.WindowStart.Equal(windowStart.Add(-l.Window)) {
+			state.PrevCount = state.Count
+		} else {
+			state.PrevCount = 0
+		}
+
+		state.Count = 0
+		state.WindowStart = windowStart
+	}
+
+	elapsed := now.Sub(windowStart)
+	weight := 1 - float64(elapsed)/float64(l.Window)
+	estimate := float64(state.PrevCount)*weight + float64(state.Count)
+
+	ret := Result{Limit: l.Requests}
+
+	if estimate+1 <= float64(l.Requests) {
+		state.Count++
+		estimate++
+		ret.Allowed = true
+	} else {
+		ret.RetryAfter = l.slidingWindowRetryAfter(state, elapsed)
+	}
+
+	ret.Remaining = max(0, l.Requests-int(math.Ceil(estimate)))
+	ret.Reset = l.Window - elapsed
+
+	if state.Count > 0 {
+		// the current window only expires at the end of the next one
+		ret.Reset += l.Window
+	}
+
+	return ret
+}
+
+// slidingWindowRetryAfter returns the time until the weighted previous
+// window has decayed enough to allow another request
+func (l Limit) slidingWindowRetryAfter(state *State, elapsed time.Duration) time.Duration {
+	remaining := l.Window - elapsed
+
+	if state.Count+1 > l.Requests || state.PrevCount == 0 {
+		// wait for the next window in which the current count is weighted
+		next := float64(l.Requests-1) / float64(max(1, state.Count))
+		if next >= 1 {
+			return remaining
+		}
+
+		return remaining + time.Duration((1-next)*float64(l.Window))
+	}
+
+	// weight at which prev * weight + count + 1 <= requests
+	weight := float64(l.Requests-state.Count-1) / float64(state.PrevCount)
+
+	return time.Duration((1-weight)*float64(l.Window)) - elapsed
+}
+
+// seconds converts fractional seconds into a duration
+func seconds(v float64) time.Duration {
+	return time.Duration(v * float64(time.Second))
+}
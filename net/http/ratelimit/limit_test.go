@@ -0,0 +1,98 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/foomo/keel/net/http/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimit_tokenBucket(t *testing.T) {
+	t.Parallel()
+
+	limit := ratelimit.TokenBucket(2, 2*time.Second)
+	now := time.Unix(1000, 0)
+	state := &ratelimit.State{}
+
+	// burst up to the capacity
+	assert.True(t, limit.Take(state, now).Allowed)
+	res := limit.Take(state, now)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 2*time.Second, res.Reset)
+
+	res = limit.Take(state, now)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	// refills one token per second
+	res = limit.Take(state, now.Add(time.Second))
+	assert.True(t, res.Allowed)
+	assert.False(t, limit.Take(state, now.Add(time.Second)).Allowed)
+}
+
+func TestLimit_slidingWindow(t *testing.T) {
+	t.Parallel()
+
+	limit := ratelimit.SlidingWindow(4, 10*time.Second)
+	now := time.Unix(1000, 0)
+	state := &ratelimit.State{}
+
+	for range 4 {
+		assert.True(t, limit.Take(state, now).Allowed)
+	}
+
+	res := limit.Take(state, now.Add(time.Second))
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	// the next window starts in 9s where 4 * weight + 1 <= 4 needs weight <= 0.75
+	assert.Equal(t, 9*time.Second+2500*time.Millisecond, res.RetryAfter)
+
+	// the previous window is weighted by its overlap
+	assert.False(t, limit.Take(state, now.Add(11*time.Second)).Allowed)
+	assert.True(t, limit.Take(state, now.Add(13*time.Second)).Allowed)
+	assert.False(t, limit.Take(state, now.Add(13*time.Second)).Allowed)
+
+	// windows older than the previous one are forgotten
+	res = limit.Take(state, now.Add(time.Minute))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 3, res.Remaining)
+}
+
+func TestLimit_Policy(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "100;w=60", ratelimit.TokenBucket(100, time.Minute).Policy())
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	store := ratelimit.NewMemoryStore(
+		ratelimit.MemoryStoreWithClock(func() time.Time { return now }),
+		ratelimit.MemoryStoreWithCleanupPeriod(time.Second),
+	)
+
+	limit := ratelimit.TokenBucket(1, time.Second)
+
+	res, err := store.Take(t.Context(), "a", limit)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	res, err = store.Take(t.Context(), "a", limit)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+
+	res, err = store.Take(t.Context(), "b", limit)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, store.Len())
+
+	// restored keys are evicted
+	now = now.Add(2 * time.Second)
+	_, err = store.Take(t.Context(), "c", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, store.Len())
+}
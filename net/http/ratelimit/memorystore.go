@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// MemoryStore keeps the state of all keys in memory. Keys are evicted
+	// once their quota is fully restored.
+	MemoryStore struct {
+		clock         func() time.Time
+		cleanupPeriod time.Duration
+		cleanupAt     time.Time
+		states        map[string]*memoryState
+		statesLock    sync.Mutex
+	}
+	MemoryStoreOption func(*MemoryStore)
+	memoryState       struct {
+		State
+
+		expires time.Time
+	}
+)
+
+// MemoryStoreWithClock sets the clock, e.g. for testing
+func MemoryStoreWithClock(v func() time.Time) MemoryStoreOption {
+	return func(o *MemoryStore) {
+		o.clock = v
+	}
+}
+
+// MemoryStoreWithCleanupPeriod sets the interval at which expired keys are evicted
+func MemoryStoreWithCleanupPeriod(v time.Duration) MemoryStoreOption {
+	return func(o *MemoryStore) {
+		o.cleanupPeriod = v
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+func NewMemoryStore(opts ...MemoryStoreOption) *MemoryStore {
+	inst := &MemoryStore{
+		clock:         time.Now,
+		cleanupPeriod: time.Minute,
+		states:        map[string]*memoryState{},
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(inst)
+		}
+	}
+
+	return inst
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// Len returns the number of tracked keys
+func (s *MemoryStore) Len() int {
+	s.statesLock.Lock()
+	defer s.statesLock.Unlock()
+
+	return len(s.states)
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := s.clock()
+
+	s.statesLock.Lock()
+	defer s.statesLock.Unlock()
+
+	s.cleanup(now)
+
+	state, ok := s.states[key]
+	if !ok {
+		state = &memoryState{}
+		s.states[key] = state
+	}
+
+	ret := limit.Take(&state.State, now)
+	state.expires = now.Add(ret.Reset)
+
+	return ret, nil
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// cleanup evicts expired keys once per cleanup period
+func (s *MemoryStore) cleanup(now time.Time) {
+	if now.Before(s.cleanupAt) {
+		return
+	}
+
+	for key, state := range s.states {
+		if !now.Before(state.expires) {
+			delete(s.states, key)
+		}
+	}
+
+	s.cleanupAt = now.Add(s.cleanupPeriod)
+}
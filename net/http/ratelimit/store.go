@@ -0,0 +1,12 @@
+package ratelimit
+
+import (
+	"context"
+)
+
+// Store takes requests for keys. Implementations backed by a shared
+// database allow limiting across replicas and should apply Limit.Take to
+// the persisted State atomically.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -79,9 +79,27 @@ func NewMatcher(cfg Config) (*Matcher, error) {
 // Evaluate classifies the request against the compiled rule set. The
 // 401-vs-403 split is driven by the extractor's authenticated flag.
 func (m *Matcher) Evaluate(extract RolesExtractor, r *http.Request) Decision {
+	return m.evaluate(extract, r, m.match(r.URL.Path))
+}
+
+// EvaluateRoute classifies the request like Evaluate but also considers a
+// rule declared for the route template, e.g. "/users/{id}". The most
+// specific rule wins: an exact path rule, then the template rule, then the
+// longest prefix rule.
+func (m *Matcher) EvaluateRoute(extract RolesExtractor, r *http.Request, template string) Decision {
+	if _, ok := m.exact[r.URL.Path]; !ok && template != "" {
+		if rule, ok := m.exact[template]; ok {
+			return m.evaluate(extract, r, &rule)
+		}
+	}
+
+	return m.Evaluate(extract, r)
+}
+
+// evaluate classifies the request against the matched rule
+func (m *Matcher) evaluate(extract RolesExtractor, r *http.Request, rule *CompiledRule) Decision {
 	roles, authed := extract(r)
 
-	rule := m.match(r.URL.Path)
 	if rule == nil {
 		if m.defaultPolicy == PolicyAllow {
 			return Decision{Outcome: OutcomeNoRuleAllow, Roles: roles, Authed: authed}
@@ -1,6 +1,8 @@
 package rbac_test
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/foomo/keel/net/http/rbac"
@@ -83,3 +85,39 @@ func TestNewMatcher_acceptsExactAndPrefixForSameBase(t *testing.T) {
 	})
 	require.NoError(t, err)
 }
+
+func TestMatcher_EvaluateRoute(t *testing.T) {
+	t.Parallel()
+
+	m, err := rbac.NewMatcher(rbac.Config{
+		DefaultPolicy: rbac.PolicyDeny,
+		Rules: []rbac.Rule{
+			{Path: "/users/{id}", AllowRoles: []string{"user"}},
+			{Path: "/users/admin", AllowRoles: []string{"admin"}},
+			{Path: "/users/*", AllowRoles: []string{"admin"}},
+		},
+	})
+	require.NoError(t, err)
+
+	extract := func(r *http.Request) ([]string, bool) {
+		return []string{"user"}, true
+	}
+
+	tests := []struct {
+		path    string
+		outcome rbac.Outcome
+	}{
+		// the exact path rule wins over the template rule
+		{path: "/users/admin", outcome: rbac.OutcomeDeny},
+		// the template rule wins over the prefix rule
+		{path: "/users/123", outcome: rbac.OutcomeAllow},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			assert.Equal(t, tc.outcome, m.EvaluateRoute(extract, r, "/users/{id}").Outcome)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package roundtripware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	stdhttp "github.com/foomo/gostandards/http"
+	"github.com/foomo/keel/net/http/compress"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type (
+	CompressOptions struct {
+		// Encodings advertised in order of preference
+		Encodings []stdhttp.Encoding
+		// RequestEncoding compresses request bodies, empty disables it
+		RequestEncoding stdhttp.Encoding
+		// Levels by encoding, falling back to the compress.DefaultLevels
+		Levels map[stdhttp.Encoding]int
+		// MinSize of request bodies to compress
+		MinSize int
+	}
+	CompressOption func(*CompressOptions)
+)
+
+// GetDefaultCompressOptions returns the default options
+func GetDefaultCompressOptions() CompressOptions {
+	return CompressOptions{
+		Encodings: compress.DefaultEncodings,
+		MinSize:   1024,
+	}
+}
+
+// CompressWithEncodings roundtripware option
+func CompressWithEncodings(v ...stdhttp.Encoding) CompressOption {
+	return func(o *CompressOptions) {
+		o.Encodings = v
+	}
+}
+
+// CompressWithRequestEncoding roundtripware option
+func CompressWithRequestEncoding(v stdhttp.Encoding) CompressOption {
+	return func(o *CompressOptions) {
+		o.RequestEncoding = v
+	}
+}
+
+// CompressWithLevel roundtripware option sets the level of the underlying library for the encoding
+func CompressWithLevel(encoding stdhttp.Encoding, v int) CompressOption {
+	return func(o *CompressOptions) {
+		if o.Levels == nil {
+			o.Levels = map[stdhttp.Encoding]int{}
+		}
+
+		o.Levels[encoding] = v
+	}
+}
+
+// CompressWithMinSize roundtripware option
+func CompressWithMinSize(v int) CompressOption {
+	return func(o *CompressOptions) {
+		o.MinSize = v
+	}
+}
+
+// CompressWithGZipOptions roundtripware option applies the options of the
+// GZip roundtripware compressing request bodies with gzip
+func CompressWithGZipOptions(v GZipOptions) CompressOption {
+	return func(o *CompressOptions) {
+		CompressWithLevel(stdhttp.EncodingGzip, v.CompressionLevel)(o)
+		o.RequestEncoding = stdhttp.EncodingGzip
+		o.MinSize = v.MinSize
+	}
+}
+
+// Compress returns a RoundTripware which advertises the encodings and
+// transparently decodes the responses unless the caller set the
+// Accept-Encoding header itself
+func Compress(opts ...CompressOption) RoundTripware {
+	o := GetDefaultCompressOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+
+	encoders := compress.NewEncoders(o.Levels)
+
+	return func(l *zap.Logger, next Handler) Handler {
+		return func(r *http.Request) (*http.Response, error) {
+			span := trace.SpanFromContext(r.Context())
+			if span.IsRecording() {
+				span.AddEvent("Compress")
+			}
+
+			if o.RequestEncoding != "" && r.Body != nil && r.Body != http.NoBody &&
+				r.Header.Get(stdhttp.HeaderContentEncoding.String()) == "" && r.ContentLength >= int64(o.MinSize) {
+				if err := compressRequest(encoders, o.RequestEncoding, r); err != nil {
+					return nil, err
+				}
+			}
+
+			decode := r.Header.Get(stdhttp.HeaderAcceptEncoding.String()) == "" && r.Header.Get("Range") == ""
+			if decode {
+				r.Header.Set(stdhttp.HeaderAcceptEncoding.String(), compress.AcceptEncoding(o.Encodings))
+			}
+
+			resp, err := next(r)
+			if err != nil || !decode {
+				return resp, err
+			}
+
+			encoding := stdhttp.Encoding(strings.ToLower(resp.Header.Get(stdhttp.HeaderContentEncoding.String())))
+			if encoding == "" || !compress.Supported(encoding) || resp.Body == nil || resp.Body == http.NoBody {
+				return resp, nil
+			}
+
+			body, err := compress.NewReader(encoding, resp.Body)
+			if err != nil {
+				_ = resp.Body.Close()
+				return nil, err
+			}
+
+			resp.Body = &decodedBody{ReadCloser: body, body: resp.Body}
+			resp.Header.Del(stdhttp.HeaderContentEncoding.String())
+			resp.Header.Del(stdhttp.HeaderContentLength.String())
+			resp.ContentLength = -1
+			resp.Uncompressed = true
+
+			return resp, nil
+		}
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// decodedBody closes the decoder and the underlying body
+type decodedBody struct {
+	io.ReadCloser
+	body io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if bodyErr := b.body.Close(); err == nil {
+		err = bodyErr
+	}
+
+	return err
+}
+
+// compressRequest replaces the request body with its encoded copy
+func compressRequest(encoders *compress.Encoders, encoding stdhttp.Encoding, r *http.Request) error {
+	var buf bytes.Buffer
+
+	writer, err := encoders.Get(encoding, &buf)
+	if err != nil {
+		return err
+	}
+	defer encoders.Put(encoding, writer)
+
+	if _, err := io.Copy(writer, r.Body); err != nil {
+		return errors.Wrap(err, "failed to copy body")
+	}
+
+	if err := writer.Close(); err != nil {
+		return errors.Wrap(err, "failed to close compress writer")
+	}
+
+	if err := r.Body.Close(); err != nil {
+		return errors.Wrap(err, "failed to close request body")
+	}
+
+	body := buf.Bytes()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	r.ContentLength = int64(len(body))
+	r.Header.Set(stdhttp.HeaderContentEncoding.String(), encoding.String())
+	r.Header.Set(stdhttp.HeaderContentLength.String(), strconv.Itoa(len(body)))
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package roundtripware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	stdhttp "github.com/foomo/gostandards/http"
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/compress"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/foomo/keel/net/http/roundtripware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+
+	payload := strings.Repeat("keel ", 1000)
+
+	for _, encoding := range compress.DefaultEncodings {
+		t.Run(encoding.String(), func(t *testing.T) {
+			t.Parallel()
+
+			l := zaptest.NewLogger(t)
+
+			svr := httptest.NewServer(middleware.Compress(
+				middleware.CompressWithEncodings(encoding),
+				middleware.CompressWithDecompressRequests(true),
+			)(zap.NewNop(), "test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "br, zstd, gzip", r.Header.Get(stdhttp.HeaderAcceptEncoding.String()))
+				// request bodies are decoded by the middleware
+				assert.Equal(t, int64(-1), r.ContentLength)
+
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+
+				_, _ = w.Write(body)
+			})))
+			defer svr.Close()
+
+			client := keelhttp.NewHTTPClient(
+				keelhttp.HTTPClientWithRoundTripware(l,
+					roundtripware.Compress(roundtripware.CompressWithRequestEncoding(encoding)),
+				),
+			)
+
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, svr.URL, strings.NewReader(payload))
+			require.NoError(t, err)
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+
+			defer resp.Body.Close()
+
+			assert.True(t, resp.Uncompressed)
+			assert.Empty(t, resp.Header.Get(stdhttp.HeaderContentEncoding.String()))
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, payload, string(body))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"context"
+	"net/http"
+)
+
+// Route describes a route registered on the Router
+type Route struct {
+	// Name of the route
+	Name string
+	// Method of the route pattern, empty for any method
+	Method string
+	// Host of the route pattern, empty for any host
+	Host string
+	// Template of the route path e.g. `/users/{id}`
+	Template string
+}
+
+type routeContextKey struct{}
+
+// Pattern returns the http.ServeMux pattern of the route
+func (r Route) Pattern() string {
+	if r.Method != "" {
+		return r.Method + " " + r.Host + r.Template
+	}
+
+	return r.Host + r.Template
+}
+
+// RouteFromContext returns the route of the request. The route is empty
+// until the request has been matched by the Router.
+func RouteFromContext(ctx context.Context) (*Route, bool) {
+	if value, ok := ctx.Value(routeContextKey{}).(*Route); ok {
+		return value, true
+	}
+
+	return nil, false
+}
+
+// RouteFromRequest returns the route of the request
+func RouteFromRequest(r *http.Request) (*Route, bool) {
+	return RouteFromContext(r.Context())
+}
+
+// InjectRouteIntoContext injects an empty route which the Router fills in
+// once the request has been matched, so that middlewares wrapping the
+// Router can access it after calling the next handler
+func InjectRouteIntoContext(ctx context.Context) (context.Context, *Route) {
+	if value, ok := RouteFromContext(ctx); ok {
+		return ctx, value
+	}
+
+	value := &Route{}
+
+	return context.WithValue(ctx, routeContextKey{}, value), value
+}
+
+// InjectRouteIntoRequest injects an empty route into the request context
+func InjectRouteIntoRequest(r *http.Request) (*http.Request, *Route) {
+	ctx, value := InjectRouteIntoContext(r.Context())
+	if ctx == r.Context() {
+		return r, value
+	}
+
+	return r.WithContext(ctx), value
+}
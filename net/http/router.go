@@ -0,0 +1,182 @@
+package http
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/foomo/keel/log"
+	"github.com/foomo/keel/markdown"
+	httplog "github.com/foomo/keel/net/http/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.uber.org/zap"
+)
+
+type (
+	// Router registers named routes on a http.ServeMux and stores the
+	// matched route in the request context, see RouteFromRequest.
+	//
+	// Routes can be grouped by a path prefix with their own middlewares:
+	//
+	//	r := keelhttp.NewRouter(l, "public")
+	//	r.HandleFunc("health", "GET /health", health)
+	//
+	//	api := r.Group("/api", middleware.RBAC(matcher, extractRoles))
+	//	api.HandleFunc("user", "GET /users/{id}", getUser)
+	Router struct {
+		l           *zap.Logger
+		name        string
+		prefix      string
+		parent      *Router
+		middlewares []Middleware
+		registry    *routerRegistry
+	}
+	routerRegistry struct {
+		mux        *http.ServeMux
+		routes     []Route
+		routesLock sync.RWMutex
+	}
+)
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewRouter returns a router whose middlewares are composed with the given
+// logger and service name
+func NewRouter(l *zap.Logger, name string) *Router {
+	if l == nil {
+		l = log.Logger()
+	}
+
+	return &Router{
+		l:    l,
+		name: name,
+		registry: &routerRegistry{
+			mux: http.NewServeMux(),
+		},
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Getter
+// ------------------------------------------------------------------------------------------------
+
+// Routes returns all registered routes
+func (r *Router) Routes() []Route {
+	r.registry.routesLock.RLock()
+	defer r.registry.routesLock.RUnlock()
+
+	return slices.Clone(r.registry.routes)
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+// Use appends middlewares for all routes registered afterwards
+func (r *Router) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// Group returns a router registering its routes below the path prefix.
+// The group middlewares are wrapped by the ones of r.
+func (r *Router) Group(prefix string, middlewares ...Middleware) *Router {
+	return &Router{
+		l:           r.l,
+		name:        r.name,
+		prefix:      r.prefix + strings.TrimSuffix(prefix, "/"),
+		parent:      r,
+		middlewares: middlewares,
+		registry:    r.registry,
+	}
+}
+
+// Handle registers the handler for the http.ServeMux pattern under the
+// given name. The path of the pattern is prefixed by the group prefix.
+func (r *Router) Handle(name, pattern string, handler http.Handler, middlewares ...Middleware) {
+	route := r.route(name, pattern)
+
+	// route middlewares are wrapped by the group middlewares up to the root
+	handler = Compose(r.l, r.name, handler, middlewares...)
+	for g := r; g != nil; g = g.parent {
+		handler = Compose(r.l, r.name, handler, g.middlewares...)
+	}
+
+	r.registry.mux.Handle(route.Pattern(), http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req, value := InjectRouteIntoRequest(req)
+		*value = route
+
+		if labeler, ok := httplog.LabelerFromRequest(req); ok {
+			labeler.Add(log.Attribute(semconv.HTTPRoute(route.Template)))
+		}
+
+		handler.ServeHTTP(w, req)
+	}))
+
+	r.registry.routesLock.Lock()
+	defer r.registry.routesLock.Unlock()
+
+	r.registry.routes = append(r.registry.routes, route)
+}
+
+// HandleFunc registers the handler func for the pattern under the given name
+func (r *Router) HandleFunc(name, pattern string, handler func(http.ResponseWriter, *http.Request), middlewares ...Middleware) {
+	r.Handle(name, pattern, http.HandlerFunc(handler), middlewares...)
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.registry.mux.ServeHTTP(w, req)
+}
+
+// Readme returns the self-documenting string
+func (r *Router) Readme() string {
+	md := &markdown.Markdown{}
+	routes := r.Routes()
+
+	rows := make([][]string, 0, len(routes))
+	for _, route := range routes {
+		rows = append(rows, []string{
+			markdown.Code(route.Name),
+			markdown.Code(route.Method),
+			markdown.Code(route.Host),
+			markdown.Code(route.Template),
+		})
+	}
+
+	if len(rows) > 0 {
+		md.Println("### Routes")
+		md.Println("")
+		md.Printf("List of all registered routes of `%s`.\n", r.name)
+		md.Println("")
+		md.Table([]string{"Name", "Method", "Host", "Template"}, rows)
+		md.Println("")
+	}
+
+	return md.String()
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// route parses the `[METHOD ][HOST]/[PATH]` pattern and applies the prefix
+func (r *Router) route(name, pattern string) Route {
+	ret := Route{Name: name}
+
+	if method, rest, ok := strings.Cut(strings.TrimSpace(pattern), " "); ok {
+		ret.Method, pattern = method, strings.TrimLeft(rest, " \t")
+	}
+
+	if i := strings.Index(pattern, "/"); i >= 0 {
+		ret.Host, pattern = pattern[:i], pattern[i:]
+	}
+
+	ret.Template = r.prefix + pattern
+	if ret.Template == "" {
+		ret.Template = "/"
+	}
+
+	return ret
+}
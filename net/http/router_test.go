@@ -0,0 +1,112 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	keelhttp "github.com/foomo/keel/net/http"
+	"github.com/foomo/keel/net/http/middleware"
+	"github.com/foomo/keel/net/http/rbac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+
+	mw := func(v string) keelhttp.Middleware {
+		return func(l *zap.Logger, name string, next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, v)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	router := keelhttp.NewRouter(zap.NewNop(), "test")
+	router.Use(mw("root"))
+	api := router.Group("/api/", mw("api"))
+	api.HandleFunc("user", "GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		route, ok := keelhttp.RouteFromRequest(r)
+		require.True(t, ok)
+		_, _ = w.Write([]byte(route.Name + " " + route.Template + " " + r.PathValue("id")))
+	}, mw("route"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/42", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user /api/users/{id} 42", w.Body.String())
+	assert.Equal(t, []string{"root", "api", "route"}, calls)
+	assert.Equal(t, []keelhttp.Route{{Name: "user", Method: http.MethodGet, Template: "/api/users/{id}"}}, router.Routes())
+	assert.Contains(t, router.Readme(), "`/api/users/{id}`")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/users/42", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestRouter_telemetry(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	core, logs := observer.New(zap.InfoLevel)
+	l := zap.New(core)
+
+	router := keelhttp.NewRouter(l, "test")
+	router.HandleFunc("user", "GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	handler := keelhttp.Compose(l, "test", router,
+		middleware.Telemetry(middleware.TelemetryWithOtelOpts(otelhttp.WithTracerProvider(tp))),
+		middleware.Logger(),
+	)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "HTTP GET /users/{id}", spans[0].Name())
+
+	entries := logs.FilterMessage("handled http request").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "/users/{id}", entries[0].ContextMap()["http_route"])
+}
+
+func TestRouter_rbac(t *testing.T) {
+	t.Parallel()
+
+	matcher, err := rbac.NewMatcher(rbac.Config{
+		DefaultPolicy: rbac.PolicyAllow,
+		Rules: []rbac.Rule{
+			{Path: "/users/{id}", AllowRoles: []string{"admin"}},
+		},
+	})
+	require.NoError(t, err)
+
+	router := keelhttp.NewRouter(zap.NewNop(), "test")
+	router.Use(middleware.RBAC(matcher, func(r *http.Request) ([]string, bool) {
+		return []string{r.Header.Get("Role")}, true
+	}))
+	router.HandleFunc("user", "GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	r := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	r.Header.Set("Role", "admin")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
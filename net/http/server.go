@@ -7,15 +7,56 @@ import (
 	"go.uber.org/zap"
 )
 
-// NewServer creates and configures an HTTP server with the provided logger, name, address, handler, and middlewares.
-func NewServer(l *zap.Logger, name, addr string, handler http.Handler, middlewares ...Middleware) *http.Server {
-	return &http.Server{
-		Addr:           addr,
-		Handler:        Compose(l, name, handler, middlewares...),
-		ErrorLog:       zap.NewStdLog(l),
+// ServerOptions configures the http.Server limits
+type ServerOptions struct {
+	// ReadTimeout for reading the entire request including the body
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout for reading the request headers, defaults to ReadTimeout if 0
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout for writing the response
+	WriteTimeout time.Duration
+	// IdleTimeout for keep-alive connections
+	IdleTimeout time.Duration
+	// MaxHeaderBytes limits the size of the request headers
+	MaxHeaderBytes int
+	// H2C enables HTTP/2 over cleartext connections
+	H2C bool
+}
+
+// GetDefaultServerOptions returns the default options
+func GetDefaultServerOptions() ServerOptions {
+	return ServerOptions{
 		ReadTimeout:    30 * time.Second,
 		WriteTimeout:   60 * time.Second,
 		IdleTimeout:    30 * time.Second,
 		MaxHeaderBytes: 1 << 20,
 	}
 }
+
+// NewServer creates and configures an HTTP server with the provided logger, name, address, handler, and middlewares.
+func NewServer(l *zap.Logger, name, addr string, handler http.Handler, middlewares ...Middleware) *http.Server {
+	return NewServerWithOptions(l, name, addr, handler, GetDefaultServerOptions(), middlewares...)
+}
+
+// NewServerWithOptions creates and configures an HTTP server like NewServer with the given options.
+func NewServerWithOptions(l *zap.Logger, name, addr string, handler http.Handler, opts ServerOptions, middlewares ...Middleware) *http.Server {
+	inst := &http.Server{
+		Addr:              addr,
+		Handler:           Compose(l, name, handler, middlewares...),
+		ErrorLog:          zap.NewStdLog(l),
+		ReadTimeout:       opts.ReadTimeout,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+		MaxHeaderBytes:    opts.MaxHeaderBytes,
+	}
+
+	if opts.H2C {
+		inst.Protocols = new(http.Protocols)
+		inst.Protocols.SetHTTP1(true)
+		inst.Protocols.SetHTTP2(true)
+		inst.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	return inst
+}
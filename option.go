@@ -12,7 +12,9 @@ import (
 
 	"github.com/foomo/keel/config"
 	"github.com/foomo/keel/env"
+	"github.com/foomo/keel/flags"
 	"github.com/foomo/keel/log"
+	"github.com/foomo/keel/net/http/cache"
 	"github.com/foomo/keel/telemetry"
 )
 
@@ -71,6 +73,31 @@ func WithGracefulPeriod(gracefulPeriod time.Duration) Option {
 	}
 }
 
+// WithUpgrade option enables the zero-downtime binary upgrade: on the
+// upgrade signal the current executable is started with the listeners of
+// all services and once its probes pass, this server shuts down gracefully
+func WithUpgrade(enabled bool, opts ...UpgradeOption) Option {
+	return func(inst *Server) {
+		if !config.GetBool(inst.Config(), "upgrade.enabled", enabled)() {
+			return
+		}
+
+		o := GetDefaultUpgradeOptions()
+		o.ReadyTimeout = config.GetDuration(inst.Config(), "upgrade.readyTimeout", o.ReadyTimeout)()
+
+		for _, opt := range opts {
+			opt(&o)
+		}
+
+		if o.Signal == nil {
+			log.WithError(inst.Logger(), ErrUpgradeUnsupported).Warn("keel upgrade disabled")
+			return
+		}
+
+		inst.upgrade = &o
+	}
+}
+
 // WithHTTPZapService option with default value
 func WithHTTPZapService(enabled bool) Option {
 	return func(inst *Server) {
@@ -353,10 +380,39 @@ func WithHTTPPProfService(enabled bool) Option {
 }
 
 // WithHTTPHealthzService option with default value
-func WithHTTPHealthzService(enabled bool) Option {
+func WithHTTPHealthzService(enabled bool, opts ...service.HealthzOption) Option {
 	return func(inst *Server) {
 		if config.GetBool(inst.Config(), "service.healthz.enabled", enabled)() {
-			svs := service.NewDefaultHTTPProbes(inst.Logger(), inst.probes())
+			svs := service.NewDefaultHTTPProbesWithChecker(inst.Logger(), inst.checker(opts...))
+			inst.initServices = append(inst.initServices, svs)
+			inst.AddAlwaysHealthzers(svs)
+		}
+	}
+}
+
+// WithGRPCHealthzService option with default value
+func WithGRPCHealthzService(enabled bool, opts ...service.GRPCHealthzOption) Option {
+	return func(inst *Server) {
+		if config.GetBool(inst.Config(), "service.grpcHealthz.enabled", enabled)() {
+			options := service.GetDefaultGRPCHealthzOptions()
+			for _, opt := range opts {
+				if opt != nil {
+					opt(&options)
+				}
+			}
+
+			// share the checker with the http healthz service
+			if options.Checker == nil {
+				opts = append(opts, service.GRPCHealthzWithChecker(inst.checker(options.HealthzOptions...)))
+			}
+
+			svs := service.NewGRPCHealthz(
+				inst.Logger(),
+				service.DefaultGRPCHealthzName,
+				config.GetString(inst.Config(), "service.grpcHealthz.addr", service.DefaultGRPCHealthzAddr)(),
+				inst.probes(),
+				opts...,
+			)
 			inst.initServices = append(inst.initServices, svs)
 			inst.AddAlwaysHealthzers(svs)
 		}
@@ -374,6 +430,30 @@ func WithHTTPReadmeService(enabled bool) Option {
 	}
 }
 
+// WithHTTPFlagsService option with default value
+func WithHTTPFlagsService(enabled bool, f *flags.Flags) Option {
+	return func(inst *Server) {
+		if config.GetBool(inst.Config(), "service.flags.enabled", enabled)() {
+			svs := service.NewDefaultHTTPFlags(inst.Logger(), f)
+			inst.initServices = append(inst.initServices, svs)
+			inst.AddAlwaysHealthzers(svs)
+		}
+
+		inst.AddReadmer(f)
+	}
+}
+
+// WithHTTPCacheService option with default value
+func WithHTTPCacheService(enabled bool, store cache.Store) Option {
+	return func(inst *Server) {
+		if config.GetBool(inst.Config(), "service.cache.enabled", enabled)() {
+			svs := service.NewDefaultHTTPCache(inst.Logger(), store)
+			inst.initServices = append(inst.initServices, svs)
+			inst.AddAlwaysHealthzers(svs)
+		}
+	}
+}
+
 // WithInitService option with default value
 func WithInitService(service Service) Option {
 	return func(inst *Server) {
@@ -0,0 +1,151 @@
+package keelmongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const DefaultJWTRevocationCollection = "keel_jwt_revocations"
+
+type (
+	// JWTRevocationStore implements the jwt.RevocationStore on a collection.
+	// Expired revocations are removed by a ttl index.
+	JWTRevocationStore struct {
+		collection *Collection
+		clock      func() time.Time
+	}
+	JWTRevocationStoreOption  func(*JWTRevocationStoreOptions)
+	JWTRevocationStoreOptions struct {
+		Collection        string
+		CollectionOptions []CollectionOption
+		Clock             func() time.Time
+	}
+)
+
+// JWTRevocationStoreWithCollection sets the collection name
+func JWTRevocationStoreWithCollection(v string) JWTRevocationStoreOption {
+	return func(o *JWTRevocationStoreOptions) {
+		o.Collection = v
+	}
+}
+
+// JWTRevocationStoreWithCollectionOptions sets additional collection options
+func JWTRevocationStoreWithCollectionOptions(v ...CollectionOption) JWTRevocationStoreOption {
+	return func(o *JWTRevocationStoreOptions) {
+		o.CollectionOptions = append(o.CollectionOptions, v...)
+	}
+}
+
+// JWTRevocationStoreWithClock sets the clock, e.g. for testing
+func JWTRevocationStoreWithClock(v func() time.Time) JWTRevocationStoreOption {
+	return func(o *JWTRevocationStoreOptions) {
+		o.Clock = v
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewJWTRevocationStore returns a new store and creates its ttl index
+func NewJWTRevocationStore(p *Persistor, opts ...JWTRevocationStoreOption) (*JWTRevocationStore, error) {
+	o := JWTRevocationStoreOptions{
+		Collection: DefaultJWTRevocationCollection,
+		Clock:      time.Now,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+
+	collection, err := p.Collection(o.Collection, append([]CollectionOption{
+		CollectionWithIndexes(mongo.IndexModel{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0),
+		}),
+	}, o.CollectionOptions...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWTRevocationStore{
+		collection: collection,
+		clock:      o.Clock,
+	}, nil
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (s *JWTRevocationStore) RevokeID(ctx context.Context, id string, expiresAt time.Time) error {
+	_, err := s.collection.Col().UpdateOne(ctx,
+		bson.M{"_id": "jti:" + id},
+		bson.M{"$max": bson.M{"expiresAt": expiresAt}},
+		options.UpdateOne().SetUpsert(true),
+	)
+
+	return err
+}
+
+// RevokeSubject truncates issuedBefore to the second precision of the iat claim
+func (s *JWTRevocationStore) RevokeSubject(ctx context.Context, subject string, issuedBefore, expiresAt time.Time) error {
+	_, err := s.collection.Col().UpdateOne(ctx,
+		bson.M{"_id": "sub:" + subject},
+		bson.M{"$max": bson.M{"issuedBefore": issuedBefore.Truncate(time.Second), "expiresAt": expiresAt}},
+		options.UpdateOne().SetUpsert(true),
+	)
+
+	return err
+}
+
+func (s *JWTRevocationStore) Revoked(ctx context.Context, id, subject string, issuedAt time.Time) (bool, error) {
+	var or bson.A
+	if id != "" {
+		or = append(or, bson.M{"_id": "jti:" + id})
+	}
+
+	// tokens without iat can not prove to be issued after the revocation
+	if subject != "" && issuedAt.IsZero() {
+		or = append(or, bson.M{"_id": "sub:" + subject})
+	} else if subject != "" {
+		or = append(or, bson.M{"_id": "sub:" + subject, "issuedBefore": bson.M{"$gt": issuedAt}})
+	}
+
+	if len(or) == 0 {
+		return false, nil
+	}
+
+	// the ttl monitor runs periodically so expired documents may still exist
+	n, err := s.collection.Col().CountDocuments(ctx,
+		bson.M{"$or": or, "expiresAt": bson.M{"$gt": s.clock()}},
+		options.Count().SetLimit(1),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (s *JWTRevocationStore) Consume(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	// only an expired document matches, an existing one fails the upsert with a duplicate key
+	_, err := s.collection.Col().UpdateOne(ctx,
+		bson.M{"_id": "use:" + id, "expiresAt": bson.M{"$lte": s.clock()}},
+		bson.M{"$set": bson.M{"expiresAt": expiresAt}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,156 @@
+package keelpostgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const DefaultJWTRevocationTable = "keel_jwt_revocations"
+
+type (
+	// JWTRevocationStore implements the jwt.RevocationStore on a postgres table.
+	// Expired revocations are removed by calling Cleanup.
+	JWTRevocationStore struct {
+		db    *sql.DB
+		table string
+		clock func() time.Time
+	}
+	JWTRevocationStoreOption func(*JWTRevocationStore)
+)
+
+// JWTRevocationStoreWithTable sets the table name
+func JWTRevocationStoreWithTable(v string) JWTRevocationStoreOption {
+	return func(o *JWTRevocationStore) {
+		o.table = v
+	}
+}
+
+// JWTRevocationStoreWithClock sets the clock, e.g. for testing
+func JWTRevocationStoreWithClock(v func() time.Time) JWTRevocationStoreOption {
+	return func(o *JWTRevocationStore) {
+		o.clock = v
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewJWTRevocationStore returns a new store and creates its table if missing
+func NewJWTRevocationStore(ctx context.Context, p *Persistor, opts ...JWTRevocationStoreOption) (*JWTRevocationStore, error) {
+	inst := &JWTRevocationStore{
+		db:    p.DB(),
+		table: DefaultJWTRevocationTable,
+		clock: time.Now,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(inst)
+		}
+	}
+
+	table := pq.QuoteIdentifier(inst.table)
+	if _, err := inst.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+table+` (
+		key TEXT PRIMARY KEY,
+		issued_before TIMESTAMPTZ,
+		expires_at TIMESTAMPTZ NOT NULL
+	)`); err != nil {
+		return nil, errors.Wrap(err, "failed to create jwt revocation table")
+	}
+
+	if _, err := inst.db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS `+pq.QuoteIdentifier(inst.table+"_expires_at")+` ON `+table+` (expires_at)`); err != nil {
+		return nil, errors.Wrap(err, "failed to create jwt revocation index")
+	}
+
+	return inst, nil
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (s *JWTRevocationStore) RevokeID(ctx context.Context, id string, expiresAt time.Time) error {
+	table := pq.QuoteIdentifier(s.table)
+	_, err := s.db.ExecContext(ctx, `INSERT INTO `+table+` (key, expires_at) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET expires_at = GREATEST(`+table+`.expires_at, EXCLUDED.expires_at)`,
+		"jti:"+id, expiresAt,
+	)
+
+	return err
+}
+
+// RevokeSubject truncates issuedBefore to the second precision of the iat claim
+func (s *JWTRevocationStore) RevokeSubject(ctx context.Context, subject string, issuedBefore, expiresAt time.Time) error {
+	table := pq.QuoteIdentifier(s.table)
+	_, err := s.db.ExecContext(ctx, `INSERT INTO `+table+` (key, issued_before, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET
+			issued_before = GREATEST(`+table+`.issued_before, EXCLUDED.issued_before),
+			expires_at = GREATEST(`+table+`.expires_at, EXCLUDED.expires_at)`,
+		"sub:"+subject, issuedBefore.Truncate(time.Second), expiresAt,
+	)
+
+	return err
+}
+
+func (s *JWTRevocationStore) Revoked(ctx context.Context, id, subject string, issuedAt time.Time) (bool, error) {
+	var idKey, subjectKey, iat sql.NullString
+	if id != "" {
+		idKey = sql.NullString{String: "jti:" + id, Valid: true}
+	}
+
+	if subject != "" {
+		subjectKey = sql.NullString{String: "sub:" + subject, Valid: true}
+	}
+
+	if !issuedAt.IsZero() {
+		iat = sql.NullString{String: issuedAt.UTC().Format(time.RFC3339Nano), Valid: true}
+	}
+
+	// tokens without iat can not prove to be issued after the revocation
+	var n int64
+	if err := s.db.QueryRowContext(ctx, `SELECT 1 FROM `+pq.QuoteIdentifier(s.table)+`
+		WHERE expires_at > $1 AND (key = $2 OR (key = $3 AND ($4::timestamptz IS NULL OR issued_before > $4::timestamptz)))
+		LIMIT 1`,
+		s.clock(), idKey, subjectKey, iat,
+	).Scan(&n); errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *JWTRevocationStore) Consume(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	table := pq.QuoteIdentifier(s.table)
+	// only an expired entry may be taken over
+	res, err := s.db.ExecContext(ctx, `INSERT INTO `+table+` (key, expires_at) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET expires_at = EXCLUDED.expires_at WHERE `+table+`.expires_at <= $3`,
+		"use:"+id, expiresAt, s.clock(),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// Cleanup deletes the expired revocations and returns their number
+func (s *JWTRevocationStore) Cleanup(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM `+pq.QuoteIdentifier(s.table)+` WHERE expires_at <= $1`, s.clock())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
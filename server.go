@@ -9,6 +9,8 @@ import (
 	"os/signal"
 	"reflect"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -55,6 +57,8 @@ type Server struct {
 	shutdownSignals []os.Signal
 	// gracefulPeriod should equal the terminationGracePeriodSeconds
 	gracefulPeriod   time.Duration
+	upgrade          *UpgradeOptions
+	upgrading        atomic.Bool
 	running          atomic.Bool
 	syncClosers      []any
 	syncClosersLock  sync.RWMutex
@@ -62,6 +66,7 @@ type Server struct {
 	syncReadmersLock sync.RWMutex
 	syncProbes       map[healthz.Type][]any
 	syncProbesLock   sync.RWMutex
+	healthzChecker   *service.HealthzChecker
 	ctx              context.Context
 	cancel           context.CancelFunc
 	gracefulCtx      context.Context
@@ -207,8 +212,39 @@ func (s *Server) AddService(v Service) {
 }
 
 func (s *Server) AddHTTPService(name, addr string, handler http.Handler, middleware ...keelhttp.Middleware) {
+	s.AddHTTPServiceWithOptions(name, addr, handler, service.HTTPOptions{
+		Middlewares: middleware,
+	})
+}
+
+// AddHTTPServiceWithOptions adds a http service whose options can be
+// overridden through the `service.http.<name>` config keys
+func (s *Server) AddHTTPServiceWithOptions(name, addr string, handler http.Handler, opts service.HTTPOptions) {
 	addrFn := config.GetString(s.Config(), "service.http."+name+".addr", addr)
-	s.AddService(service.NewHTTP(s.l, name, addrFn(), handler, middleware...))
+
+	tlsOpts, err := s.httpTLSOptions(name, opts.TLS)
+	log.Must(s.l, err, "failed to configure tls")
+
+	opts.TLS = tlsOpts
+
+	opts.Server = s.httpServerOptions(name, opts.Server)
+
+	listenerOpts, err := s.httpListenerOptions(name, opts.Listener)
+	log.Must(s.l, err, "failed to configure listener")
+
+	opts.Listener = listenerOpts
+
+	svs := service.NewHTTPWithOptions(s.l, name, addrFn(), handler, opts)
+	s.AddService(svs)
+
+	// list the registered routes
+	if router, ok := handler.(*keelhttp.Router); ok {
+		s.AddReadmer(router)
+	}
+
+	if svs.TLS() != nil {
+		s.AddReadinessHealthzers(svs.TLS())
+	}
 }
 
 func (s *Server) AddInternalHTTPService(handler http.Handler, middleware ...keelhttp.Middleware) {
@@ -298,6 +334,11 @@ func (s *Server) AddReadinessHealthzers(probes ...any) {
 	s.AddHealthzers(healthz.TypeReadiness, probes...)
 }
 
+// AddDegradedHealthzers adds the non-critical probes to be called on healthz checks
+func (s *Server) AddDegradedHealthzers(probes ...any) {
+	s.AddHealthzers(healthz.TypeDegraded, probes...)
+}
+
 // Healthz returns true if the server is running
 func (s *Server) Healthz() error {
 	if !s.running.Load() {
@@ -336,6 +377,19 @@ func (s *Server) Run() {
 
 	s.running.Store(true)
 
+	// upgrade binary on signal
+	if s.upgrade != nil {
+		s.g.Go(func() error {
+			s.watchUpgrade()
+			return nil
+		})
+	}
+
+	// notify the parent process if started by an upgrade
+	if w := upgradeReadyPipe(); w != nil {
+		go s.notifyUpgradeReady(s.gracefulCtx, w)
+	}
+
 	// wait for shutdown
 	if err := s.g.Wait(); errors.Is(err, ErrServerShutdown) {
 		s.l.Info("keel server stopped")
@@ -379,6 +433,22 @@ func (s *Server) probes() map[healthz.Type][]any {
 	return s.syncProbes
 }
 
+// checker returns the healthz checker shared by the healthz services. It is
+// created with the config and the options of the first service asking for it.
+func (s *Server) checker(opts ...service.HealthzOption) *service.HealthzChecker {
+	if s.healthzChecker == nil {
+		defaults := service.GetDefaultHealthzOptions()
+		s.healthzChecker = service.NewHealthzChecker(s.Logger(), s.probes(), append([]service.HealthzOption{
+			service.HealthzWithTimeout(config.GetDuration(s.Config(), "service.healthz.timeout", defaults.Timeout)()),
+			service.HealthzWithConcurrent(config.GetBool(s.Config(), "service.healthz.concurrent", defaults.Concurrent)()),
+			service.HealthzWithInterval(config.GetDuration(s.Config(), "service.healthz.interval", defaults.Interval)()),
+			service.HealthzWithFailureThreshold(config.GetInt(s.Config(), "service.healthz.failureThreshold", defaults.FailureThreshold)()),
+		}, opts...)...)
+	}
+
+	return s.healthzChecker
+}
+
 func (s *Server) addProbes(typ healthz.Type, v ...any) {
 	s.syncProbesLock.Lock()
 	defer s.syncProbesLock.Unlock()
@@ -413,6 +483,103 @@ func (s *Server) startService(services ...Service) {
 	close(done)
 }
 
+// httpServerOptions reads the `service.http.<name>` timeout and limit
+// config keys on top of the given options
+func (s *Server) httpServerOptions(name string, opts *keelhttp.ServerOptions) *keelhttp.ServerOptions {
+	c := s.Config()
+	prefix := "service.http." + name + "."
+
+	ret := keelhttp.GetDefaultServerOptions()
+	if opts != nil {
+		ret = *opts
+	}
+
+	ret.ReadTimeout = config.GetDuration(c, prefix+"readTimeout", ret.ReadTimeout)()
+	ret.ReadHeaderTimeout = config.GetDuration(c, prefix+"readHeaderTimeout", ret.ReadHeaderTimeout)()
+	ret.WriteTimeout = config.GetDuration(c, prefix+"writeTimeout", ret.WriteTimeout)()
+	ret.IdleTimeout = config.GetDuration(c, prefix+"idleTimeout", ret.IdleTimeout)()
+	ret.MaxHeaderBytes = config.GetInt(c, prefix+"maxHeaderBytes", ret.MaxHeaderBytes)()
+	ret.H2C = config.GetBool(c, prefix+"h2c", ret.H2C)()
+
+	return &ret
+}
+
+// httpListenerOptions reads the `service.http.<name>.listener` config keys
+// on top of the given options
+func (s *Server) httpListenerOptions(name string, opts service.ListenerOptions) (service.ListenerOptions, error) {
+	c := s.Config()
+	prefix := "service.http." + name + ".listener."
+
+	opts.Network = config.GetString(c, prefix+"network", opts.Network)()
+	opts.SocketActivation = config.GetBool(c, prefix+"socketActivation", opts.SocketActivation)()
+	opts.SocketActivationName = config.GetString(c, prefix+"socketActivationName", opts.SocketActivationName)()
+	opts.ReusePort = config.GetBool(c, prefix+"reusePort", opts.ReusePort)()
+	opts.MaxConns = config.GetInt(c, prefix+"maxConns", opts.MaxConns)()
+	opts.MaxConnsPerIP = config.GetInt(c, prefix+"maxConnsPerIP", opts.MaxConnsPerIP)()
+
+	if v := config.GetString(c, prefix+"unixSocketMode", "")(); v != "" {
+		mode, err := strconv.ParseUint(v, 8, 32)
+		if err != nil {
+			return opts, fmt.Errorf("keel: invalid unix socket mode %q: %w", v, err)
+		}
+
+		opts.UnixSocketMode = os.FileMode(mode)
+	}
+
+	return opts, nil
+}
+
+// httpTLSOptions reads the `service.http.<name>.tls` config keys on top of
+// the given options. TLS is enabled if a certificate file is configured.
+func (s *Server) httpTLSOptions(name string, opts *service.TLSOptions) (*service.TLSOptions, error) {
+	c := s.Config()
+	prefix := "service.http." + name + ".tls."
+
+	ret := service.GetDefaultTLSOptions()
+	if opts != nil {
+		ret = *opts
+	}
+
+	ret.CertFile = config.GetString(c, prefix+"certFile", ret.CertFile)()
+	if ret.CertFile == "" {
+		return opts, nil
+	}
+
+	ret.KeyFile = config.GetString(c, prefix+"keyFile", ret.KeyFile)()
+	ret.ClientCAFile = config.GetString(c, prefix+"clientCAFile", ret.ClientCAFile)()
+	ret.ReloadInterval = config.GetDuration(c, prefix+"reloadInterval", ret.ReloadInterval)()
+	ret.ExpiryThreshold = config.GetDuration(c, prefix+"expiryThreshold", ret.ExpiryThreshold)()
+
+	if v := config.GetString(c, prefix+"clientAuth", "")(); v != "" {
+		clientAuth, err := service.ParseTLSClientAuth(v)
+		if err != nil {
+			return nil, err
+		}
+
+		ret.ClientAuth = clientAuth
+	}
+
+	if v := config.GetString(c, prefix+"minVersion", "")(); v != "" {
+		minVersion, err := service.ParseTLSVersion(v)
+		if err != nil {
+			return nil, err
+		}
+
+		ret.MinVersion = minVersion
+	}
+
+	if v := config.GetStringSlice(c, prefix+"cipherSuites", nil)(); len(v) > 0 {
+		cipherSuites, err := service.ParseTLSCipherSuites(v)
+		if err != nil {
+			return nil, err
+		}
+
+		ret.CipherSuites = cipherSuites
+	}
+
+	return &ret, nil
+}
+
 func (s *Server) readmeCloser() string {
 	md := &markdown.Markdown{}
 	closers := s.closers()
@@ -500,6 +667,9 @@ func (s *Server) readmeHealthz() string {
 		md.Println("")
 		md.Println("List of all registered healthz probes that are being called during startup and runtime.")
 		md.Println("")
+		md.Println("- `startup` probes latch: once succeeded they are not called again")
+		md.Println("- `degraded` probes are reported on readiness checks but never fail them; `/healthz/degraded` fails instead")
+		md.Println("")
 		md.Table([]string{"Name", "Probe", "Type", "Description"}, rows)
 	}
 
@@ -513,12 +683,13 @@ func (s *Server) readmeServices() string {
 		var rows [][]string
 
 		for _, value := range s.initServices {
-			if v, ok := value.(*service.HTTP); ok {
-				t := reflect.TypeFor[*service.HTTP]()
+			switch v := value.(type) {
+			case *service.HTTP, *service.GRPCHealthz:
 				rows = append(rows, []string{
-					markdown.Code(v.Name()),
-					markdown.Code(t.String()),
+					markdown.Code(markdown.Name(v)),
+					markdown.Code(reflect.TypeOf(v).String()),
 					markdown.String(v),
+					readmeServiceSettings(v),
 				})
 			}
 		}
@@ -528,7 +699,7 @@ func (s *Server) readmeServices() string {
 			md.Println("")
 			md.Println("List of all registered init services that are being immediately started.")
 			md.Println("")
-			md.Table([]string{"Name", "Type", "Address"}, rows)
+			md.Table([]string{"Name", "Type", "Address", "Settings"}, rows)
 		}
 	}
 
@@ -543,6 +714,7 @@ func (s *Server) readmeServices() string {
 				markdown.Code(t.Name()),
 				markdown.Code(t.String()),
 				markdown.String(value),
+				readmeServiceSettings(value),
 			})
 		}
 
@@ -551,9 +723,32 @@ func (s *Server) readmeServices() string {
 			md.Println("")
 			md.Println("List of all registered services that are being started.")
 			md.Println("")
-			md.Table([]string{"Name", "Type", "Description"}, rows)
+			md.Table([]string{"Name", "Type", "Description", "Settings"}, rows)
 		}
 	}
 
 	return md.String()
 }
+
+// readmeServiceSettings returns the effective http.Server settings of http services
+func readmeServiceSettings(v any) string {
+	svs, ok := v.(*service.HTTP)
+	if !ok {
+		return ""
+	}
+
+	srv := svs.Server()
+	settings := []string{
+		"read: " + markdown.Code(srv.ReadTimeout.String()),
+		"readHeader: " + markdown.Code(srv.ReadHeaderTimeout.String()),
+		"write: " + markdown.Code(srv.WriteTimeout.String()),
+		"idle: " + markdown.Code(srv.IdleTimeout.String()),
+		"maxHeaderBytes: " + markdown.Code(strconv.Itoa(srv.MaxHeaderBytes)),
+	}
+
+	if srv.Protocols != nil && srv.Protocols.UnencryptedHTTP2() {
+		settings = append(settings, "h2c")
+	}
+
+	return strings.Join(settings, ", ")
+}
@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/foomo/keel/healthz"
+	"github.com/foomo/keel/log"
+	keelsemconv "github.com/foomo/keel/semconv"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	DefaultGRPCHealthzName = "healthz-grpc"
+	DefaultGRPCHealthzAddr = ":9401"
+)
+
+type (
+	// GRPCHealthz serves the gRPC Health Checking Protocol backed by the
+	// healthz probes.
+	//
+	// Service names map to the same checks as the http healthz service:
+	//
+	//	""           all probes except startup
+	//	"liveness"   always and liveness probes
+	//	"readiness"  always, readiness and degraded probes
+	//	"startup"    always and startup probes
+	//	"degraded"   degraded probes only, NOT_SERVING if any is degraded
+	GRPCHealthz struct {
+		healthpb.UnimplementedHealthServer
+
+		l        *zap.Logger
+		name     string
+		addr     string
+		opts     GRPCHealthzOptions
+		server   *grpc.Server
+		checker  *HealthzChecker
+		checks   map[string]grpcHealthzCheck
+		ln       net.Listener
+		lnLock   sync.RWMutex
+		running  atomic.Bool
+		done     chan struct{}
+		doneOnce sync.Once
+		// statuses by service shared by all Watch streams
+		watchStatuses map[string]healthpb.HealthCheckResponse_ServingStatus
+		watchChanged  chan struct{}
+		watchLock     sync.RWMutex
+		watchOnce     sync.Once
+	}
+	GRPCHealthzOptions struct {
+		// WatchInterval at which the probes are checked once for all Watch streams
+		WatchInterval time.Duration
+		// HealthzOptions for the probe checker
+		HealthzOptions []HealthzOption
+		// Checker shared with other healthz services, the HealthzOptions are ignored if set
+		Checker *HealthzChecker
+		// ServerOptions for the grpc server
+		ServerOptions []grpc.ServerOption
+	}
+	GRPCHealthzOption func(*GRPCHealthzOptions)
+	grpcHealthzCheck  struct {
+		strict bool
+		match  func(healthz.Type) bool
+	}
+)
+
+// GetDefaultGRPCHealthzOptions returns the default options
+func GetDefaultGRPCHealthzOptions() GRPCHealthzOptions {
+	return GRPCHealthzOptions{
+		WatchInterval: 5 * time.Second,
+	}
+}
+
+// GRPCHealthzWithWatchInterval option
+func GRPCHealthzWithWatchInterval(v time.Duration) GRPCHealthzOption {
+	return func(o *GRPCHealthzOptions) {
+		o.WatchInterval = v
+	}
+}
+
+// GRPCHealthzWithHealthzOptions option
+func GRPCHealthzWithHealthzOptions(v ...HealthzOption) GRPCHealthzOption {
+	return func(o *GRPCHealthzOptions) {
+		o.HealthzOptions = append(o.HealthzOptions, v...)
+	}
+}
+
+// GRPCHealthzWithChecker option
+func GRPCHealthzWithChecker(v *HealthzChecker) GRPCHealthzOption {
+	return func(o *GRPCHealthzOptions) {
+		o.Checker = v
+	}
+}
+
+// GRPCHealthzWithServerOptions option
+func GRPCHealthzWithServerOptions(v ...grpc.ServerOption) GRPCHealthzOption {
+	return func(o *GRPCHealthzOptions) {
+		o.ServerOptions = append(o.ServerOptions, v...)
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+func NewGRPCHealthz(l *zap.Logger, name, addr string, probes map[healthz.Type][]any, opts ...GRPCHealthzOption) *GRPCHealthz {
+	if l == nil {
+		l = log.Logger()
+	}
+	// enrich the log
+	l = log.WithAttributes(l,
+		keelsemconv.KeelServiceType("grpc"),
+		keelsemconv.KeelServiceName(name),
+	)
+
+	options := GetDefaultGRPCHealthzOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	checker := options.Checker
+	if checker == nil {
+		checker = NewHealthzChecker(l, probes, options.HealthzOptions...)
+	}
+
+	inst := &GRPCHealthz{
+		l:       l,
+		name:    name,
+		addr:    addr,
+		opts:    options,
+		server:  grpc.NewServer(options.ServerOptions...),
+		checker: checker,
+		checks: map[string]grpcHealthzCheck{
+			"":                             {match: healthzMatchRoot},
+			healthz.TypeLiveness.String():  {match: healthzMatch(healthz.TypeLiveness)},
+			healthz.TypeReadiness.String(): {match: healthzMatch(healthz.TypeReadiness)},
+			healthz.TypeStartup.String():   {match: healthzMatch(healthz.TypeStartup)},
+			healthz.TypeDegraded.String():  {match: healthzMatch(healthz.TypeDegraded), strict: true},
+		},
+		done:         make(chan struct{}),
+		watchChanged: make(chan struct{}),
+	}
+
+	healthpb.RegisterHealthServer(inst.server, inst)
+
+	return inst
+}
+
+func NewDefaultGRPCHealthz(l *zap.Logger, probes map[healthz.Type][]any, opts ...GRPCHealthzOption) *GRPCHealthz {
+	return NewGRPCHealthz(
+		l,
+		DefaultGRPCHealthzName,
+		DefaultGRPCHealthzAddr,
+		probes,
+		opts...,
+	)
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Getter
+// ------------------------------------------------------------------------------------------------
+
+func (s *GRPCHealthz) Name() string {
+	return s.name
+}
+
+func (s *GRPCHealthz) Server() *grpc.Server {
+	return s.server
+}
+
+// Listener returns the bound listener once started
+func (s *GRPCHealthz) Listener() net.Listener {
+	s.lnLock.RLock()
+	defer s.lnLock.RUnlock()
+
+	return s.ln
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (s *GRPCHealthz) Healthz() error {
+	if !s.running.Load() {
+		return ErrServiceNotRunning
+	}
+
+	return nil
+}
+
+func (s *GRPCHealthz) String() string {
+	s.lnLock.RLock()
+	defer s.lnLock.RUnlock()
+
+	return fmt.Sprintf("`grpc.health.v1.Health` on `%s`", s.addr)
+}
+
+func (s *GRPCHealthz) Start(ctx context.Context) error {
+	s.l.Info("starting keel service", zap.String("address", s.addr))
+
+	ln, err := listen(ctx, s.name, s.addr, ListenerOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to start service")
+	}
+
+	s.lnLock.Lock()
+	s.ln = ln
+	s.addr = ln.Addr().String()
+	s.lnLock.Unlock()
+
+	if s.checker.opts.Interval > 0 {
+		checkerCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		go s.checker.start(checkerCtx)
+	}
+
+	s.running.Store(true)
+	defer s.running.Store(false)
+
+	if err := s.server.Serve(ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return errors.Wrap(err, "failed to start service")
+	}
+
+	return nil
+}
+
+// Close ends all Watch streams and stops the server gracefully, falling
+// back to a hard stop once ctx is done.
+func (s *GRPCHealthz) Close(ctx context.Context) error {
+	s.l.Info("stopping keel service")
+	s.doneOnce.Do(func() {
+		close(s.done)
+	})
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return errors.Wrap(ctx.Err(), "failed to stop service")
+	}
+}
+
+// Check implements healthpb.HealthServer
+func (s *GRPCHealthz) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	check, ok := s.checks[req.GetService()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+
+	return &healthpb.HealthCheckResponse{Status: s.status(ctx, check)}, nil
+}
+
+// List implements healthpb.HealthServer
+func (s *GRPCHealthz) List(ctx context.Context, req *healthpb.HealthListRequest) (*healthpb.HealthListResponse, error) {
+	ret := &healthpb.HealthListResponse{Statuses: make(map[string]*healthpb.HealthCheckResponse, len(s.checks))}
+	for service, check := range s.checks {
+		ret.Statuses[service] = &healthpb.HealthCheckResponse{Status: s.status(ctx, check)}
+	}
+
+	return ret, nil
+}
+
+// Watch implements healthpb.HealthServer. The current status is sent
+// immediately and then on every change until the client cancels or the
+// service is closed. All streams share the statuses of a single watch
+// routine started with the first stream.
+func (s *GRPCHealthz) Watch(req *healthpb.HealthCheckRequest, stream grpc.ServerStreamingServer[healthpb.HealthCheckResponse]) error {
+	ctx := stream.Context()
+	_, known := s.checks[req.GetService()]
+
+	s.watchOnce.Do(func() {
+		go s.watch()
+	})
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+
+	for {
+		s.watchLock.RLock()
+		statuses, changed := s.watchStatuses, s.watchChanged
+		s.watchLock.RUnlock()
+
+		current := healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		if known {
+			current = statuses[req.GetService()]
+		}
+
+		// wait for the first statuses of known services
+		if current != last && (!known || statuses != nil) {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+
+			last = current
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-s.done:
+			// let clients know before the stream ends
+			if last != healthpb.HealthCheckResponse_NOT_SERVING {
+				_ = stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING})
+			}
+
+			return nil
+		}
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func (s *GRPCHealthz) status(ctx context.Context, check grpcHealthzCheck) healthpb.HealthCheckResponse_ServingStatus {
+	select {
+	case <-s.done:
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	default:
+	}
+
+	return check.status(s.checker.check(ctx, check.match, nil).Probes)
+}
+
+// watch checks all probes once per interval and notifies the Watch streams
+// until the service is closed
+func (s *GRPCHealthz) watch() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ticker := time.NewTicker(s.opts.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		probes := s.checker.check(ctx, func(healthz.Type) bool { return true }, nil).Probes
+
+		statuses := make(map[string]healthpb.HealthCheckResponse_ServingStatus, len(s.checks))
+		for service, check := range s.checks {
+			statuses[service] = check.status(probes)
+		}
+
+		s.watchLock.Lock()
+		s.watchStatuses = statuses
+		close(s.watchChanged)
+		s.watchChanged = make(chan struct{})
+		s.watchLock.Unlock()
+
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// status of the check over the results of its matching probes
+func (c grpcHealthzCheck) status(probes []healthz.ProbeResult) healthpb.HealthCheckResponse_ServingStatus {
+	for _, res := range probes {
+		if !c.match(res.Type) {
+			continue
+		}
+
+		if res.Status == healthz.StatusFailed || c.strict && res.Status == healthz.StatusDegraded {
+			return healthpb.HealthCheckResponse_NOT_SERVING
+		}
+	}
+
+	return healthpb.HealthCheckResponse_SERVING
+}
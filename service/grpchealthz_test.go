@@ -0,0 +1,124 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/foomo/keel/healthz"
+	"github.com/foomo/keel/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type toggleProbe struct {
+	healthy atomic.Bool
+}
+
+func (p *toggleProbe) Name() string {
+	return "toggle"
+}
+
+func (p *toggleProbe) Healthz(ctx context.Context) error {
+	if !p.healthy.Load() {
+		return errors.New("unhealthy")
+	}
+
+	return nil
+}
+
+func TestGRPCHealthz(t *testing.T) {
+	t.Parallel()
+
+	readiness := &toggleProbe{}
+	svs := service.NewGRPCHealthz(zap.NewNop(), "healthz-grpc", ":0", map[healthz.Type][]any{
+		healthz.TypeLiveness:  {namedProbe{name: "live"}},
+		healthz.TypeReadiness: {readiness},
+		healthz.TypeDegraded:  {namedProbe{name: "optional", err: errors.New("timeout")}},
+	}, service.GRPCHealthzWithWatchInterval(10*time.Millisecond))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		_ = svs.Server().Serve(ln)
+	}()
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	client := healthpb.NewHealthClient(conn)
+
+	check := func(svc string) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: svc})
+		require.NoError(t, err)
+
+		return resp.GetStatus()
+	}
+
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check("liveness"))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check("readiness"))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check("degraded"))
+
+	_, err = client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	stream, err := client.Watch(t.Context(), &healthpb.HealthCheckRequest{Service: "readiness"})
+	require.NoError(t, err)
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+
+	// degraded probes do not fail readiness
+	readiness.healthy.Store(true)
+
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+	// watchers are notified and closed on shutdown
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+
+	require.NoError(t, svs.Close(ctx))
+
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+}
+
+func TestGRPCHealthz_sharedChecker(t *testing.T) {
+	t.Parallel()
+
+	checker := service.NewHealthzChecker(zap.NewNop(), map[healthz.Type][]any{
+		healthz.TypeReadiness: {namedProbe{name: "mongo", err: errors.New("connection refused")}},
+	}, service.HealthzWithFailureThreshold(2))
+
+	handler := service.NewHealthzWithChecker(zap.NewNop(), "healthz", ":0", "/healthz", checker).Server().Handler
+	svs := service.NewGRPCHealthz(zap.NewNop(), "healthz-grpc", ":0", nil, service.GRPCHealthzWithChecker(checker))
+
+	// the first failure is below the threshold
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz/readiness", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// the second failure is counted on the same state
+	resp, err := svs.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "readiness"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+}
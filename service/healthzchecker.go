@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/foomo/keel/healthz"
+	"github.com/foomo/keel/log"
+	"github.com/foomo/keel/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	healthzProbeNameKey   = attribute.Key("healthz.probe.name")
+	healthzProbeTypeKey   = attribute.Key("healthz.probe.type")
+	healthzProbeStatusKey = attribute.Key("healthz.probe.status")
+)
+
+type (
+	// HealthzChecker runs the probes and keeps the state of each probe
+	// across calls to apply the failure threshold and serve cached results.
+	// A single checker may be shared by the http and grpc healthz services.
+	HealthzChecker struct {
+		l          *zap.Logger
+		probes     map[healthz.Type][]any
+		opts       HealthzOptions
+		states     map[string]*healthzState
+		statesLock sync.Mutex
+		started    atomic.Bool
+		// metrics
+		statusGauge      metric.Int64Gauge
+		latencyHistogram metric.Float64Histogram
+		failureCounter   metric.Int64Counter
+	}
+	healthzState struct {
+		result   healthz.ProbeResult
+		failures int
+		// latched startup probes are not called again once they succeeded
+		latched bool
+	}
+	healthzEntry struct {
+		key   string
+		typ   healthz.Type
+		probe any
+	}
+)
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+// NewHealthzChecker returns a checker for the probes to share between healthz services
+func NewHealthzChecker(l *zap.Logger, probes map[healthz.Type][]any, opts ...HealthzOption) *HealthzChecker {
+	options := GetDefaultHealthzOptions()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return newHealthzChecker(l, probes, options)
+}
+
+func newHealthzChecker(l *zap.Logger, probes map[healthz.Type][]any, opts HealthzOptions) *HealthzChecker {
+	return &HealthzChecker{
+		l:      l,
+		probes: probes,
+		opts:   opts,
+		states: map[string]*healthzState{},
+		statusGauge: telemetry.NewIntGauge("keel.healthz.probe.status",
+			metric.WithDescription("Reported status of the healthz probe (1 = ok, 0 = failed)"),
+		),
+		latencyHistogram: telemetry.NewFloatHistogram("keel.healthz.probe.duration",
+			metric.WithDescription("Duration of the healthz probe call"),
+			metric.WithUnit("s"),
+		),
+		failureCounter: telemetry.NewIntCounter("keel.healthz.probe.failures",
+			metric.WithDescription("Number of failed healthz probe calls"),
+		),
+	}
+}
+
+// start runs all probes on the configured interval until ctx is done. Only
+// the first call of the services sharing the checker starts it.
+func (c *HealthzChecker) start(ctx context.Context) {
+	if c.opts.Interval <= 0 || !c.started.CompareAndSwap(false, true) {
+		return
+	}
+
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		runCtx, span := telemetry.Tracer().Start(ctx, "healthz")
+		c.run(runCtx, c.pending(c.entries(func(healthz.Type) bool { return true }, nil)))
+		span.End()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check returns the result of all matching probes. Latched startup
+// probes and, in background mode, all probes are served from the cache
+// with their age; the remaining probes are called inline.
+func (c *HealthzChecker) check(ctx context.Context, match func(healthz.Type) bool, exclude []string) healthz.Result {
+	entries := c.entries(match, exclude)
+	results := make([]healthz.ProbeResult, len(entries))
+
+	var missing []int
+
+	for i, entry := range entries {
+		if state, ok := c.state(entry.key); ok && (state.latched || c.opts.Interval > 0) {
+			results[i] = state.result
+			results[i].Age = time.Since(state.result.Timestamp)
+		} else {
+			missing = append(missing, i)
+		}
+	}
+
+	if len(missing) > 0 {
+		subset := make([]healthzEntry, len(missing))
+		for j, i := range missing {
+			subset[j] = entries[i]
+		}
+
+		for j, res := range c.run(ctx, subset) {
+			results[missing[j]] = res
+		}
+	}
+
+	ret := healthz.Result{Status: healthz.StatusOK, Probes: results}
+	for _, res := range results {
+		switch res.Status {
+		case healthz.StatusFailed:
+			ret.Status = healthz.StatusFailed
+		case healthz.StatusDegraded:
+			if ret.Status == healthz.StatusOK {
+				ret.Status = healthz.StatusDegraded
+			}
+		}
+	}
+
+	return ret
+}
+
+// pending filters out latched startup probes
+func (c *HealthzChecker) pending(entries []healthzEntry) []healthzEntry {
+	ret := make([]healthzEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		if state, ok := c.state(entry.key); !ok || !state.latched {
+			ret = append(ret, entry)
+		}
+	}
+
+	return ret
+}
+
+// entries returns the matching probes in a stable order
+func (c *HealthzChecker) entries(match func(healthz.Type) bool, exclude []string) []healthzEntry {
+	types := make([]healthz.Type, 0, len(c.probes))
+	for typ := range c.probes {
+		if match(typ) {
+			types = append(types, typ)
+		}
+	}
+
+	slices.Sort(types)
+
+	var ret []healthzEntry
+
+	for _, typ := range types {
+		for i, probe := range c.probes[typ] {
+			name := healthz.Name(probe)
+			if slices.Contains(exclude, name) {
+				continue
+			}
+
+			ret = append(ret, healthzEntry{
+				key:   fmt.Sprintf("%s/%d", typ, i),
+				typ:   typ,
+				probe: probe,
+			})
+		}
+	}
+
+	return ret
+}
+
+// run calls the probes, concurrently if configured, and updates their state
+func (c *HealthzChecker) run(ctx context.Context, entries []healthzEntry) []healthz.ProbeResult {
+	results := make([]healthz.ProbeResult, len(entries))
+
+	if !c.opts.Concurrent {
+		for i, entry := range entries {
+			results[i] = c.call(ctx, entry)
+		}
+
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i, entry := range entries {
+		wg.Go(func() {
+			results[i] = c.call(ctx, entry)
+		})
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// call calls a single probe with its timeout and applies the failure threshold
+func (c *HealthzChecker) call(ctx context.Context, entry healthzEntry) healthz.ProbeResult {
+	timeout := c.opts.Timeout
+	if v, ok := entry.probe.(healthz.TimeoutHealthzer); ok && v.HealthzTimeout() > 0 {
+		timeout = v.HealthzTimeout()
+	}
+
+	res, err := healthzCall(ctx, healthz.Name(entry.probe), entry.typ, entry.probe, timeout)
+
+	c.statesLock.Lock()
+
+	state, ok := c.states[entry.key]
+	if !ok {
+		// probes are assumed to be healthy until they fail
+		state = &healthzState{result: healthz.ProbeResult{Status: healthz.StatusOK}}
+		c.states[entry.key] = state
+	}
+
+	previous := state.result.Status
+
+	if err != nil {
+		state.failures++
+	} else {
+		state.failures = 0
+	}
+
+	res.Failures = state.failures
+	// tolerate failures until the threshold is reached
+	if err != nil && state.failures < c.opts.FailureThreshold {
+		res.Status = healthz.StatusOK
+	}
+
+	// failures of non-critical probes are reported without failing the check
+	if entry.typ == healthz.TypeDegraded && res.Status == healthz.StatusFailed {
+		res.Status = healthz.StatusDegraded
+	}
+
+	// startup probes only have to succeed once
+	if entry.typ == healthz.TypeStartup && err == nil {
+		state.latched = true
+	}
+
+	state.result = res
+
+	c.statesLock.Unlock()
+
+	c.record(ctx, res, err, previous)
+
+	return res
+}
+
+// record reports the metrics of a probe call and logs state transitions
+func (c *HealthzChecker) record(ctx context.Context, res healthz.ProbeResult, err error, previous healthz.Status) {
+	attrs := []attribute.KeyValue{
+		healthzProbeNameKey.String(res.Name),
+		healthzProbeTypeKey.String(res.Type.String()),
+	}
+
+	var status int64
+	if res.Status == healthz.StatusOK {
+		status = 1
+	}
+
+	c.statusGauge.Record(ctx, status, metric.WithAttributes(attrs...))
+	c.latencyHistogram.Record(ctx, res.Latency.Seconds(), metric.WithAttributes(attrs...))
+
+	if err != nil {
+		c.failureCounter.Add(ctx, 1, metric.WithAttributes(attrs...))
+	}
+
+	if res.Status == previous {
+		return
+	}
+
+	trace.SpanFromContext(ctx).AddEvent("healthz.probe.state_change", trace.WithAttributes(
+		append(attrs, healthzProbeStatusKey.String(res.Status.String()))...,
+	))
+
+	l := c.l.With(
+		zap.String("probe", res.Name),
+		zap.String("type", res.Type.String()),
+		zap.String("status", res.Status.String()),
+		zap.String("previous", previous.String()),
+	)
+	if res.Status == healthz.StatusOK {
+		l.Info("healthz probe recovered")
+	} else {
+		log.WithError(l, err).Warn("healthz probe failed", zap.Int("failures", res.Failures))
+	}
+}
+
+func (c *HealthzChecker) state(key string) (healthzState, bool) {
+	c.statesLock.Lock()
+	defer c.statesLock.Unlock()
+
+	if state, ok := c.states[key]; ok {
+		return *state, true
+	}
+
+	return healthzState{}, false
+}
@@ -5,7 +5,8 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"strings"
+	"strconv"
+	"sync"
 	"sync/atomic"
 
 	keelhttp "github.com/foomo/keel/net/http"
@@ -18,18 +19,42 @@ import (
 )
 
 // HTTP struct
-type HTTP struct {
-	l       *zap.Logger
-	name    string
-	server  *http.Server
-	running atomic.Bool
-}
+type (
+	HTTP struct {
+		l        *zap.Logger
+		name     string
+		server   *http.Server
+		tls      *TLSReloader
+		listener ListenerOptions
+		ln       net.Listener
+		lnLock   sync.RWMutex
+		running  atomic.Bool
+		// background routines run while the service is started
+		background []func(ctx context.Context)
+	}
+	HTTPOptions struct {
+		// Middlewares wrapping the handler
+		Middlewares []keelhttp.Middleware
+		// TLS enables tls if set
+		TLS *TLSOptions
+		// Listener configures how the address is bound
+		Listener ListenerOptions
+		// Server configures timeouts and limits, defaults to keelhttp.GetDefaultServerOptions
+		Server *keelhttp.ServerOptions
+	}
+)
 
 // ------------------------------------------------------------------------------------------------
 // ~ Constructor
 // ------------------------------------------------------------------------------------------------
 
 func NewHTTP(l *zap.Logger, name, addr string, handler http.Handler, middlewares ...keelhttp.Middleware) *HTTP {
+	return NewHTTPWithOptions(l, name, addr, handler, HTTPOptions{
+		Middlewares: middlewares,
+	})
+}
+
+func NewHTTPWithOptions(l *zap.Logger, name, addr string, handler http.Handler, opts HTTPOptions) *HTTP {
 	if l == nil {
 		l = log.Logger()
 	}
@@ -39,11 +64,23 @@ func NewHTTP(l *zap.Logger, name, addr string, handler http.Handler, middlewares
 		keelsemconv.KeelServiceName(name),
 	)
 
-	return &HTTP{
-		l:      l,
-		name:   name,
-		server: keelhttp.NewServer(l, name, addr, handler, middlewares...),
+	serverOpts := keelhttp.GetDefaultServerOptions()
+	if opts.Server != nil {
+		serverOpts = *opts.Server
+	}
+
+	inst := &HTTP{
+		l:        l,
+		name:     name,
+		server:   keelhttp.NewServerWithOptions(l, name, addr, handler, serverOpts, opts.Middlewares...),
+		listener: opts.Listener,
+	}
+
+	if opts.TLS != nil {
+		inst.tls = NewTLSReloader(l, name, *opts.TLS)
 	}
+
+	return inst
 }
 
 // ------------------------------------------------------------------------------------------------
@@ -58,6 +95,19 @@ func (s *HTTP) Server() *http.Server {
 	return s.server
 }
 
+// Listener returns the bound listener once started
+func (s *HTTP) Listener() net.Listener {
+	s.lnLock.RLock()
+	defer s.lnLock.RUnlock()
+
+	return s.ln
+}
+
+// TLS returns the tls reloader or nil if tls is disabled
+func (s *HTTP) TLS() *TLSReloader {
+	return s.tls
+}
+
 // ------------------------------------------------------------------------------------------------
 // ~ Public methods
 // ------------------------------------------------------------------------------------------------
@@ -71,29 +121,56 @@ func (s *HTTP) Healthz() error {
 }
 
 func (s *HTTP) String() string {
+	if s.tls != nil {
+		return fmt.Sprintf("`%T` on `%s` with tls", s.server.Handler, s.server.Addr)
+	}
+
 	return fmt.Sprintf("`%T` on `%s`", s.server.Handler, s.server.Addr)
 }
 
 func (s *HTTP) Start(ctx context.Context) error {
-	var fields []zap.Field
+	ln, err := listen(ctx, s.name, s.server.Addr, s.listener)
+	if err != nil {
+		return errors.Wrap(err, "failed to start service")
+	}
 
-	if value := strings.Split(s.server.Addr, ":"); len(value) == 2 {
-		ip, port := value[0], value[1]
-		if ip == "" {
-			ip = "0.0.0.0"
-		}
+	s.lnLock.Lock()
+	s.ln = ln
+	s.lnLock.Unlock()
 
-		fields = append(fields, log.Attributes(semconv.ServerAddress(ip), semconv.ServerPortKey.String(port))...)
-	}
+	ln = newLimitListener(ln, s.name, s.listener)
 
-	s.l.Info("starting keel service", fields...)
+	s.l.Info("starting keel service", listenerFields(ln.Addr())...)
 	s.server.BaseContext = func(_ net.Listener) context.Context { return ctx }
 	s.server.RegisterOnShutdown(func() {
 		s.running.Store(false)
 	})
+
+	serve := s.server.Serve
+	if s.tls != nil {
+		if err := s.tls.Load(); err != nil {
+			_ = ln.Close()
+			return errors.Wrap(err, "failed to start service")
+		}
+
+		s.server.TLSConfig = s.tls.Config()
+		serve = func(ln net.Listener) error { return s.server.ServeTLS(ln, "", "") }
+
+		go s.tls.Watch(ctx)
+	}
+
+	if len(s.background) > 0 {
+		backgroundCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		for _, fn := range s.background {
+			go fn(backgroundCtx)
+		}
+	}
+
 	s.running.Store(true)
 
-	if err := s.server.ListenAndServe(); errors.Is(err, http.ErrServerClosed) {
+	if err := serve(ln); errors.Is(err, http.ErrServerClosed) {
 		return nil
 	} else if err != nil {
 		return errors.Wrap(err, "failed to start service")
@@ -111,3 +188,23 @@ func (s *HTTP) Close(ctx context.Context) error {
 
 	return nil
 }
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// listenerFields returns the log fields of the bound address
+func listenerFields(addr net.Addr) []zap.Field {
+	if addr.Network() == "unix" {
+		return log.Attributes(semconv.NetworkTransportUnix, semconv.ServerAddress(addr.String()))
+	}
+
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return log.Attributes(semconv.ServerAddress(addr.String()))
+	}
+
+	portNum, _ := strconv.Atoi(port)
+
+	return log.Attributes(semconv.NetworkTransportTCP, semconv.ServerAddress(host), semconv.ServerPort(portNum))
+}
@@ -1,11 +1,17 @@
 package service_test
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"testing"
 	"time"
 
 	"github.com/foomo/keel"
+	keelhttp "github.com/foomo/keel/net/http"
 	"github.com/foomo/keel/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -46,3 +52,49 @@ func _ExampleNewHTTP() {
 	// {"level":"info","msg":"keel closer closed: complete"}
 	// {"level":"info","msg":"keel server stopped"}
 }
+
+func TestHTTP_serverOptions(t *testing.T) {
+	t.Parallel()
+
+	addr := freeAddr(t)
+	svs := service.NewHTTPWithOptions(zap.NewNop(), "h2c", addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}), service.HTTPOptions{Server: &keelhttp.ServerOptions{
+		ReadTimeout:       time.Second,
+		ReadHeaderTimeout: 500 * time.Millisecond,
+		WriteTimeout:      2 * time.Second,
+		IdleTimeout:       3 * time.Second,
+		MaxHeaderBytes:    4096,
+		H2C:               true,
+	}})
+
+	assert.Equal(t, time.Second, svs.Server().ReadTimeout)
+	assert.Equal(t, 500*time.Millisecond, svs.Server().ReadHeaderTimeout)
+	assert.Equal(t, 2*time.Second, svs.Server().WriteTimeout)
+	assert.Equal(t, 3*time.Second, svs.Server().IdleTimeout)
+	assert.Equal(t, 4096, svs.Server().MaxHeaderBytes)
+
+	go func() {
+		_ = svs.Start(t.Context())
+	}()
+
+	t.Cleanup(func() {
+		_ = svs.Close(context.Background())
+	})
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+	require.Eventually(t, func() bool {
+		resp, err := client.Get("http://" + addr) //nolint:noctx
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+
+		return err == nil && string(body) == "HTTP/2.0"
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/foomo/keel/net/http/cache"
+	"go.uber.org/zap"
+)
+
+var (
+	DefaultHTTPCacheName = "cache"
+	DefaultHTTPCacheAddr = "localhost:9600"
+	DefaultHTTPCachePath = "/cache"
+)
+
+// NewHTTPCache returns a service to purge the response cache:
+//
+//	DELETE /cache?key=/foo&key=/bar purge keys
+//	DELETE /cache?tag=product-1     purge surrogate keys
+func NewHTTPCache(l *zap.Logger, store cache.Store, name, addr, path string) *HTTP {
+	handler := http.NewServeMux()
+	handler.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		type response struct {
+			Purged int `json:"purged"`
+		}
+
+		if r.Method != http.MethodDelete {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		keys, tags := r.URL.Query()["key"], r.URL.Query()["tag"]
+		if len(keys) == 0 && len(tags) == 0 {
+			http.Error(w, "missing key or tag", http.StatusBadRequest)
+			return
+		}
+
+		var ret response
+
+		if len(keys) > 0 {
+			n, err := store.Purge(r.Context(), keys...)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			ret.Purged += n
+		}
+
+		if len(tags) > 0 {
+			n, err := store.PurgeTags(r.Context(), tags...)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			ret.Purged += n
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(ret); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	return NewHTTP(l, name, addr, handler)
+}
+
+func NewDefaultHTTPCache(l *zap.Logger, store cache.Store) *HTTP {
+	return NewHTTPCache(
+		l,
+		store,
+		DefaultHTTPCacheName,
+		DefaultHTTPCacheAddr,
+		DefaultHTTPCachePath,
+	)
+}
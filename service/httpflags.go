@@ -0,0 +1,85 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/foomo/keel/flags"
+	"go.uber.org/zap"
+)
+
+var (
+	DefaultHTTPFlagsName = "flags"
+	DefaultHTTPFlagsAddr = "localhost:9500"
+	DefaultHTTPFlagsPath = "/flags"
+)
+
+// NewHTTPFlags returns a service to inspect the flags and to set or
+// delete runtime overrides:
+//
+//	GET    /flags                              list flags and overrides
+//	PUT    /flags {"key":"foo","variant":"on"} set an override
+//	DELETE /flags?key=foo                      delete an override
+func NewHTTPFlags(l *zap.Logger, f *flags.Flags, name, addr, path string) *HTTP {
+	handler := http.NewServeMux()
+	handler.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		type payload struct {
+			Key     string `json:"key"`
+			Variant string `json:"variant"`
+		}
+
+		type response struct {
+			Flags     []flags.Flag      `json:"flags"`
+			Overrides map[string]string `json:"overrides"`
+		}
+
+		enc := json.NewEncoder(w)
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+
+			if err := enc.Encode(response{Flags: f.Flags(), Overrides: f.Overrides()}); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		case http.MethodPut:
+			var req payload
+
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if err := f.SetOverride(req.Key, req.Variant); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+		case http.MethodDelete:
+			key := r.URL.Query().Get("key")
+			if key == "" {
+				http.Error(w, "missing key", http.StatusBadRequest)
+				return
+			}
+
+			f.DeleteOverride(key)
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+
+	return NewHTTP(l, name, addr, handler)
+}
+
+func NewDefaultHTTPFlags(l *zap.Logger, f *flags.Flags) *HTTP {
+	return NewHTTPFlags(
+		l,
+		f,
+		DefaultHTTPFlagsName,
+		DefaultHTTPFlagsAddr,
+		DefaultHTTPFlagsPath,
+	)
+}
@@ -0,0 +1,68 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/foomo/keel/flags"
+	"github.com/foomo/keel/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewHTTPFlags(t *testing.T) {
+	t.Parallel()
+
+	f, err := flags.New(zap.NewNop(), flags.Config{
+		Flags: []flags.Flag{{Key: "beta"}},
+	})
+	require.NoError(t, err)
+
+	handler := service.NewDefaultHTTPFlags(zap.NewNop(), f).Server().Handler
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+		return w
+	}
+
+	assert.Equal(t, "off", f.Variant(context.Background(), "beta", ""))
+
+	// set an override
+	require.Equal(t, http.StatusOK, serve(http.MethodPut, "/flags", `{"key":"beta","variant":"on"}`).Code)
+
+	evaluation := f.Evaluate(context.Background(), "beta")
+	assert.Equal(t, "on", evaluation.Variant)
+	assert.Equal(t, flags.ReasonOverride, evaluation.Reason)
+
+	// inspect
+	w := serve(http.MethodGet, "/flags", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res struct {
+		Flags     []flags.Flag      `json:"flags"`
+		Overrides map[string]string `json:"overrides"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+	require.Len(t, res.Flags, 1)
+	assert.Equal(t, "beta", res.Flags[0].Key)
+	assert.Equal(t, map[string]string{"beta": "on"}, res.Overrides)
+
+	// invalid overrides
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/flags", `{"key":"beta","variant":"unknown"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/flags", `{"key":"unknown","variant":"on"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/flags", `invalid`).Code)
+
+	// delete the override
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodDelete, "/flags", "").Code)
+	require.Equal(t, http.StatusOK, serve(http.MethodDelete, "/flags?key=beta", "").Code)
+	assert.Equal(t, "off", f.Variant(context.Background(), "beta", ""))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodPost, "/flags", "").Code)
+}
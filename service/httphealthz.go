@@ -2,13 +2,14 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/foomo/keel/healthz"
-	"github.com/foomo/keel/interfaces"
-	"github.com/foomo/keel/log"
-	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 	"go.uber.org/zap"
 )
 
@@ -19,144 +20,238 @@ var (
 )
 
 var (
-	ErrUnhandledHealthzProbe = errors.New("unhandled healthz probe")
-	ErrProbeFailed           = errors.New("probe failed")
+	ErrUnhandledHealthzProbe = healthz.ErrUnhandledProbe
+	ErrProbeFailed           = healthz.ErrProbeFailed
 	ErrLivenessProbeFailed   = errors.New("liveness probe failed")
 	ErrReadinessProbeFailed  = errors.New("readiness probe failed")
 	ErrStartupProbeFailed    = errors.New("startup probe failed")
+	ErrDegradedProbeFailed   = errors.New("degraded probe failed")
+	ErrProbeTimeout          = errors.New("probe timeout")
 )
 
-func NewHealthz(l *zap.Logger, name, addr, path string, probes map[healthz.Type][]any) *HTTP {
-	handler := http.NewServeMux()
+type (
+	HealthzOptions struct {
+		// Timeout of a single probe call. Probes may override it by
+		// implementing healthz.TimeoutHealthzer
+		Timeout time.Duration
+		// Concurrent calls all probes of a check in parallel
+		Concurrent bool
+		// Interval enables the background mode: probes are called on the
+		// interval and handlers serve the cached results
+		Interval time.Duration
+		// FailureThreshold is the number of consecutive failures before a
+		// probe is reported as failed
+		FailureThreshold int
+	}
+	HealthzOption func(*HealthzOptions)
+)
 
-	unavailable := func(l *zap.Logger, w http.ResponseWriter, r *http.Request, err error) {
-		if err != nil {
-			log.WithError(l, err).With(log.Attribute(semconv.URLFull(r.RequestURI))).Debug("healthz probe failed")
-			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
-		}
+// GetDefaultHealthzOptions returns the default options
+func GetDefaultHealthzOptions() HealthzOptions {
+	return HealthzOptions{
+		Timeout:          5 * time.Second,
+		Concurrent:       true,
+		Interval:         0,
+		FailureThreshold: 1,
 	}
+}
 
-	call := func(ctx context.Context, probe any) (bool, error) {
-		switch h := probe.(type) {
-		case healthz.BoolHealthzer:
-			return h.Healthz(), nil
-		case healthz.BoolHealthzerWithContext:
-			return h.Healthz(ctx), nil
-		case healthz.ErrorHealthzer:
-			return true, h.Healthz()
-		case healthz.ErrorHealthzWithContext:
-			return true, h.Healthz(ctx)
-		case interfaces.ErrorPinger:
-			return true, h.Ping()
-		case interfaces.ErrorPingerWithContext:
-			return true, h.Ping(ctx)
-		default:
-			return false, ErrUnhandledHealthzProbe
-		}
+// HealthzWithTimeout option
+func HealthzWithTimeout(v time.Duration) HealthzOption {
+	return func(o *HealthzOptions) {
+		o.Timeout = v
 	}
+}
 
-	handler.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-		for typ, values := range probes {
-			if typ == healthz.TypeStartup {
-				continue
-			}
+// HealthzWithConcurrent option
+func HealthzWithConcurrent(v bool) HealthzOption {
+	return func(o *HealthzOptions) {
+		o.Concurrent = v
+	}
+}
 
-			for _, p := range values {
-				if ok, err := call(r.Context(), p); err != nil {
-					unavailable(l, w, r, err)
-					return
-				} else if !ok {
-					unavailable(l, w, r, ErrProbeFailed)
-					return
-				}
-			}
-		}
+// HealthzWithInterval option
+func HealthzWithInterval(v time.Duration) HealthzOption {
+	return func(o *HealthzOptions) {
+		o.Interval = v
+	}
+}
 
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("OK"))
-	})
+// HealthzWithFailureThreshold option
+func HealthzWithFailureThreshold(v int) HealthzOption {
+	return func(o *HealthzOptions) {
+		o.FailureThreshold = v
+	}
+}
 
-	handler.HandleFunc(path+"/"+healthz.TypeLiveness.String(), func(w http.ResponseWriter, r *http.Request) {
-		var ps []any
-		if p, ok := probes[healthz.TypeAlways]; ok {
-			ps = append(ps, p...)
-		}
+// NewHealthz returns a service exposing the probes on path and on
+// path/{liveness,readiness,startup,degraded}, see the healthz package for
+// the semantics of each probe type.
+//
+// Responses follow the Kubernetes /livez conventions:
+//
+//	GET /healthz                         "OK" or 503
+//	GET /healthz?verbose                 "[+]name ok" / "[!]name degraded: error" / "[-]name failed: error" per probe
+//	GET /healthz?exclude=name            skips the named probe (repeatable)
+//	GET /healthz -H 'Accept: application/json'  healthz.Result as json
+//
+// Probes are named through interfaces.Namer, falling back to their type.
+func NewHealthz(l *zap.Logger, name, addr, path string, probes map[healthz.Type][]any, opts ...HealthzOption) *HTTP {
+	return NewHealthzWithChecker(l, name, addr, path, NewHealthzChecker(l, probes, opts...))
+}
 
-		if p, ok := probes[healthz.TypeLiveness]; ok {
-			ps = append(ps, p...)
-		}
+// NewHealthzWithChecker returns the healthz service of NewHealthz on a shared checker
+func NewHealthzWithChecker(l *zap.Logger, name, addr, path string, checker *HealthzChecker) *HTTP {
+	handler := http.NewServeMux()
 
-		for _, p := range ps {
-			if ok, err := call(r.Context(), p); err != nil {
-				unavailable(l, w, r, err)
-				return
-			} else if !ok {
-				unavailable(l, w, r, ErrLivenessProbeFailed)
-				return
-			}
-		}
+	handler.HandleFunc(path, healthzHandler(checker, ErrProbeFailed, false, healthzMatchRoot))
+	handler.HandleFunc(path+"/"+healthz.TypeLiveness.String(), healthzHandler(checker, ErrLivenessProbeFailed, false, healthzMatch(healthz.TypeLiveness)))
+	handler.HandleFunc(path+"/"+healthz.TypeReadiness.String(), healthzHandler(checker, ErrReadinessProbeFailed, false, healthzMatch(healthz.TypeReadiness)))
+	handler.HandleFunc(path+"/"+healthz.TypeStartup.String(), healthzHandler(checker, ErrStartupProbeFailed, false, healthzMatch(healthz.TypeStartup)))
+	// degraded handler fails on degraded probes e.g. for alerting
+	handler.HandleFunc(path+"/"+healthz.TypeDegraded.String(), healthzHandler(checker, ErrDegradedProbeFailed, true, healthzMatch(healthz.TypeDegraded)))
 
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("OK"))
-	})
+	svs := NewHTTP(l, name, addr, handler)
 
-	handler.HandleFunc(path+"/"+healthz.TypeReadiness.String(), func(w http.ResponseWriter, r *http.Request) {
-		var ps []any
-		if p, ok := probes[healthz.TypeAlways]; ok {
-			ps = append(ps, p...)
-		}
+	// run the probes in the background once the service is started
+	if checker.opts.Interval > 0 {
+		svs.background = append(svs.background, checker.start)
+	}
 
-		if p, ok := probes[healthz.TypeReadiness]; ok {
-			ps = append(ps, p...)
-		}
+	return svs
+}
 
-		for _, p := range ps {
-			if ok, err := call(r.Context(), p); err != nil {
-				unavailable(l, w, r, err)
-				return
-			} else if !ok {
-				unavailable(l, w, r, ErrReadinessProbeFailed)
-				return
-			}
-		}
+func NewDefaultHTTPProbesWithChecker(l *zap.Logger, checker *HealthzChecker) *HTTP {
+	return NewHealthzWithChecker(
+		l,
+		DefaultHTTPHealthzName,
+		DefaultHTTPHealthzAddr,
+		DefaultHTTPHealthzPath,
+		checker,
+	)
+}
 
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("OK"))
-	})
+func NewDefaultHTTPProbes(l *zap.Logger, probes map[healthz.Type][]any, opts ...HealthzOption) *HTTP {
+	return NewHealthz(
+		l,
+		DefaultHTTPHealthzName,
+		DefaultHTTPHealthzAddr,
+		DefaultHTTPHealthzPath,
+		probes,
+		opts...,
+	)
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
 
-	handler.HandleFunc(path+"/"+healthz.TypeStartup.String(), func(w http.ResponseWriter, r *http.Request) {
-		var ps []any
-		if p, ok := probes[healthz.TypeAlways]; ok {
-			ps = append(ps, p...)
+// healthzMatchRoot matches everything except startup probes
+func healthzMatchRoot(typ healthz.Type) bool {
+	return typ != healthz.TypeStartup
+}
+
+// healthzMatch returns the probe types run by the check of the given type
+func healthzMatch(check healthz.Type) func(healthz.Type) bool {
+	return func(typ healthz.Type) bool {
+		switch check {
+		case healthz.TypeDegraded:
+			return typ == healthz.TypeDegraded
+		case healthz.TypeReadiness:
+			// degraded probes are reported on readiness checks without failing them
+			return typ == healthz.TypeAlways || typ == healthz.TypeReadiness || typ == healthz.TypeDegraded
+		default:
+			return typ == healthz.TypeAlways || typ == check
 		}
+	}
+}
+
+// healthzHandler runs all probes of the matching types and writes the result.
+// Degraded results only fail the check if strict is set.
+func healthzHandler(checker *HealthzChecker, failed error, strict bool, match func(healthz.Type) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result := checker.check(r.Context(), match, r.URL.Query()["exclude"])
 
-		if p, ok := probes[healthz.TypeStartup]; ok {
-			ps = append(ps, p...)
+		status := http.StatusOK
+		if result.Status == healthz.StatusFailed || strict && result.Status == healthz.StatusDegraded {
+			status = http.StatusServiceUnavailable
 		}
 
-		for _, p := range ps {
-			if ok, err := call(r.Context(), p); err != nil {
-				unavailable(l, w, r, err)
-				return
-			} else if !ok {
-				unavailable(l, w, r, ErrStartupProbeFailed)
-				return
+		switch {
+		case strings.Contains(r.Header.Get("Accept"), "application/json"):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(result)
+		case r.URL.Query().Has("verbose"):
+			var b strings.Builder
+			for _, res := range result.Probes {
+				switch res.Status {
+				case healthz.StatusOK:
+					_, _ = fmt.Fprintf(&b, "[+]%s ok\n", res.Name)
+				case healthz.StatusDegraded:
+					_, _ = fmt.Fprintf(&b, "[!]%s degraded: %s\n", res.Name, res.Error)
+				default:
+					_, _ = fmt.Fprintf(&b, "[-]%s failed: %s\n", res.Name, res.Error)
+				}
 			}
-		}
 
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("OK"))
-	})
+			switch {
+			case status != http.StatusOK:
+				_, _ = fmt.Fprintf(&b, "healthz check failed: %s\n", failed)
+			case result.Status == healthz.StatusDegraded:
+				b.WriteString("healthz check passed with degraded probes\n")
+			default:
+				b.WriteString("healthz check passed\n")
+			}
 
-	return NewHTTP(l, name, addr, handler)
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(b.String()))
+		case status == http.StatusOK:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("OK"))
+		default:
+			http.Error(w, http.StatusText(status), status)
+		}
+	}
 }
 
-func NewDefaultHTTPProbes(l *zap.Logger, probes map[healthz.Type][]any) *HTTP {
-	return NewHealthz(
-		l,
-		DefaultHTTPHealthzName,
-		DefaultHTTPHealthzAddr,
-		DefaultHTTPHealthzPath,
-		probes,
-	)
+// healthzCall calls the probe with the timeout and measures its latency.
+// Probes that do not honor the context are abandoned once the timeout is hit.
+func healthzCall(ctx context.Context, name string, typ healthz.Type, probe any, timeout time.Duration) (healthz.ProbeResult, error) {
+	res := healthz.ProbeResult{
+		Name:      name,
+		Type:      typ,
+		Status:    healthz.StatusOK,
+		Timestamp: time.Now(),
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- healthz.Check(ctx, probe)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("%w: %w", ErrProbeTimeout, context.Cause(ctx))
+	}
+
+	res.Latency = time.Since(res.Timestamp)
+
+	if err != nil {
+		res.Status = healthz.StatusFailed
+		res.Error = err.Error()
+	}
+
+	return res, err
 }
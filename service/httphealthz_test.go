@@ -0,0 +1,325 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/foomo/keel/healthz"
+	"github.com/foomo/keel/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type namedProbe struct {
+	name string
+	err  error
+}
+
+func (p namedProbe) Name() string {
+	return p.name
+}
+
+func (p namedProbe) Healthz(ctx context.Context) error {
+	return p.err
+}
+
+func TestNewHealthz(t *testing.T) {
+	t.Parallel()
+
+	probes := map[healthz.Type][]any{
+		healthz.TypeAlways:    {namedProbe{name: "always"}},
+		healthz.TypeReadiness: {namedProbe{name: "mongo", err: errors.New("connection refused")}, namedProbe{name: "cache"}},
+		healthz.TypeStartup:   {namedProbe{name: "migrations"}},
+	}
+
+	handler := service.NewHealthz(zap.NewNop(), "healthz", ":0", "/healthz", probes).Server().Handler
+
+	serve := func(target string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	t.Run("plain", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, http.StatusServiceUnavailable, serve("/healthz/readiness", nil).Code)
+
+		w := serve("/healthz/liveness", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "OK", w.Body.String())
+	})
+
+	t.Run("verbose", func(t *testing.T) {
+		t.Parallel()
+
+		w := serve("/healthz/readiness?verbose", nil)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "[+]always ok\n")
+		assert.Contains(t, w.Body.String(), "[-]mongo failed: connection refused\n")
+		assert.Contains(t, w.Body.String(), "[+]cache ok\n")
+		assert.NotContains(t, w.Body.String(), "migrations")
+	})
+
+	t.Run("exclude", func(t *testing.T) {
+		t.Parallel()
+
+		w := serve("/healthz/readiness?verbose&exclude=mongo", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "mongo")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		w := serve("/healthz", http.Header{"Accept": {"application/json"}})
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var result struct {
+			Status string `json:"status"`
+			Probes []struct {
+				Name    string `json:"name"`
+				Type    string `json:"type"`
+				Status  string `json:"status"`
+				Latency string `json:"latency"`
+				Error   string `json:"error"`
+			} `json:"probes"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		assert.Equal(t, "failed", result.Status)
+		require.Len(t, result.Probes, 3)
+		assert.Equal(t, "always", result.Probes[0].Name)
+		assert.Equal(t, "always", result.Probes[0].Type)
+		assert.NotEmpty(t, result.Probes[0].Latency)
+		assert.Equal(t, "mongo", result.Probes[1].Name)
+		assert.Equal(t, "failed", result.Probes[1].Status)
+		assert.Equal(t, "connection refused", result.Probes[1].Error)
+	})
+}
+
+type slowProbe struct {
+	delay time.Duration
+}
+
+func (p slowProbe) Name() string {
+	return "slow"
+}
+
+func (p slowProbe) Healthz() error {
+	time.Sleep(p.delay)
+	return nil
+}
+
+type flakyProbe struct {
+	calls atomic.Int32
+	fail  int32
+}
+
+func (p *flakyProbe) Name() string {
+	return "flaky"
+}
+
+func (p *flakyProbe) Healthz(ctx context.Context) error {
+	if p.calls.Add(1) <= p.fail {
+		return errors.New("flaky")
+	}
+
+	return nil
+}
+
+func TestNewHealthz_timeout(t *testing.T) {
+	t.Parallel()
+
+	probes := map[healthz.Type][]any{
+		healthz.TypeReadiness: {slowProbe{delay: time.Second}, namedProbe{name: "fast"}},
+	}
+
+	handler := service.NewHealthz(zap.NewNop(), "healthz", ":0", "/healthz", probes,
+		service.HealthzWithTimeout(50*time.Millisecond),
+	).Server().Handler
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz/readiness?verbose", nil))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "[-]slow failed: "+service.ErrProbeTimeout.Error())
+	assert.Contains(t, w.Body.String(), "[+]fast ok\n")
+}
+
+func TestNewHealthz_failureThreshold(t *testing.T) {
+	t.Parallel()
+
+	probe := &flakyProbe{fail: 3}
+	handler := service.NewHealthz(zap.NewNop(), "healthz", ":0", "/healthz", map[healthz.Type][]any{
+		healthz.TypeReadiness: {probe},
+	}, service.HealthzWithFailureThreshold(3)).Server().Handler
+
+	var codes []int
+
+	for range 4 {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz/readiness", nil))
+		codes = append(codes, w.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusServiceUnavailable, http.StatusOK}, codes)
+}
+
+func TestNewHealthz_interval(t *testing.T) {
+	t.Parallel()
+
+	svs := service.NewHealthz(zap.NewNop(), "healthz", "127.0.0.1:0", "/healthz", map[healthz.Type][]any{
+		healthz.TypeReadiness: {&flakyProbe{}},
+	}, service.HealthzWithInterval(time.Hour))
+
+	go func() {
+		_ = svs.Start(t.Context())
+	}()
+
+	t.Cleanup(func() {
+		_ = svs.Close(context.Background())
+	})
+
+	type result struct {
+		Probes []struct {
+			Timestamp time.Time `json:"timestamp"`
+			Age       string    `json:"age"`
+		} `json:"probes"`
+	}
+
+	get := func() result {
+		r := httptest.NewRequest(http.MethodGet, "/healthz/readiness", nil)
+		r.Header.Set("Accept", "application/json")
+
+		w := httptest.NewRecorder()
+		svs.Server().Handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var ret result
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ret))
+		require.Len(t, ret.Probes, 1)
+
+		return ret
+	}
+
+	// wait for the cached probe state
+	var cached result
+
+	require.Eventually(t, func() bool {
+		cached = get()
+		return cached.Probes[0].Age != ""
+	}, time.Second, 10*time.Millisecond)
+
+	// results are served from the cache
+	for range 3 {
+		res := get()
+		assert.NotEmpty(t, res.Probes[0].Age)
+		assert.True(t, cached.Probes[0].Timestamp.Equal(res.Probes[0].Timestamp))
+	}
+}
+
+func TestNewHealthz_stateChange(t *testing.T) {
+	t.Parallel()
+
+	core, logs := observer.New(zap.InfoLevel)
+	spanRecorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)).Tracer("test")
+
+	handler := service.NewHealthz(zap.New(core), "healthz", ":0", "/healthz", map[healthz.Type][]any{
+		healthz.TypeReadiness: {&flakyProbe{fail: 2}},
+	}).Server().Handler
+
+	for range 4 {
+		ctx, span := tracer.Start(context.Background(), "request")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz/readiness", nil).WithContext(ctx))
+		span.End()
+	}
+
+	// transitions are logged once
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "healthz probe failed", entries[0].Message)
+	assert.Equal(t, "flaky", entries[0].ContextMap()["probe"])
+	assert.Equal(t, "healthz probe recovered", entries[1].Message)
+
+	var events []int
+	for i, span := range spanRecorder.Ended() {
+		for _, event := range span.Events() {
+			if event.Name == "healthz.probe.state_change" {
+				events = append(events, i)
+			}
+		}
+	}
+
+	assert.Equal(t, []int{0, 2}, events)
+}
+
+func TestNewHealthz_startupLatch(t *testing.T) {
+	t.Parallel()
+
+	startup := &flakyProbe{fail: 1}
+	handler := service.NewHealthz(zap.NewNop(), "healthz", ":0", "/healthz", map[healthz.Type][]any{
+		healthz.TypeStartup: {startup},
+	}).Server().Handler
+
+	var codes []int
+
+	for range 4 {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz/startup", nil))
+		codes = append(codes, w.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusOK, http.StatusOK}, codes)
+	// not called again once succeeded
+	assert.Equal(t, int32(2), startup.calls.Load())
+}
+
+func TestNewHealthz_degraded(t *testing.T) {
+	t.Parallel()
+
+	handler := service.NewHealthz(zap.NewNop(), "healthz", ":0", "/healthz", map[healthz.Type][]any{
+		healthz.TypeReadiness: {namedProbe{name: "mongo"}},
+		healthz.TypeDegraded:  {namedProbe{name: "recommendations", err: errors.New("timeout")}},
+	}).Server().Handler
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+		return w
+	}
+
+	w := serve("/healthz/readiness?verbose")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "[+]mongo ok\n")
+	assert.Contains(t, w.Body.String(), "[!]recommendations degraded: timeout\n")
+	assert.Contains(t, w.Body.String(), "healthz check passed with degraded probes\n")
+
+	assert.Equal(t, http.StatusOK, serve("/healthz").Code)
+	assert.NotContains(t, serve("/healthz/liveness?verbose").Body.String(), "recommendations")
+
+	w = serve("/healthz/degraded?verbose")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NotContains(t, w.Body.String(), "mongo")
+	assert.Contains(t, w.Body.String(), "healthz check failed: "+service.ErrDegradedProbeFailed.Error())
+}
@@ -123,18 +123,18 @@ func _ExampleNewHTTPReadme() {
 	//
 	// List of all registered init services that are being immediately started.
 	//
-	// | Name     | Type            | Address                              |
-	// | -------- | --------------- | ------------------------------------ |
-	// | `readme` | `*service.HTTP` | `*http.ServeMux` on `localhost:9001` |
+	// | Name     | Type            | Address                              | Settings                                                                             |
+	// | -------- | --------------- | ------------------------------------ | ------------------------------------------------------------------------------------ |
+	// | `readme` | `*service.HTTP` | `*http.ServeMux` on `localhost:9001` | read: `30s`, readHeader: `0s`, write: `1m0s`, idle: `30s`, maxHeaderBytes: `1048576` |
 	//
 	// ### Runtime Services
 	//
 	// List of all registered services that are being started.
 	//
-	// | Name             | Type                 | Description                            |
-	// | ---------------- | -------------------- | -------------------------------------- |
-	// | `demo-goroutine` | `*service.GoRoutine` | parallel: `1`                          |
-	// | `demp-http`      | `*service.HTTP`      | `http.HandlerFunc` on `localhost:8080` |
+	// | Name             | Type                 | Description                            | Settings                                                                             |
+	// | ---------------- | -------------------- | -------------------------------------- | ------------------------------------------------------------------------------------ |
+	// | `demo-goroutine` | `*service.GoRoutine` | parallel: `1`                          |                                                                                      |
+	// | `demp-http`      | `*service.HTTP`      | `http.HandlerFunc` on `localhost:8080` | read: `30s`, readHeader: `0s`, write: `1m0s`, idle: `30s`, maxHeaderBytes: `1048576` |
 	//
 	// ### Health probes
 	//
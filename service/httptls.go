@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/foomo/keel/log"
+	keelsemconv "github.com/foomo/keel/semconv"
+	"github.com/foomo/keel/telemetry"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrTLSCertificateExpiring = errors.New("tls certificate expiring")
+	ErrTLSCertificateMissing  = errors.New("tls certificate not loaded")
+	ErrTLSInvalidClientCA     = errors.New("tls client ca contains no certificates")
+)
+
+type (
+	// TLSOptions configures TLS and mTLS for service.HTTP
+	TLSOptions struct {
+		// CertFile and KeyFile of the server certificate
+		CertFile string
+		KeyFile  string
+		// ClientCAFile enables mTLS by verifying client certificates against it
+		ClientCAFile string
+		// ClientAuth mode, defaults to tls.RequireAndVerifyClientCert if a
+		// ClientCAFile is set
+		ClientAuth tls.ClientAuthType
+		// MinVersion of the accepted TLS protocol
+		MinVersion uint16
+		// CipherSuites for TLS 1.2 connections, defaults to the go defaults
+		CipherSuites []uint16
+		// ReloadInterval at which the files are checked for changes
+		ReloadInterval time.Duration
+		// ExpiryThreshold below which the readiness probe fails
+		ExpiryThreshold time.Duration
+	}
+	// TLSReloader keeps the certificates of a service up to date and
+	// reports their expiry. It is registered as a readiness probe.
+	TLSReloader struct {
+		l           *zap.Logger
+		name        string
+		opts        TLSOptions
+		cert        atomic.Pointer[tls.Certificate]
+		clientCAs   atomic.Pointer[x509.CertPool]
+		modTime     time.Time
+		expiryGauge metric.Int64Gauge
+	}
+)
+
+// GetDefaultTLSOptions returns the default options
+func GetDefaultTLSOptions() TLSOptions {
+	return TLSOptions{
+		MinVersion:      tls.VersionTLS12,
+		ReloadInterval:  time.Minute,
+		ExpiryThreshold: 7 * 24 * time.Hour,
+	}
+}
+
+// ParseTLSVersion parses a version such as "1.2" or "1.3"
+func ParseTLSVersion(v string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(v), "tls") {
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("service: invalid tls version %q", v)
+	}
+}
+
+// ParseTLSClientAuth parses the client certificate verification mode:
+// none, request, requireAny, verifyIfGiven or requireAndVerify
+func ParseTLSClientAuth(v string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(v) {
+	case "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "requireany":
+		return tls.RequireAnyClientCert, nil
+	case "verifyifgiven":
+		return tls.VerifyClientCertIfGiven, nil
+	case "requireandverify":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("service: invalid tls client auth %q", v)
+	}
+}
+
+// ParseTLSCipherSuites parses cipher suite names as returned by tls.CipherSuiteName
+func ParseTLSCipherSuites(v []string) ([]uint16, error) {
+	suites := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = suite.ID
+	}
+
+	ret := make([]uint16, 0, len(v))
+	for _, name := range v {
+		id, ok := suites[name]
+		if !ok {
+			return nil, fmt.Errorf("service: invalid or insecure tls cipher suite %q", name)
+		}
+
+		ret = append(ret, id)
+	}
+
+	return ret, nil
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Constructor
+// ------------------------------------------------------------------------------------------------
+
+func NewTLSReloader(l *zap.Logger, name string, opts TLSOptions) *TLSReloader {
+	if l == nil {
+		l = log.Logger()
+	}
+
+	if opts.ClientCAFile != "" && opts.ClientAuth == tls.NoClientCert {
+		opts.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return &TLSReloader{
+		l:    l,
+		name: name,
+		opts: opts,
+		expiryGauge: telemetry.NewIntGauge("keel.tls.certificate.expiry",
+			metric.WithDescription("Unix time at which the served tls certificate expires"),
+			metric.WithUnit("s"),
+		),
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (r *TLSReloader) Name() string {
+	return r.name + "-tls"
+}
+
+func (r *TLSReloader) String() string {
+	ret := fmt.Sprintf("certificate `%s`", r.opts.CertFile)
+	if r.opts.ClientCAFile != "" {
+		ret += fmt.Sprintf(", client ca `%s` (%s)", r.opts.ClientCAFile, r.opts.ClientAuth)
+	}
+
+	return ret
+}
+
+// Healthz fails if the certificate expires within the expiry threshold
+func (r *TLSReloader) Healthz(ctx context.Context) error {
+	cert := r.cert.Load()
+	if cert == nil || cert.Leaf == nil {
+		return ErrTLSCertificateMissing
+	}
+
+	if left := time.Until(cert.Leaf.NotAfter); left < r.opts.ExpiryThreshold {
+		return fmt.Errorf("%w: expires in %s", ErrTLSCertificateExpiring, left.Truncate(time.Second))
+	}
+
+	return nil
+}
+
+// Certificate returns the currently served certificate
+func (r *TLSReloader) Certificate() *tls.Certificate {
+	return r.cert.Load()
+}
+
+// Config returns a tls config always serving the latest certificates
+func (r *TLSReloader) Config() *tls.Config {
+	base := &tls.Config{
+		MinVersion:   r.opts.MinVersion,
+		CipherSuites: r.opts.CipherSuites,
+		ClientAuth:   r.opts.ClientAuth,
+		NextProtos:   []string{"h2", "http/1.1"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.cert.Load(), nil
+		},
+	}
+
+	if r.opts.ClientCAFile == "" {
+		return base
+	}
+
+	return &tls.Config{
+		MinVersion: r.opts.MinVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			config := base.Clone()
+			config.ClientCAs = r.clientCAs.Load()
+
+			return config, nil
+		},
+	}
+}
+
+// Load reads the certificate and client ca files and swaps them atomically
+func (r *TLSReloader) Load() error {
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load tls certificate")
+	}
+
+	var clientCAs *x509.CertPool
+
+	if r.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to load tls client ca")
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return ErrTLSInvalidClientCA
+		}
+	}
+
+	r.cert.Store(&cert)
+
+	if clientCAs != nil {
+		r.clientCAs.Store(clientCAs)
+	}
+
+	r.expiryGauge.Record(context.Background(), cert.Leaf.NotAfter.Unix(),
+		metric.WithAttributes(keelsemconv.KeelServiceName(r.name)),
+	)
+
+	return nil
+}
+
+// Watch reloads the files whenever they change until ctx is done. Files
+// are polled so that symlink swaps of mounted secrets are picked up.
+func (r *TLSReloader) Watch(ctx context.Context) {
+	if r.opts.ReloadInterval <= 0 {
+		return
+	}
+
+	r.modTime = r.lastModTime()
+
+	ticker := time.NewTicker(r.opts.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		modTime := r.lastModTime()
+		if modTime.Equal(r.modTime) {
+			continue
+		}
+
+		if err := r.Load(); err != nil {
+			// keep serving the previous certificate; files may be mid-rotation
+			log.WithError(r.l, err).Warn("failed to reload tls certificate")
+			continue
+		}
+
+		r.modTime = modTime
+		r.l.Info("reloaded tls certificate", zap.Time("not_after", r.cert.Load().Leaf.NotAfter))
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+func (r *TLSReloader) lastModTime() time.Time {
+	var ret time.Time
+
+	for _, filename := range []string{r.opts.CertFile, r.opts.KeyFile, r.opts.ClientCAFile} {
+		if filename == "" {
+			continue
+		}
+
+		if info, err := os.Stat(filename); err == nil && info.ModTime().After(ret) {
+			ret = info.ModTime()
+		}
+	}
+
+	return ret
+}
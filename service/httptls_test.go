@@ -0,0 +1,207 @@
+package service_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/foomo/keel/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// writeCert writes a certificate signed by ca (or self-signed if ca is nil)
+// and returns it
+func writeCert(t *testing.T, dir, name string, serial int64, ca *tls.Certificate) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  ca == nil,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	parent, signer := tmpl, any(key)
+	if ca != nil {
+		parent, signer = ca.Leaf, ca.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"), certPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0o600))
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	return cert
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	return addr
+}
+
+func startTLS(t *testing.T, opts service.TLSOptions) (*service.HTTP, string) {
+	t.Helper()
+
+	addr := freeAddr(t)
+	svs := service.NewHTTPWithOptions(zap.NewNop(), "tls", addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}), service.HTTPOptions{TLS: &opts})
+
+	go func() {
+		_ = svs.Start(t.Context())
+	}()
+
+	t.Cleanup(func() {
+		_ = svs.Close(t.Context())
+	})
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr) //nolint:noctx
+		if err != nil {
+			return false
+		}
+
+		return conn.Close() == nil
+	}, time.Second, 10*time.Millisecond)
+
+	return svs, addr
+}
+
+func TestHTTP_TLS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := writeCert(t, dir, "ca", 1, nil)
+	writeCert(t, dir, "server", 2, &ca)
+
+	opts := service.GetDefaultTLSOptions()
+	opts.CertFile = filepath.Join(dir, "server.crt")
+	opts.KeyFile = filepath.Join(dir, "server.key")
+	opts.ReloadInterval = 10 * time.Millisecond
+
+	svs, addr := startTLS(t, opts)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}}}
+
+	serial := func() int64 {
+		resp, err := client.Get("https://" + addr) //nolint:noctx
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	assert.Equal(t, int64(2), serial())
+
+	// expires within the default threshold of 7 days
+	require.ErrorIs(t, svs.TLS().Healthz(t.Context()), service.ErrTLSCertificateExpiring)
+
+	// rotated certificates are picked up without a restart
+	time.Sleep(20 * time.Millisecond)
+	writeCert(t, dir, "server", 3, &ca)
+
+	client.CloseIdleConnections()
+	assert.Eventually(t, func() bool {
+		client.CloseIdleConnections()
+		return serial() == 3
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestHTTP_mTLS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := writeCert(t, dir, "ca", 1, nil)
+	writeCert(t, dir, "server", 2, &ca)
+	clientCert := writeCert(t, dir, "client", 3, &ca)
+
+	opts := service.GetDefaultTLSOptions()
+	opts.CertFile = filepath.Join(dir, "server.crt")
+	opts.KeyFile = filepath.Join(dir, "server.key")
+	opts.ClientCAFile = filepath.Join(dir, "ca.crt")
+	opts.ExpiryThreshold = time.Minute
+
+	svs, addr := startTLS(t, opts)
+	require.NoError(t, svs.TLS().Healthz(t.Context()))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+
+	get := func(certs ...tls.Certificate) error {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: certs,
+			MinVersion:   tls.VersionTLS12,
+		}}}
+
+		resp, err := client.Get("https://" + addr) //nolint:noctx
+		if err != nil {
+			return err
+		}
+
+		return resp.Body.Close()
+	}
+
+	require.Error(t, get())
+	require.NoError(t, get(clientCert))
+}
+
+func TestParseTLS(t *testing.T) {
+	t.Parallel()
+
+	v, err := service.ParseTLSVersion("1.3")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), v)
+
+	auth, err := service.ParseTLSClientAuth("verifyIfGiven")
+	require.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, auth)
+
+	suites, err := service.ParseTLSCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, suites)
+
+	_, err = service.ParseTLSCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})
+	require.Error(t, err)
+}
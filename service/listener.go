@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Environment variables of the systemd socket activation protocol
+const (
+	EnvListenPID     = "LISTEN_PID"
+	EnvListenFDs     = "LISTEN_FDS"
+	EnvListenFDNames = "LISTEN_FDNAMES"
+	// EnvListenPPID replaces LISTEN_PID for listeners handed over by a
+	// parent process which cannot know the pid of its child in advance
+	EnvListenPPID = "KEEL_LISTEN_PPID"
+)
+
+// listenFDsStart is the first inherited file descriptor
+const listenFDsStart = 3
+
+var (
+	ErrNoInheritedListener  = errors.New("no inherited listener")
+	ErrReusePortUnsupported = errors.New("SO_REUSEPORT is not supported on this system")
+)
+
+type inheritedListenerEntry struct {
+	name string
+	ln   net.Listener
+}
+
+var (
+	inheritedListeners     []inheritedListenerEntry
+	inheritedListenersErr  error
+	inheritedListenersOnce sync.Once
+	inheritedListenersLock sync.Mutex
+)
+
+// ListenerOptions configures how service.HTTP binds its address
+type ListenerOptions struct {
+	// Network to listen on: "tcp" or "unix". Addresses prefixed with
+	// "unix:" select the unix network as well.
+	Network string
+	// UnixSocketMode sets the permissions of the unix socket file
+	UnixSocketMode os.FileMode
+	// SocketActivation takes an inherited listener (systemd LISTEN_FDS)
+	// instead of binding the address
+	SocketActivation bool
+	// SocketActivationName selects the inherited listener by its
+	// LISTEN_FDNAMES entry, defaults to the next unused one
+	SocketActivationName string
+	// ReusePort sets SO_REUSEPORT so that several processes can bind the
+	// same address
+	ReusePort bool
+	// MaxConns limits the concurrently open connections, 0 means unlimited
+	MaxConns int
+	// MaxConnsPerIP limits the concurrently open connections per client
+	// ip, 0 means unlimited
+	MaxConnsPerIP int
+}
+
+// Listen returns a listener for addr according to the options
+func Listen(ctx context.Context, addr string, opts ListenerOptions) (net.Listener, error) {
+	if opts.SocketActivation {
+		return inheritedListener(opts.SocketActivationName)
+	}
+
+	network := opts.Network
+	if v, ok := strings.CutPrefix(addr, "unix:"); ok {
+		network, addr = "unix", v
+	}
+
+	if network == "" {
+		network = "tcp"
+	}
+
+	lc := net.ListenConfig{}
+	if opts.ReusePort {
+		lc.Control = reusePortControl
+	}
+
+	if network == "unix" {
+		// remove stale sockets of previous runs but never other files
+		if info, err := os.Lstat(addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
+				return nil, errors.Wrap(err, "failed to remove unix socket")
+			}
+		} else if err == nil {
+			return nil, errors.Errorf("failed to listen on unix socket: %s exists and is not a socket", addr)
+		}
+	}
+
+	ln, err := lc.Listen(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if network == "unix" && opts.UnixSocketMode != 0 {
+		if err := os.Chmod(addr, opts.UnixSocketMode); err != nil {
+			_ = ln.Close()
+			return nil, errors.Wrap(err, "failed to chmod unix socket")
+		}
+	}
+
+	return ln, nil
+}
+
+// CloseInheritedListeners closes all inherited listeners that have not
+// been taken by a service so that no connections queue up on them
+func CloseInheritedListeners() error {
+	inheritedListenersLock.Lock()
+	defer inheritedListenersLock.Unlock()
+
+	var ret error
+	for _, entry := range inheritedListeners {
+		if err := entry.ln.Close(); err != nil && ret == nil {
+			ret = errors.Wrapf(err, "failed to close inherited listener %q", entry.name)
+		}
+	}
+
+	inheritedListeners = nil
+
+	return ret
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// listen takes the inherited listener named after the service if there is
+// one, e.g. when handed over during an upgrade, or binds addr otherwise
+func listen(ctx context.Context, name, addr string, opts ListenerOptions) (net.Listener, error) {
+	if !opts.SocketActivation {
+		if ln, err := inheritedListener(name); err == nil {
+			return ln, nil
+		}
+	}
+
+	return Listen(ctx, addr, opts)
+}
+
+// inheritedListener returns the next unused inherited listener with the
+// given name, or any name if empty
+func inheritedListener(name string) (net.Listener, error) {
+	inheritedListenersOnce.Do(func() {
+		inheritedListeners, inheritedListenersErr = listenFDs()
+	})
+
+	if inheritedListenersErr != nil {
+		return nil, inheritedListenersErr
+	}
+
+	inheritedListenersLock.Lock()
+	defer inheritedListenersLock.Unlock()
+
+	for i, entry := range inheritedListeners {
+		if name == "" || entry.name == name {
+			inheritedListeners = append(inheritedListeners[:i], inheritedListeners[i+1:]...)
+			return entry.ln, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrNoInheritedListener, name)
+}
+
+// listenFDs returns the listeners passed through the systemd socket
+// activation protocol in order
+func listenFDs() ([]inheritedListenerEntry, error) {
+	pid, _ := strconv.Atoi(os.Getenv(EnvListenPID))
+	ppid, _ := strconv.Atoi(os.Getenv(EnvListenPPID))
+	fds := os.Getenv(EnvListenFDs)
+	names := strings.Split(os.Getenv(EnvListenFDNames), ":")
+
+	// don't pass the descriptors on to child processes
+	for _, key := range []string{EnvListenPID, EnvListenPPID, EnvListenFDs, EnvListenFDNames} {
+		_ = os.Unsetenv(key)
+	}
+
+	// the ppid is 0 for processes without a parent e.g. pid 1 in a container
+	if pid != os.Getpid() && (ppid == 0 || ppid != os.Getppid()) {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(fds)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid "+EnvListenFDs)
+	}
+	ret := make([]inheritedListenerEntry, 0, count)
+
+	for i := range count {
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+
+		ln, err := net.FileListener(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to use inherited file descriptor %d", listenFDsStart+i)
+		}
+
+		// the listener holds a dup of the descriptor
+		_ = f.Close()
+
+		ret = append(ret, inheritedListenerEntry{name: name, ln: ln})
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,169 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/foomo/keel/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestListen_unix(t *testing.T) {
+	t.Parallel()
+
+	socket := filepath.Join(t.TempDir(), "http.sock")
+	svs := service.NewHTTPWithOptions(zap.NewNop(), "unix", "unix:"+socket, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}), service.HTTPOptions{Listener: service.ListenerOptions{UnixSocketMode: 0o600}})
+
+	go func() {
+		_ = svs.Start(t.Context())
+	}()
+
+	t.Cleanup(func() {
+		_ = svs.Close(context.Background())
+	})
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(socket)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+
+	resp, err := client.Get("http://unix/") //nolint:noctx
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "OK", string(body))
+}
+
+func TestListen_unixNoSocket(t *testing.T) {
+	t.Parallel()
+
+	// regular files are never removed
+	file := filepath.Join(t.TempDir(), "http.sock")
+	require.NoError(t, os.WriteFile(file, []byte("data"), 0o600))
+
+	_, err := service.Listen(t.Context(), "unix:"+file, service.ListenerOptions{})
+	require.Error(t, err)
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	// stale sockets are replaced
+	socket := filepath.Join(t.TempDir(), "stale.sock")
+	ln, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, ln.Close())
+
+	ln, err = service.Listen(t.Context(), "unix:"+socket, service.ListenerOptions{})
+	require.NoError(t, err)
+	require.NoError(t, ln.Close())
+}
+
+func TestListen_maxConns(t *testing.T) {
+	t.Parallel()
+
+	addr := freeAddr(t)
+	svs := service.NewHTTPWithOptions(zap.NewNop(), "limit", addr, http.NotFoundHandler(), service.HTTPOptions{
+		Listener: service.ListenerOptions{MaxConnsPerIP: 1},
+	})
+
+	go func() {
+		_ = svs.Start(t.Context())
+	}()
+
+	t.Cleanup(func() {
+		_ = svs.Close(context.Background())
+	})
+
+	dial := func() net.Conn {
+		var conn net.Conn
+
+		require.Eventually(t, func() bool {
+			var err error
+			conn, err = net.Dial("tcp", addr) //nolint:noctx
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+
+		return conn
+	}
+
+	first := dial()
+	defer first.Close()
+
+	// give the server time to accept the first connection
+	time.Sleep(50 * time.Millisecond)
+
+	second := dial()
+	defer second.Close()
+
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err := second.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+
+	// the slot is released once the first connection is closed
+	require.NoError(t, first.Close())
+	time.Sleep(50 * time.Millisecond)
+
+	third := dial()
+	defer third.Close()
+
+	_, err = third.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+
+	resp, err := io.ReadAll(io.LimitReader(third, 12))
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 404", string(resp))
+}
+
+func TestListen_reusePort(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("SO_REUSEPORT is not supported")
+	}
+
+	first, err := service.Listen(t.Context(), "127.0.0.1:0", service.ListenerOptions{ReusePort: true})
+	require.NoError(t, err)
+
+	defer first.Close()
+
+	second, err := service.Listen(t.Context(), first.Addr().String(), service.ListenerOptions{ReusePort: true})
+	require.NoError(t, err)
+	require.NoError(t, second.Close())
+
+	_, err = service.Listen(t.Context(), first.Addr().String(), service.ListenerOptions{})
+	require.Error(t, err)
+}
+
+func TestListen_socketActivation(t *testing.T) {
+	t.Parallel()
+
+	_, err := service.Listen(t.Context(), "", service.ListenerOptions{SocketActivation: true})
+	require.ErrorIs(t, err, service.ErrNoInheritedListener)
+}
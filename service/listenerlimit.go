@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	keelsemconv "github.com/foomo/keel/semconv"
+	"github.com/foomo/keel/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	connectionsRejectReasonKey = attribute.Key("keel.connection.reject_reason")
+	rejectReasonMaxConns       = "max_conns"
+	rejectReasonMaxConnsPerIP  = "max_conns_per_ip"
+)
+
+type (
+	// limitListener closes connections exceeding the limits right after
+	// accepting them and reports accepted and rejected connections
+	limitListener struct {
+		net.Listener
+		maxConns      int
+		maxConnsPerIP int
+		attrs         metric.MeasurementOption
+		conns         int
+		connsPerIP    map[string]int
+		connsLock     sync.Mutex
+		accepted      metric.Int64Counter
+		rejected      metric.Int64Counter
+		active        metric.Int64UpDownCounter
+	}
+	limitConn struct {
+		net.Conn
+		ip        string
+		listener  *limitListener
+		closeOnce sync.Once
+	}
+)
+
+// newLimitListener wraps ln to apply the connection limits of the options.
+// Without limits ln is returned as is and no connection metrics are recorded.
+func newLimitListener(ln net.Listener, name string, opts ListenerOptions) net.Listener {
+	if opts.MaxConns <= 0 && opts.MaxConnsPerIP <= 0 {
+		return ln
+	}
+
+	return &limitListener{
+		Listener:      ln,
+		maxConns:      opts.MaxConns,
+		maxConnsPerIP: opts.MaxConnsPerIP,
+		attrs:         metric.WithAttributes(keelsemconv.KeelServiceName(name)),
+		connsPerIP:    map[string]int{},
+		accepted: telemetry.NewIntCounter("keel.http.server.connections.accepted",
+			metric.WithDescription("Number of accepted connections"),
+		),
+		rejected: telemetry.NewIntCounter("keel.http.server.connections.rejected",
+			metric.WithDescription("Number of connections rejected by the connection limits"),
+		),
+		active: telemetry.NewIntUpDownCounter("keel.http.server.connections.active",
+			metric.WithDescription("Number of open connections"),
+		),
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Public methods
+// ------------------------------------------------------------------------------------------------
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		ip := connIP(conn)
+
+		if reason := l.acquire(ip); reason != "" {
+			_ = conn.Close()
+			l.rejected.Add(context.Background(), 1, l.attrs, metric.WithAttributes(connectionsRejectReasonKey.String(reason)))
+
+			continue
+		}
+
+		l.accepted.Add(context.Background(), 1, l.attrs)
+		l.active.Add(context.Background(), 1, l.attrs)
+
+		return &limitConn{Conn: conn, ip: ip, listener: l}, nil
+	}
+}
+
+func (c *limitConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.listener.release(c.ip)
+		c.listener.active.Add(context.Background(), -1, c.listener.attrs)
+	})
+
+	return c.Conn.Close()
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// acquire reserves a slot for the ip and returns the reject reason if the
+// limits are exceeded
+func (l *limitListener) acquire(ip string) string {
+	l.connsLock.Lock()
+	defer l.connsLock.Unlock()
+
+	if l.maxConns > 0 && l.conns >= l.maxConns {
+		return rejectReasonMaxConns
+	}
+
+	if l.maxConnsPerIP > 0 && ip != "" && l.connsPerIP[ip] >= l.maxConnsPerIP {
+		return rejectReasonMaxConnsPerIP
+	}
+
+	l.conns++
+	if ip != "" {
+		l.connsPerIP[ip]++
+	}
+
+	return ""
+}
+
+func (l *limitListener) release(ip string) {
+	l.connsLock.Lock()
+	defer l.connsLock.Unlock()
+
+	l.conns--
+
+	if ip != "" {
+		if l.connsPerIP[ip] <= 1 {
+			delete(l.connsPerIP, ip)
+		} else {
+			l.connsPerIP[ip]--
+		}
+	}
+}
+
+// connIP returns the remote ip of tcp connections
+func connIP(conn net.Conn) string {
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP.String()
+	}
+
+	return ""
+}
@@ -0,0 +1,344 @@
+package keel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foomo/keel/healthz"
+	"github.com/foomo/keel/log"
+	"github.com/foomo/keel/service"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// EnvUpgradeReadyFD is the file descriptor the upgraded process writes to
+// once its probes pass
+const EnvUpgradeReadyFD = "KEEL_UPGRADE_READY_FD"
+
+// upgradeListenPPID is read on init as the services unset the variable once
+// they took over the inherited listeners
+var upgradeListenPPID, _ = strconv.Atoi(os.Getenv(service.EnvListenPPID))
+
+var (
+	ErrUpgradeUnsupported = errors.New("upgrade is not supported on this system")
+	ErrUpgradeInProgress  = errors.New("upgrade is already in progress")
+	ErrUpgradeChildExited = errors.New("upgraded process exited before becoming ready")
+	ErrUpgradeTimeout     = errors.New("upgraded process did not become ready in time")
+)
+
+type (
+	// UpgradeOptions configures the zero-downtime binary upgrade
+	UpgradeOptions struct {
+		// Signal triggering the upgrade, defaults to SIGUSR2
+		Signal os.Signal
+		// ReadyTimeout to wait for the new process to pass its probes
+		ReadyTimeout time.Duration
+		// Interval at which the new process checks its probes
+		Interval time.Duration
+	}
+	UpgradeOption func(*UpgradeOptions)
+	// listenerService is implemented by services whose listener can be
+	// handed over to the new process
+	listenerService interface {
+		Name() string
+		Listener() net.Listener
+	}
+	fileListener interface {
+		File() (*os.File, error)
+	}
+)
+
+// GetDefaultUpgradeOptions returns the default options
+func GetDefaultUpgradeOptions() UpgradeOptions {
+	return UpgradeOptions{
+		Signal:       defaultUpgradeSignal,
+		ReadyTimeout: time.Minute,
+		Interval:     100 * time.Millisecond,
+	}
+}
+
+// UpgradeWithSignal sets the signal triggering the upgrade
+func UpgradeWithSignal(v os.Signal) UpgradeOption {
+	return func(o *UpgradeOptions) {
+		o.Signal = v
+	}
+}
+
+// UpgradeWithReadyTimeout sets the time to wait for the new process
+func UpgradeWithReadyTimeout(v time.Duration) UpgradeOption {
+	return func(o *UpgradeOptions) {
+		o.ReadyTimeout = v
+	}
+}
+
+// UpgradeWithInterval sets the interval at which the new process checks its probes
+func UpgradeWithInterval(v time.Duration) UpgradeOption {
+	return func(o *UpgradeOptions) {
+		o.Interval = v
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ~ Private methods
+// ------------------------------------------------------------------------------------------------
+
+// watchUpgrade upgrades the binary on every upgrade signal until shutdown
+func (s *Server) watchUpgrade() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, s.upgrade.Signal)
+
+	defer signal.Stop(sig)
+
+	for {
+		select {
+		case <-s.gracefulCtx.Done():
+			return
+		case <-sig:
+			if err := s.upgradeBinary(); err != nil {
+				log.WithError(s.l, err).Error("keel upgrade failed")
+				continue
+			}
+
+			s.l.Info("keel upgrade completed, shutting down")
+			s.gracefulCancel()
+
+			return
+		}
+	}
+}
+
+// upgradeBinary starts the current executable with the service listeners
+// and waits until the new process reports ready
+func (s *Server) upgradeBinary() error {
+	if !s.upgrading.CompareAndSwap(false, true) {
+		return ErrUpgradeInProgress
+	}
+	defer s.upgrading.Store(false)
+
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve executable")
+	}
+
+	files, names, restore, err := s.upgradeFiles()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		restore()
+		return errors.Wrap(err, "failed to create ready pipe")
+	}
+	defer r.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...) //nolint:gosec
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, w)
+	cmd.Env = append(upgradeEnviron(),
+		service.EnvListenFDs+"="+strconv.Itoa(len(files)),
+		service.EnvListenFDNames+"="+strings.Join(names, ":"),
+		service.EnvListenPPID+"="+strconv.Itoa(os.Getpid()),
+		EnvUpgradeReadyFD+"="+strconv.Itoa(3+len(files)),
+	)
+
+	s.l.Info("keel upgrade starting new process",
+		zap.String("executable", executable),
+		zap.Strings("listeners", names),
+	)
+
+	err = cmd.Start()
+	// the child holds its own copy of the write end
+	_ = w.Close()
+
+	if err != nil {
+		restore()
+		return errors.Wrap(err, "failed to start new process")
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		if _, err := r.Read(buf); err != nil {
+			ready <- ErrUpgradeChildExited
+			return
+		}
+		ready <- nil
+	}()
+
+	timer := time.NewTimer(s.upgrade.ReadyTimeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-ready:
+	case <-timer.C:
+		err = ErrUpgradeTimeout
+	case <-s.gracefulCtx.Done():
+		err = ErrServerShutdown
+	}
+
+	if err != nil {
+		restore()
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+
+		return err
+	}
+
+	s.l.Info("keel upgrade new process ready", zap.Int("pid", cmd.Process.Pid))
+
+	// reap the child in case it exits before us
+	go func() {
+		_ = cmd.Wait()
+	}()
+
+	return nil
+}
+
+// upgradeFiles returns duplicates of the listener descriptors of all started
+// services along with their names and a function restoring the unix socket
+// cleanup in case the upgrade fails
+func (s *Server) upgradeFiles() ([]*os.File, []string, func(), error) {
+	var (
+		files []*os.File
+		names []string
+		unix  []*net.UnixListener
+	)
+
+	restore := func() {
+		for _, ln := range unix {
+			ln.SetUnlinkOnClose(true)
+		}
+	}
+
+	for _, value := range append(slices.Clone(s.initServices), s.services...) {
+		svs, ok := value.(listenerService)
+		if !ok || svs.Listener() == nil {
+			continue
+		}
+
+		ln, ok := svs.Listener().(fileListener)
+		if !ok {
+			s.l.Warn("keel upgrade skipping listener", log.FName(svs.Name()), log.FValue(fmt.Sprintf("%T", svs.Listener())))
+			continue
+		}
+
+		f, err := ln.File()
+		if err != nil {
+			restore()
+
+			for _, f := range files {
+				_ = f.Close()
+			}
+
+			return nil, nil, nil, errors.Wrapf(err, "failed to get listener file of %q", svs.Name())
+		}
+
+		// keep the socket file for the new process
+		if v, ok := ln.(*net.UnixListener); ok {
+			v.SetUnlinkOnClose(false)
+			unix = append(unix, v)
+		}
+
+		files = append(files, f)
+		names = append(names, svs.Name())
+	}
+
+	return files, names, restore, nil
+}
+
+// notifyUpgradeReady reports to the parent process once all startup and
+// readiness probes pass and releases the listeners no service has taken
+func (s *Server) notifyUpgradeReady(ctx context.Context, w io.WriteCloser) {
+	defer w.Close()
+
+	interval := GetDefaultUpgradeOptions().Interval
+	if s.upgrade != nil {
+		interval = s.upgrade.Interval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for !s.upgradeReady(ctx) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+
+	if err := service.CloseInheritedListeners(); err != nil {
+		log.WithError(s.l, err).Warn("keel upgrade failed to close unused listeners")
+	}
+
+	if _, err := w.Write([]byte{1}); err != nil {
+		log.WithError(s.l, err).Error("keel upgrade failed to notify parent process")
+		return
+	}
+
+	s.l.Info("keel upgrade ready, notified parent process", zap.Int("ppid", os.Getppid()))
+}
+
+// upgradeReady returns true if all startup and readiness probes pass
+func (s *Server) upgradeReady(ctx context.Context) bool {
+	probes := s.probes()
+	for _, typ := range []healthz.Type{healthz.TypeAlways, healthz.TypeStartup, healthz.TypeReadiness} {
+		for _, probe := range probes[typ] {
+			if err := healthz.Check(ctx, probe); err != nil {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// upgradeReadyPipe returns the pipe to notify the parent process through
+// if this process was started by an upgrade
+func upgradeReadyPipe() io.WriteCloser {
+	fd, err := strconv.Atoi(os.Getenv(EnvUpgradeReadyFD))
+	if err != nil {
+		return nil
+	}
+
+	if upgradeListenPPID == 0 || upgradeListenPPID != os.Getppid() {
+		return nil
+	}
+
+	return os.NewFile(uintptr(fd), "keel-upgrade-ready")
+}
+
+// upgradeEnviron returns the environment without the variables of a
+// previous socket activation or upgrade
+func upgradeEnviron() []string {
+	ret := make([]string, 0, len(os.Environ()))
+
+	for _, v := range os.Environ() {
+		switch key, _, _ := strings.Cut(v, "="); key {
+		case service.EnvListenPID, service.EnvListenFDs, service.EnvListenFDNames, service.EnvListenPPID, EnvUpgradeReadyFD:
+			continue
+		}
+
+		ret = append(ret, v)
+	}
+
+	return ret
+}
@@ -0,0 +1,10 @@
+//go:build windows || plan9 || js || wasip1
+
+package keel
+
+import (
+	"os"
+)
+
+// defaultUpgradeSignal is unset as there is no user defined signal
+var defaultUpgradeSignal os.Signal
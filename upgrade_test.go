@@ -0,0 +1,103 @@
+//go:build unix
+
+package keel_test
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/foomo/keel"
+	"github.com/foomo/keel/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestMain(m *testing.M) {
+	// the test binary is started again by TestServer_upgrade
+	if os.Getenv(service.EnvListenPPID) != "" {
+		runUpgradedServer()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// runUpgradedServer serves the new version on the inherited listener
+func runUpgradedServer() {
+	svr := keel.NewServer(
+		keel.WithLogger(zap.NewNop()),
+		keel.WithGracefulPeriod(time.Second),
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("v2"))
+	})
+	mux.HandleFunc("/quit", func(w http.ResponseWriter, r *http.Request) {
+		svr.ShutdownCancel()()
+	})
+
+	svr.AddHTTPService("upgrade", "127.0.0.1:0", mux)
+	svr.Run()
+}
+
+func TestServer_upgrade(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	svr := keel.NewServer(
+		keel.WithLogger(zaptest.NewLogger(t)),
+		keel.WithGracefulPeriod(time.Second),
+		keel.WithUpgrade(true, keel.UpgradeWithReadyTimeout(10*time.Second)),
+	)
+	svr.AddHTTPService("upgrade", addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("v1"))
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		svr.Run()
+		close(done)
+	}()
+
+	get := func(path string) string {
+		resp, err := http.Get("http://" + addr + path) //nolint:noctx
+		if err != nil {
+			return ""
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		return string(body)
+	}
+
+	require.Eventually(t, func() bool {
+		return get("/") == "v1"
+	}, time.Second, 10*time.Millisecond)
+
+	t.Cleanup(func() {
+		_ = get("/quit")
+	})
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+
+	select {
+	case <-done:
+	case <-time.After(15 * time.Second):
+		require.FailNow(t, "server did not shut down after the upgrade")
+	}
+
+	// the new process serves on the same listener
+	assert.Equal(t, "v2", get("/"))
+}
@@ -0,0 +1,10 @@
+//go:build !windows && !plan9 && !js && !wasip1
+
+package keel
+
+import (
+	"os"
+	"syscall"
+)
+
+var defaultUpgradeSignal os.Signal = syscall.SIGUSR2
@@ -37,6 +37,11 @@ func BadRequestServerError(l *zap.Logger, w http.ResponseWriter, r *http.Request
 	ServerError(l, w, r, http.StatusBadRequest, err)
 }
 
+// ForbiddenServerError http response
+func ForbiddenServerError(l *zap.Logger, w http.ResponseWriter, r *http.Request, err error) {
+	ServerError(l, w, r, http.StatusForbidden, err)
+}
+
 // MethodNotAllowedServerError http response
 func MethodNotAllowedServerError(l *zap.Logger, w http.ResponseWriter, r *http.Request, err error) {
 	ServerError(l, w, r, http.StatusMethodNotAllowed, err)
@@ -47,6 +52,11 @@ func RequestEntityTooLargeServerError(l *zap.Logger, w http.ResponseWriter, r *h
 	ServerError(l, w, r, http.StatusRequestEntityTooLarge, err)
 }
 
+// TooManyRequestsServerError http response
+func TooManyRequestsServerError(l *zap.Logger, w http.ResponseWriter, r *http.Request, err error) {
+	ServerError(l, w, r, http.StatusTooManyRequests, err)
+}
+
 // NotFoundServerError http response
 func NotFoundServerError(l *zap.Logger, w http.ResponseWriter, r *http.Request, err error) {
 	ServerError(l, w, r, http.StatusNotFound, err)